@@ -0,0 +1,260 @@
+// Package assetcheck defines an Analyzer finding references to non-existent
+// assets in go code.
+//
+// The analyzer inspects calls on asset file systems defined in files
+// generated by go-assets (such as static.Assets.Open("/index.html")) and
+// reports string literal paths which do not exist in the generated file
+// system. This catches mistyped asset paths when vetting code instead of at
+// runtime. References are resolved using type information, such that
+// aliased imports, shadowed names and unrelated values with the same name
+// are handled correctly. The assets of a generated file system are exported
+// as a fact of its variable, such that references from other packages are
+// checked as well. cmd/assetcheck runs the analyzer from go vet.
+package assetcheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jessevdk/go-assets"
+	"golang.org/x/tools/go/analysis"
+)
+
+// The methods of a file system taking an asset path as first argument. These
+// include MustReadFile, which is called by generated accessor functions (see
+// Generator.Accessors) with the served path of the asset.
+var PathMethods = []string{"Open", "ReadFile", "MustReadFile"}
+
+// The methods of a file system taking a logical asset path as first argument,
+// which may refer to a fingerprinted asset (see Generator.Fingerprint).
+var URLMethods = []string{"URL"}
+
+// The methods of a file system taking an asset key as first argument.
+var KeyMethods = []string{"Lookup"}
+
+// The analyzer reporting references to non-existent assets.
+var Analyzer = &analysis.Analyzer{
+	Name:      "assetcheck",
+	Doc:       "report references to assets which do not exist in a generated asset file system",
+	Run:       run,
+	FactTypes: []analysis.Fact{new(fileSystemFact)},
+}
+
+// The import path of the package defining the file system type.
+const assetsPath = "github.com/jessevdk/go-assets"
+
+// The assets of a generated file system, attached to the variable holding it.
+type fileSystemFact struct {
+	// The asset paths.
+	Files map[string]bool
+
+	// The logical paths of fingerprinted assets.
+	Fingerprints map[string]bool
+
+	// The asset keys.
+	Keys map[string]bool
+}
+
+func (*fileSystemFact) AFact() {}
+
+func (f *fileSystemFact) String() string {
+	return fmt.Sprintf("assets(%d files, %d keys)", len(f.Files), len(f.Keys))
+}
+
+func newFileSystemFact(fs *assets.FileSystem) *fileSystemFact {
+	ret := &fileSystemFact{
+		Files:        make(map[string]bool),
+		Fingerprints: make(map[string]bool),
+		Keys:         make(map[string]bool),
+	}
+
+	for p := range fs.Files {
+		ret.Files[p] = true
+	}
+
+	for p := range fs.Fingerprints {
+		ret.Fingerprints[p] = true
+	}
+
+	for k := range fs.Keys {
+		ret.Keys[k] = true
+	}
+
+	return ret
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check whether t is the *assets.FileSystem type.
+func isFileSystem(t types.Type) bool {
+	ptr, ok := t.(*types.Pointer)
+
+	if !ok {
+		return false
+	}
+
+	named, ok := ptr.Elem().(*types.Named)
+
+	if !ok {
+		return false
+	}
+
+	obj := named.Obj()
+	return obj.Name() == "FileSystem" && obj.Pkg() != nil && obj.Pkg().Path() == assetsPath
+}
+
+// Check whether f was generated by go-assets.
+func isGenerated(f *ast.File) bool {
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, "// Code generated by go-assets") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Export the assets of the file systems defined in the generated files of
+// the package as facts of their variables.
+func exportFacts(pass *analysis.Pass) error {
+	for _, f := range pass.Files {
+		if !isGenerated(f) {
+			continue
+		}
+
+		filename := pass.Fset.File(f.Pos()).Name()
+		fss, err := assets.ParseFile(filename)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", filename, err)
+		}
+
+		names := make([]string, 0, len(fss))
+
+		for name := range fss {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			v, ok := pass.Pkg.Scope().Lookup(name).(*types.Var)
+
+			if ok && isFileSystem(v.Type()) {
+				pass.ExportObjectFact(v, newFileSystemFact(fss[name]))
+			}
+		}
+	}
+
+	return nil
+}
+
+// Get the variable referred to by expr, if expr is a (qualified) identifier.
+func variable(info *types.Info, expr ast.Expr) *types.Var {
+	var id *ast.Ident
+
+	switch e := ast.Unparen(expr).(type) {
+	case *ast.Ident:
+		id = e
+	case *ast.SelectorExpr:
+		id = e.Sel
+	default:
+		return nil
+	}
+
+	v, _ := info.Uses[id].(*types.Var)
+	return v
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if err := exportFacts(pass); err != nil {
+		return nil, err
+	}
+
+	for _, f := range pass.Files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+
+			if !ok {
+				return true
+			}
+
+			selection, ok := pass.TypesInfo.Selections[sel]
+
+			if !ok || selection.Kind() != types.MethodVal || !isFileSystem(selection.Recv()) {
+				return true
+			}
+
+			v := variable(pass.TypesInfo, sel.X)
+
+			if v == nil {
+				return true
+			}
+
+			var fact fileSystemFact
+
+			if !pass.ImportObjectFact(v, &fact) {
+				return true
+			}
+
+			lit, ok := ast.Unparen(call.Args[0]).(*ast.BasicLit)
+
+			if !ok || lit.Kind != token.STRING {
+				return true
+			}
+
+			s, err := strconv.Unquote(lit.Value)
+
+			if err != nil {
+				return true
+			}
+
+			p := path.Clean(path.Join("/", s))
+			name := sel.Sel.Name
+
+			if contains(URLMethods, name) {
+				if !fact.Files[p] && !fact.Fingerprints[p] {
+					pass.Reportf(lit.Pos(), "asset %s does not exist in %s", s, v.Name())
+				}
+			} else if contains(PathMethods, name) {
+				if !fact.Files[p] {
+					pass.Reportf(lit.Pos(), "asset %s does not exist in %s", s, v.Name())
+				}
+			} else if contains(KeyMethods, name) {
+				if !fact.Keys[s] {
+					pass.Reportf(lit.Pos(), "asset key %q does not exist in %s", s, v.Name())
+				}
+			}
+
+			return true
+		})
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,218 @@
+package assetcheck
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/analysis"
+)
+
+// The source directories of the packages imported by the test packages.
+var sourceDirs = map[string]string{
+	assetsPath:               "..",
+	"example.com/app/static": "testdata/static",
+}
+
+// A minimal analysis driver, type checking packages from source and running
+// the analyzer on them in dependency order while sharing object facts.
+type driver struct {
+	t        *testing.T
+	fset     *token.FileSet
+	fallback types.Importer
+	packages map[string]*types.Package
+	facts    map[types.Object]analysis.Fact
+}
+
+func newDriver(t *testing.T) *driver {
+	return &driver{
+		t:        t,
+		fset:     token.NewFileSet(),
+		fallback: importer.Default(),
+		packages: make(map[string]*types.Package),
+		facts:    make(map[types.Object]analysis.Fact),
+	}
+}
+
+func (d *driver) Import(p string) (*types.Package, error) {
+	if pkg, ok := d.packages[p]; ok {
+		return pkg, nil
+	}
+
+	dir, ok := sourceDirs[p]
+
+	if !ok {
+		return d.fallback.Import(p)
+	}
+
+	pkg, _, _ := d.check(p, dir)
+	return pkg, nil
+}
+
+// Parse and type check the package with the given import path in dir.
+func (d *driver) check(p string, dir string) (*types.Package, []*ast.File, *types.Info) {
+	bp, err := build.Default.ImportDir(dir, 0)
+
+	if err != nil {
+		d.t.Fatal(err)
+	}
+
+	var files []*ast.File
+
+	for _, name := range bp.GoFiles {
+		f, err := parser.ParseFile(d.fset, filepath.Join(dir, name), nil, parser.ParseComments)
+
+		if err != nil {
+			d.t.Fatal(err)
+		}
+
+		files = append(files, f)
+	}
+
+	info := &types.Info{
+		Types:      make(map[ast.Expr]types.TypeAndValue),
+		Defs:       make(map[*ast.Ident]types.Object),
+		Uses:       make(map[*ast.Ident]types.Object),
+		Selections: make(map[*ast.SelectorExpr]*types.Selection),
+	}
+
+	pkg, err := (&types.Config{Importer: d}).Check(p, d.fset, files, info)
+
+	if err != nil {
+		d.t.Fatal(err)
+	}
+
+	d.packages[p] = pkg
+	return pkg, files, info
+}
+
+// Run the analyzer on the package with the given import path in dir.
+func (d *driver) run(p string, dir string) []analysis.Diagnostic {
+	pkg, files, info := d.check(p, dir)
+
+	var ret []analysis.Diagnostic
+
+	pass := &analysis.Pass{
+		Analyzer:  Analyzer,
+		Fset:      d.fset,
+		Files:     files,
+		Pkg:       pkg,
+		TypesInfo: info,
+		ResultOf:  make(map[*analysis.Analyzer]interface{}),
+		Report: func(diagnostic analysis.Diagnostic) {
+			ret = append(ret, diagnostic)
+		},
+		ImportObjectFact: func(obj types.Object, fact analysis.Fact) bool {
+			f, ok := d.facts[obj]
+
+			if ok {
+				reflect.ValueOf(fact).Elem().Set(reflect.ValueOf(f).Elem())
+			}
+
+			return ok
+		},
+		ExportObjectFact: func(obj types.Object, fact analysis.Fact) {
+			if obj.Pkg() != pkg {
+				d.t.Fatalf("fact exported for object %s of another package", obj)
+			}
+
+			d.facts[obj] = fact
+		},
+		ImportPackageFact: func(*types.Package, analysis.Fact) bool { return false },
+		ExportPackageFact: func(analysis.Fact) {},
+	}
+
+	if _, err := Analyzer.Run(pass); err != nil {
+		d.t.Fatal(err)
+	}
+
+	return ret
+}
+
+// Collect the expected diagnostics of the go files in dir, given as
+// "// want" comments holding the quoted message, keyed by file and line.
+func wanted(t *testing.T, dir string) map[string]string {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	ret := make(map[string]string)
+
+	for _, filename := range matches {
+		f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, group := range f.Comments {
+			for _, c := range group.List {
+				text := strings.TrimPrefix(c.Text, "//")
+
+				if !strings.HasPrefix(strings.TrimSpace(text), "want ") {
+					continue
+				}
+
+				msg, err := strconv.Unquote(strings.TrimPrefix(strings.TrimSpace(text), "want "))
+
+				if err != nil {
+					t.Fatalf("%s: invalid want comment: %s", fset.Position(c.Pos()), err)
+				}
+
+				pos := fset.Position(c.Pos())
+				ret[pos.Filename+":"+strconv.Itoa(pos.Line)] = msg
+			}
+		}
+	}
+
+	return ret
+}
+
+func TestAnalyzer(t *testing.T) {
+	if err := analysis.Validate([]*analysis.Analyzer{Analyzer}); err != nil {
+		t.Fatal(err)
+	}
+
+	d := newDriver(t)
+
+	// The generated package is analyzed first, exporting its facts
+	for _, pkg := range []struct{ path, dir string }{
+		{"example.com/app/static", "testdata/static"},
+		{"example.com/app", "testdata/app"},
+	} {
+		diagnostics := d.run(pkg.path, pkg.dir)
+		want := wanted(t, pkg.dir)
+
+		for _, diagnostic := range diagnostics {
+			pos := d.fset.Position(diagnostic.Pos)
+			key := pos.Filename + ":" + strconv.Itoa(pos.Line)
+
+			if msg, ok := want[key]; !ok {
+				t.Errorf("%s: unexpected diagnostic %q", pos, diagnostic.Message)
+			} else if msg != diagnostic.Message {
+				t.Errorf("%s: expected %q, got %q", key, msg, diagnostic.Message)
+			}
+
+			delete(want, key)
+		}
+
+		for key, msg := range want {
+			t.Errorf("%s: missing diagnostic %q", key, msg)
+		}
+	}
+
+	if len(d.facts) != 1 {
+		t.Errorf("expected a single fact for static.Assets, got %v", d.facts)
+	}
+}
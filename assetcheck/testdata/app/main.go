@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jessevdk/go-assets"
+
+	"example.com/app/static"
+	web "example.com/app/static"
+)
+
+// An unrelated type with file system like methods
+type store struct{}
+
+func (store) Open(name string) {}
+
+// Unrelated values sharing the name of the file system are not checked
+var Assets store
+
+func main() {
+	static.Assets.Open("/templates/index.html")
+	static.Assets.Open("templates/partial.html")
+	static.Assets.Open("/templates/missing.html") // want "asset /templates/missing.html does not exist in Assets"
+
+	static.Assets.ReadFile("/static/app.js")
+	static.Assets.ReadFile("/static/app.ts") // want "asset /static/app.ts does not exist in Assets"
+
+	static.Assets.MustReadFile("/static/css/app.942ffb83.css")
+	static.Assets.MustReadFile("/static/css/app.css") // want "asset /static/css/app.css does not exist in Assets"
+
+	fmt.Println(static.Assets.URL("/static/css/app.css"))
+	fmt.Println(static.Assets.URL("/static/css/main.css")) // want "asset /static/css/main.css does not exist in Assets"
+
+	static.Assets.Lookup("app")
+	static.Assets.Lookup("main") // want "asset key \"main\" does not exist in Assets"
+
+	// Aliased imports are resolved
+	web.Assets.Open("/static/app.js")
+	web.Assets.Open("/static/missing.js") // want "asset /static/missing.js does not exist in Assets"
+
+	// Only string literals are checked
+	p := "/missing"
+	static.Assets.Open(p)
+
+	// Only file systems defined in generated files are checked
+	other := &assets.FileSystem{}
+	other.Open("/missing")
+	Assets.Open("/missing")
+
+	fmt.Println(static.StaticAppJS(), static.TemplatesIndexHTML())
+}
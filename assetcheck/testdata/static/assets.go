@@ -0,0 +1,61 @@
+// Code generated by go-assets. DO NOT EDIT.
+
+package static
+
+import (
+	"github.com/jessevdk/go-assets"
+)
+
+const _Assets390ca31a28084a35644c608ccbc27bc4e0829b0e = `console.log("hello");
+`
+const _Assets6355ff43e98121d7251d61afba66d602bcf5bec8 = `body {
+	margin: 0;
+}
+`
+const _Assets9f0ae851af9adb08e3242917d7d52dc270c7bae2 = `<!DOCTYPE html>
+<html>
+<head><title>{{.Title}}</title></head>
+<body>{{.Body}}</body>
+</html>
+`
+const _Assets50d89ce14d162dbec04abb5644a932be779455f7 = `<p>{{.}}</p>
+`
+
+var _AssetsEntries = [...]assets.FileEntry{
+	{Path: "/", FileMode: 0x800001ed, Mtime: 1792060920443243003},
+	{Path: "/static", FileMode: 0x800001ed, Mtime: 1792060920443243003},
+	{Path: "/static/app.js", FileMode: 0x1a4, Mtime: 1792051468000000000, Data: _Assets390ca31a28084a35644c608ccbc27bc4e0829b0e},
+	{Path: "/static/css", FileMode: 0x800001ed, Mtime: 1792060920443243003},
+	{Path: "/static/css/app.942ffb83.css", FileMode: 0x1a4, Mtime: 1792051468000000000, Data: _Assets6355ff43e98121d7251d61afba66d602bcf5bec8, Fingerprinted: true},
+	{Path: "/templates", FileMode: 0x800001ed, Mtime: 1792060920443243003},
+	{Path: "/templates/index.html", FileMode: 0x1a4, Mtime: 1792051468000000000, Data: _Assets9f0ae851af9adb08e3242917d7d52dc270c7bae2},
+	{Path: "/templates/partial.html", FileMode: 0x1a4, Mtime: 1792051468000000000, Data: _Assets50d89ce14d162dbec04abb5644a932be779455f7},
+}
+
+// Assets returns go-assets FileSystem
+var Assets = assets.NewVersionedFileSystem(2, _AssetsEntries[:], "")
+
+func init() {
+	Assets.Keys = map[string]string{"app": "/static/app.js"}
+	Assets.Fingerprints = map[string]string{"/static/css/app.css": "/static/css/app.942ffb83.css"}
+}
+
+// StaticAppJS returns the contents of /static/app.js.
+func StaticAppJS() []byte {
+	return Assets.MustReadFile("/static/app.js")
+}
+
+// StaticCSSAppCSS returns the contents of /static/css/app.css.
+func StaticCSSAppCSS() []byte {
+	return Assets.MustReadFile("/static/css/app.942ffb83.css")
+}
+
+// TemplatesIndexHTML returns the contents of /templates/index.html.
+func TemplatesIndexHTML() []byte {
+	return Assets.MustReadFile("/templates/index.html")
+}
+
+// TemplatesPartialHTML returns the contents of /templates/partial.html.
+func TemplatesPartialHTML() []byte {
+	return Assets.MustReadFile("/templates/partial.html")
+}
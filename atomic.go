@@ -0,0 +1,103 @@
+package assets
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Write data to the file at filename by writing a temporary file in the same
+// directory and renaming it, such that the file is replaced atomically and
+// never left half-written.
+func writeFileAtomic(filename string, data []byte) error {
+	return writeFileAtomicFunc(filename, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// Write the file at filename atomically like writeFileAtomic, streaming its
+// contents from write.
+func writeFileAtomicFunc(filename string, write func(w io.Writer) error) error {
+	fd, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+
+	if err != nil {
+		return err
+	}
+
+	tmp := fd.Name()
+
+	if err := write(fd); err != nil {
+		fd.Close()
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := fd.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// Temporary files are created with mode 0600
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// Infer the name of the package in dir: the package of the go files in dir
+// (other than tests and files generated by go-assets), or a package named
+// after dir if there are none.
+func inferPackageName(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+
+	for _, filename := range files {
+		if strings.HasSuffix(filename, "_test.go") {
+			continue
+		}
+
+		src, err := ioutil.ReadFile(filename)
+
+		if err != nil || bytes.HasPrefix(src, []byte(generatedMarker+"\n")) {
+			continue
+		}
+
+		if f, err := parser.ParseFile(fset, filename, src, parser.PackageClauseOnly); err == nil {
+			return f.Name.Name
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+
+	if err != nil {
+		return "main"
+	}
+
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+
+		return -1
+	}, filepath.Base(abs))
+
+	if len(name) == 0 || unicode.IsDigit(rune(name[0])) || token.Lookup(name).IsKeyword() {
+		return "main"
+	}
+
+	return name
+}
@@ -0,0 +1,85 @@
+package assets
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// The first line of generated files, which marks them as generated for
+// linters and code review tools.
+const generatedMarker = "// Code generated by go-assets. DO NOT EDIT."
+
+// The data the banner of generated files is executed with, see
+// Generator.Banner.
+type BannerData struct {
+	generator *Generator
+}
+
+// The command generating the file.
+func (b *BannerData) Command() string {
+	args := append([]string{filepath.Base(os.Args[0])}, os.Args[1:]...)
+	return strings.Join(args, " ")
+}
+
+// The location of the go:generate directive running the generator (e.g.
+// static.go:3), or empty when not run by go generate.
+func (b *BannerData) Directive() string {
+	file := os.Getenv("GOFILE")
+
+	if len(file) == 0 {
+		return ""
+	}
+
+	return file + ":" + os.Getenv("GOLINE")
+}
+
+// The commit checked out in the git repository of the working directory, or
+// empty when not in a git repository.
+func (b *BannerData) Commit() string {
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// The time of generation, or the time given by the SOURCE_DATE_EPOCH
+// environment variable for reproducible builds (see Generator.MaxModTime).
+func (b *BannerData) Time() (time.Time, error) {
+	t, err := b.generator.maxModTime()
+
+	if err != nil || !t.IsZero() {
+		return t.UTC(), err
+	}
+
+	return time.Now().UTC(), nil
+}
+
+// Get the banner written at the top of generated files: the generated code
+// marker, followed by the expanded Banner.
+func (x *Generator) banner() (string, error) {
+	if len(x.Banner) == 0 {
+		return generatedMarker + "\n\n", nil
+	}
+
+	tmpl, err := template.New("banner").Parse(x.Banner)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid banner: %s", err)
+	}
+
+	var buf strings.Builder
+
+	if err := tmpl.Execute(&buf, &BannerData{generator: x}); err != nil {
+		return "", fmt.Errorf("invalid banner: %s", err)
+	}
+
+	return generatedMarker + "\n" + commentBlock(buf.String()), nil
+}
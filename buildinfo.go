@@ -0,0 +1,74 @@
+package assets
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+)
+
+// The path of the asset holding the build metadata, see
+// Generator.AddBuildInfo.
+const BuildInfoPath = "/.build-info.json"
+
+// The build metadata of a generated file system, see Generator.AddBuildInfo.
+type BuildInfo struct {
+	// The version of the assets, as given to AddBuildInfo
+	Version string `json:"version,omitempty"`
+
+	// The git commit checked out at generation time, if any
+	Commit string `json:"commit,omitempty"`
+
+	// The time of generation
+	Time time.Time `json:"time"`
+}
+
+// Add the build metadata of the assets (the given version, the git commit
+// checked out in the working directory and the time of generation) to the
+// generator as a JSON asset at BuildInfoPath, such that servers can report
+// which assets they are running (see FileSystem.BuildInfo). The asset is
+// placed at BuildInfoPath regardless of StripPrefix. Like in the banner (see
+// BannerData.Time), the time of generation is taken from MaxModTime or the
+// SOURCE_DATE_EPOCH environment variable when set, which keeps the generated
+// file reproducible.
+func (x *Generator) AddBuildInfo(version string) error {
+	banner := &BannerData{generator: x}
+	t, err := banner.Time()
+
+	if err != nil {
+		return err
+	}
+
+	info := BuildInfo{
+		Version: version,
+		Commit:  banner.Commit(),
+		Time:    t,
+	}
+
+	data, err := json.MarshalIndent(info, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	x.addVirtual(path.Join("/", x.StripPrefix, BuildInfoPath), 0644, t, append(data, '\n'))
+	return nil
+}
+
+// Get the build metadata of the file system, see Generator.AddBuildInfo.
+// Fails with an error satisfying os.IsNotExist when the file system has no
+// build metadata.
+func (f *FileSystem) BuildInfo() (*BuildInfo, error) {
+	data, err := f.ReadFile(BuildInfoPath)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var info BuildInfo
+
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
@@ -0,0 +1,77 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"time"
+)
+
+// Concatenate the files at inputs (e.g. CSS partials or JavaScript snippets)
+// in order, and add the result to the generator as a single asset at the
+// virtual path outputPath. Like the paths of other added files, outputPath is
+// subject to StripPrefix. The bundle starts with BundleBanner, and its inputs
+// are separated by BundleSeparator. Without a separator, a newline is
+// inserted after inputs which do not end with one. The modification time of
+// the asset is the latest modification time of its inputs. The inputs are
+// read again whenever the assets are regenerated (see Watch).
+func (x *Generator) Bundle(outputPath string, inputs ...string) error {
+	if err := x.bundle(outputPath, inputs); err != nil {
+		return err
+	}
+
+	x.sources = append(x.sources, source{
+		paths: inputs,
+		add:   func() error { return x.bundle(outputPath, inputs) },
+	})
+
+	return nil
+}
+
+func (x *Generator) bundle(outputPath string, inputs []string) error {
+	if len(inputs) == 0 {
+		return fmt.Errorf("bundle %s has no inputs", outputPath)
+	}
+
+	var buf bytes.Buffer
+	var mtime time.Time
+
+	buf.WriteString(x.BundleBanner)
+
+	for i, input := range inputs {
+		info, err := os.Stat(input)
+
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return fmt.Errorf("bundle %s: %s is a directory", outputPath, input)
+		}
+
+		data, err := ioutil.ReadFile(input)
+
+		if err != nil {
+			return err
+		}
+
+		if i != 0 {
+			if len(x.BundleSeparator) != 0 {
+				buf.WriteString(x.BundleSeparator)
+			} else if buf.Len() != 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+				buf.WriteByte('\n')
+			}
+		}
+
+		buf.Write(data)
+
+		if info.ModTime().After(mtime) {
+			mtime = info.ModTime()
+		}
+	}
+
+	x.addEntry(outputPath, virtualFile(path.Base(outputPath), 0644, mtime, buf.Bytes()))
+	return nil
+}
@@ -0,0 +1,20 @@
+// Command assetcheck reports references to assets which do not exist in a
+// generated asset file system, see package assetcheck. It is run by go vet:
+//
+//	go build github.com/jessevdk/go-assets/cmd/assetcheck
+//	go vet -vettool=$(pwd)/assetcheck ./...
+//
+// References are checked in every package importing a package with generated
+// asset files, including the generated package itself. Diagnostics are
+// printed as file:line:column: message, and go vet exits with a non-zero
+// status when any are reported, such that it can be run from CI.
+package main
+
+import (
+	"github.com/jessevdk/go-assets/assetcheck"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(assetcheck.Analyzer)
+}
@@ -0,0 +1,696 @@
+// Command go-assets generates asset files, and inspects generated asset files
+// without building a go program.
+//
+// Usage:
+//
+//	go-assets generate [-p package] [-v variable] [-s prefix] [-x pattern]... [-c compression] [-o output] input...
+//	go-assets generate -config file
+//	go-assets ls [-var name] [-pack file] generated.go
+//	go-assets ls pack
+//	go-assets inspect [-var name] [-pack file] [-extract path [-o file]] generated.go
+//	go-assets inspect pack
+//	go-assets extract [-var name] -o dir generated.go
+//	go-assets cat [-var name] [-f generated.go] path...
+//	go-assets diff [-var name] old new
+//	go-assets sizes [-var name] generated.go
+//	go-assets append-pack executable pack
+//
+// The generate command writes the files and directories given as inputs to a
+// generated go file (assets.go by default), such that it can be run directly
+// from a go:generate directive:
+//
+//	//go:generate go run github.com/jessevdk/go-assets/cmd/go-assets generate -s /web web
+//
+// Excludes are glob patterns (see Generator.Exclude) and can be given several
+// times. The compression is one of none (the default), gzip and snappy. With
+// -config, the generation is described by a JSON or YAML config file instead,
+// see LoadConfig.
+//
+// The ls command lists the assets of the file systems defined in the
+// generated file with their modes, sizes (original and as stored) and SHA-256
+// digests. The data of file systems generated with a pack file is read from
+// the pack next to the generated file, or from the pack given with -pack.
+// Given a pack file instead, ls verifies the pack and lists its size and
+// digest, which identifies the generated file it belongs to.
+//
+// The inspect command prints a manifest of the file systems defined in the
+// generated file and verifies their structure and that their data can be
+// read, see FileSystem.Verify. Like with ls, the data of file systems
+// generated with a pack file is read from the pack given with -pack. With
+// -extract, the (decompressed) contents of a single asset are written to
+// standard output, or to the file given with -o, which is given the mode of
+// the asset. Given a pack file instead, inspect verifies the pack against its
+// digest and prints it like ls; the assets of a pack are described by the
+// generated file it belongs to.
+//
+// The extract command writes the (decompressed) assets of a file system to
+// the directory given with -o, restoring their modes and modification times.
+//
+// The cat command writes the (decompressed) contents of the assets at the
+// given paths to standard output, reading them from assets.go unless another
+// generated file is given with -f.
+//
+// The diff command compares two generated files, or a generated file and a
+// source directory, and reports the added (+), removed (-) and modified (M)
+// paths with the change in their (decompressed) sizes. Since the modes of
+// generated files may have been normalized (see Generator.Hermetic), only
+// whether files are executable is compared with a source directory.
+//
+// The sizes command reports the contribution of each asset to the size of
+// the executable, in descending order.
+//
+// The append-pack command appends an asset pack file to a built executable,
+// see Generator.AppendPack.
+package main
+
+import (
+	"crypto/sha256"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jessevdk/go-assets"
+)
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: go-assets generate [-p package] [-v variable] [-s prefix] [-x pattern]... [-c compression] [-o output] input...")
+	fmt.Fprintln(w, "       go-assets generate -config file")
+	fmt.Fprintln(w, "       go-assets ls [-var name] [-pack file] generated.go")
+	fmt.Fprintln(w, "       go-assets ls pack")
+	fmt.Fprintln(w, "       go-assets inspect [-var name] [-pack file] [-extract path [-o file]] generated.go")
+	fmt.Fprintln(w, "       go-assets inspect pack")
+	fmt.Fprintln(w, "       go-assets extract [-var name] -o dir generated.go")
+	fmt.Fprintln(w, "       go-assets cat [-var name] [-f generated.go] path...")
+	fmt.Fprintln(w, "       go-assets diff [-var name] old new")
+	fmt.Fprintln(w, "       go-assets sizes [-var name] generated.go")
+	fmt.Fprintln(w, "       go-assets append-pack executable pack")
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return fmt.Errorf("no command specified")
+	}
+
+	switch args[0] {
+	case "generate":
+		return generate(args[1:])
+	case "ls":
+		return ls(args[1:], stdout)
+	case "inspect":
+		return inspect(args[1:], stdout)
+	case "extract":
+		return extract(args[1:])
+	case "cat":
+		return cat(args[1:], stdout)
+	case "diff":
+		return diff(args[1:], stdout)
+	case "sizes":
+		return sizes(args[1:], stdout)
+	case "append-pack":
+		if len(args) != 3 {
+			usage(os.Stderr)
+			return fmt.Errorf("expected an executable and a pack file")
+		}
+
+		return assets.AppendPack(args[1], args[2])
+	}
+
+	usage(os.Stderr)
+	return fmt.Errorf("unknown command %s", args[0])
+}
+
+// A flag which can be given several times, collecting its values.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func generate(args []string) error {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+
+	var excludes listFlag
+
+	pkg := flags.String("p", "", "the package name of the generated file (defaults to the package of the output directory)")
+	variable := flags.String("v", "Assets", "the variable name of the generated file system")
+	strip := flags.String("s", "", "strip this prefix from all asset paths")
+	flags.Var(&excludes, "x", "exclude files matching this glob pattern (can be given several times)")
+	compression := flags.String("c", "none", "the compression of the asset data (none, gzip or snappy)")
+	output := flags.String("o", "assets.go", "the generated go file")
+	config := flags.String("config", "", "generate as described by this config file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if len(*config) != 0 {
+		if flags.NArg() != 0 {
+			return fmt.Errorf("inputs cannot be combined with -config")
+		}
+
+		c, err := assets.LoadConfig(*config)
+
+		if err != nil {
+			return err
+		}
+
+		return c.Generate()
+	}
+
+	if flags.NArg() == 0 {
+		usage(os.Stderr)
+		return fmt.Errorf("no inputs specified")
+	}
+
+	g := &assets.Generator{
+		PackageName:  *pkg,
+		VariableName: *variable,
+		StripPrefix:  *strip,
+		Exclude:      excludes,
+	}
+
+	var c assets.Compression
+
+	if err := c.UnmarshalText([]byte(*compression)); err != nil {
+		return err
+	}
+
+	if c != assets.NoCompression {
+		g.Compressed, g.Compression = true, c
+	}
+
+	for _, input := range flags.Args() {
+		if err := g.Add(input); err != nil {
+			return err
+		}
+	}
+
+	return g.WriteFile(*output)
+}
+
+func ls(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("ls", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "only list the file system with this variable name")
+	pack := flags.String("pack", "", "read the asset data from this pack file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		usage(os.Stderr)
+		return fmt.Errorf("expected a single generated file")
+	}
+
+	filename := flags.Arg(0)
+
+	if filepath.Ext(filename) != ".go" {
+		return listPack(stdout, filename)
+	}
+
+	fss, names, err := parseFileSystems(filename, *variable)
+
+	if err != nil {
+		return err
+	}
+
+	if len(*pack) != 0 {
+		if len(names) != 1 {
+			return errSeveral(filename)
+		}
+
+		if err := fss[names[0]].LoadPack(*pack); err != nil {
+			return err
+		}
+	}
+
+	for i, name := range names {
+		if i != 0 {
+			fmt.Fprintln(stdout)
+		}
+
+		if len(names) != 1 {
+			fmt.Fprintln(stdout, name)
+		}
+
+		if err := list(stdout, fss[name], false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List a pack file on its own. Packs only hold the asset data, their assets
+// are described by the generated file they belong to.
+func listPack(w io.Writer, filename string) error {
+	digest, size, err := assets.ReadPackDigest(filename)
+
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "SIZE\tSHA256\tPACK")
+	fmt.Fprintf(tw, "%d\t%s\t%s\n", size, digest, filename)
+
+	return tw.Flush()
+}
+
+// List the assets of a file system with their modes, (decompressed and
+// stored) sizes and digests. Manifests also list the compression and
+// modification time of each asset.
+func list(w io.Writer, fs *assets.FileSystem, manifest bool) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	if manifest {
+		fmt.Fprintln(tw, "MODE\tSIZE\tSTORED\tCOMPRESSION\tMODIFIED\tSHA256\tPATH")
+	} else {
+		fmt.Fprintln(tw, "MODE\tSIZE\tSTORED\tSHA256\tPATH")
+	}
+
+	var paths []string
+
+	for p := range fs.Files {
+		paths = append(paths, p)
+	}
+
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		f := fs.Files[p]
+		row := []string{f.Mode().String(), "-", "-"}
+		sum := "-"
+
+		if !f.IsDir() {
+			data, err := fs.ReadFile(p)
+
+			if err != nil {
+				return fmt.Errorf("%s: %s", p, err)
+			}
+
+			row[1], row[2] = strconv.Itoa(len(data)), strconv.FormatInt(f.Size(), 10)
+			sum = fmt.Sprintf("%x", sha256.Sum256(data))
+		}
+
+		if manifest && f.IsDir() {
+			row = append(row, "-", modified(f))
+		} else if manifest {
+			row = append(row, compression(fs, f), modified(f))
+		}
+
+		fmt.Fprintln(tw, strings.Join(append(row, sum, p), "\t"))
+	}
+
+	return tw.Flush()
+}
+
+func inspect(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("inspect", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "only inspect the file system with this variable name")
+	pack := flags.String("pack", "", "read the asset data from this pack file")
+	extract := flags.String("extract", "", "extract the asset at this path")
+	output := flags.String("o", "", "write the extracted asset to this file instead of standard output")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		usage(os.Stderr)
+		return fmt.Errorf("expected a single generated file or pack")
+	}
+
+	filename := flags.Arg(0)
+
+	if filepath.Ext(filename) != ".go" {
+		if len(*extract) != 0 {
+			return fmt.Errorf("%s: assets can only be extracted using the generated file of the pack", filename)
+		}
+
+		return listPack(stdout, filename)
+	}
+
+	fss, names, err := parseFileSystems(filename, *variable)
+
+	if err != nil {
+		return err
+	}
+
+	if len(*pack) != 0 || len(*extract) != 0 {
+		if len(names) != 1 {
+			return errSeveral(filename)
+		}
+	}
+
+	if len(*pack) != 0 {
+		if err := fss[names[0]].LoadPack(*pack); err != nil {
+			return err
+		}
+	}
+
+	if len(*extract) != 0 {
+		fs := fss[names[0]]
+		data, err := fs.ReadFile(*extract)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", *extract, err)
+		}
+
+		if len(*output) != 0 {
+			// Keep the mode of the asset, e.g. of executable scripts
+			return assets.WriteFileMode(*output, data, fs.Files[path.Clean(*extract)])
+		}
+
+		_, err = stdout.Write(data)
+		return err
+	}
+
+	for i, name := range names {
+		if i != 0 {
+			fmt.Fprintln(stdout)
+		}
+
+		if err := printManifest(stdout, name, fss[name]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sizes(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("sizes", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "only report the file system with this variable name")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		usage(os.Stderr)
+		return fmt.Errorf("expected a single generated file")
+	}
+
+	filename := flags.Arg(0)
+	fss, names, err := parseFileSystems(filename, *variable)
+
+	if err != nil {
+		return err
+	}
+
+	var ret []assets.AssetSize
+
+	for _, name := range names {
+		for _, s := range fss[name].AssetSizes() {
+			s.FileSystem = name
+			ret = append(ret, s)
+		}
+	}
+
+	if len(ret) == 0 {
+		return fmt.Errorf("%s: no assets found", filename)
+	}
+
+	// Sizes of each file system are sorted already, keep their order for
+	// equal sizes
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].Size() > ret[j].Size()
+	})
+
+	return assets.WriteAssetSizes(stdout, ret)
+}
+
+// Parse the generated file, and get its file systems and their sorted
+// variable names. When variable is not empty, only the file system with this
+// variable name is selected.
+func parseFileSystems(filename string, variable string) (map[string]*assets.FileSystem, []string, error) {
+	fss, err := assets.ParseFile(filename)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var names []string
+
+	for name := range fss {
+		if len(variable) == 0 || name == variable {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, nil, fmt.Errorf("%s: no asset file systems found", filename)
+	}
+
+	sort.Strings(names)
+	return fss, names, nil
+}
+
+// Get the error for commands operating on a single file system, when the
+// generated file defines several.
+func errSeveral(filename string) error {
+	return fmt.Errorf("%s defines several file systems, select one using -var", filename)
+}
+
+func extract(args []string) error {
+	flags := flag.NewFlagSet("extract", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "extract the file system with this variable name")
+	output := flags.String("o", "", "the directory to extract the assets to")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 || len(*output) == 0 {
+		usage(os.Stderr)
+		return fmt.Errorf("expected a single generated file and an output directory")
+	}
+
+	filename := flags.Arg(0)
+	fss, names, err := parseFileSystems(filename, *variable)
+
+	if err != nil {
+		return err
+	}
+
+	if len(names) != 1 {
+		return errSeveral(filename)
+	}
+
+	return fss[names[0]].Extract(*output)
+}
+
+func cat(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("cat", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "read from the file system with this variable name")
+	filename := flags.String("f", "assets.go", "the generated file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		usage(os.Stderr)
+		return fmt.Errorf("no asset paths specified")
+	}
+
+	fss, names, err := parseFileSystems(*filename, *variable)
+
+	if err != nil {
+		return err
+	}
+
+	if len(names) != 1 {
+		return errSeveral(*filename)
+	}
+
+	for _, p := range flags.Args() {
+		data, err := fss[names[0]].ReadFile(p)
+
+		if err != nil {
+			return fmt.Errorf("%s: %s", p, err)
+		}
+
+		if _, err := stdout.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func diff(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("diff", flag.ContinueOnError)
+
+	variable := flags.String("var", "", "compare the file systems with this variable name")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 2 {
+		usage(os.Stderr)
+		return fmt.Errorf("expected two generated files or directories")
+	}
+
+	older, err := loadFileSystem(flags.Arg(0), *variable)
+
+	if err != nil {
+		return err
+	}
+
+	newer, err := loadFileSystem(flags.Arg(1), *variable)
+
+	if err != nil {
+		return err
+	}
+
+	// The modes of generated files may have been normalized (see
+	// Generator.Hermetic and Generator.ModeOverrides), such that only
+	// whether files are executable is compared with source directories
+	if isDir(flags.Arg(0)) || isDir(flags.Arg(1)) {
+		normalizeModes(older)
+		normalizeModes(newer)
+	}
+
+	changes, err := assets.DiffFS(older, newer)
+
+	if err != nil {
+		return err
+	}
+
+	var delta int64
+
+	for _, c := range changes {
+		marker := "M"
+
+		if c.Kind == assets.Added {
+			marker = "+"
+		} else if c.Kind == assets.Removed {
+			marker = "-"
+		}
+
+		fmt.Fprintf(stdout, "%s %s (%+d bytes)\n", marker, c.Path, c.Delta())
+		delta += c.Delta()
+	}
+
+	if len(changes) == 1 {
+		fmt.Fprintf(stdout, "1 change, %+d bytes\n", delta)
+	} else {
+		fmt.Fprintf(stdout, "%d changes, %+d bytes\n", len(changes), delta)
+	}
+	return nil
+}
+
+// Load the file system of a generated file, or the file system of the files
+// in a source directory.
+func loadFileSystem(filename string, variable string) (*assets.FileSystem, error) {
+	if isDir(filename) {
+		g := &assets.Generator{}
+
+		if err := g.AddRoot(assets.Root{Dir: filename}); err != nil {
+			return nil, err
+		}
+
+		return g.FileSystem()
+	}
+
+	fss, names, err := parseFileSystems(filename, variable)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) != 1 {
+		return nil, errSeveral(filename)
+	}
+
+	return fss[names[0]], nil
+}
+
+func isDir(filename string) bool {
+	info, err := os.Stat(filename)
+	return err == nil && info.IsDir()
+}
+
+// Normalize the permissions of the files of a file system to 0644, or 0755
+// for executables, as Generator.Hermetic does.
+func normalizeModes(fs *assets.FileSystem) {
+	for _, f := range fs.Files {
+		if f.IsDir() {
+			continue
+		}
+
+		perm := os.FileMode(0644)
+
+		if f.FileMode&0111 != 0 {
+			perm = 0755
+		}
+
+		f.FileMode = f.FileMode&^os.ModePerm | perm
+	}
+}
+
+// Print the manifest of a file system and verify it, see FileSystem.Verify.
+func printManifest(w io.Writer, name string, fs *assets.FileSystem) error {
+	fmt.Fprintln(w, name)
+
+	if err := list(w, fs, true); err != nil {
+		return err
+	}
+
+	if err := fs.Verify(); err != nil {
+		return fmt.Errorf("%s: verification failed: %s", name, err)
+	}
+
+	fmt.Fprintf(w, "%d assets, verified\n", len(fs.Files))
+	return nil
+}
+
+// Get the compression of an asset, taking into account file systems
+// generated by older versions which are compressed as a whole.
+func compression(fs *assets.FileSystem, f *assets.File) string {
+	if !f.Compressed && !fs.Compressed {
+		return "none"
+	}
+
+	if f.Compression != assets.Gzip {
+		return f.Compression.String()
+	}
+
+	return fs.Compression.String()
+}
+
+func modified(f *assets.File) string {
+	if f.Mtime.IsZero() {
+		return "-"
+	}
+
+	return f.Mtime.UTC().Format("2006-01-02T15:04:05Z")
+}
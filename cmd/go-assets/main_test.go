@@ -0,0 +1,428 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jessevdk/go-assets"
+)
+
+func TestInspect(t *testing.T) {
+	g := &assets.Generator{Compressed: true}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"inspect", filename}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"COMPRESSION", "/static/app.js", "assets, verified"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected manifest to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	buf.Reset()
+
+	if err := run([]string{"inspect", "-extract", "/static/app.js", filename}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "console.log(\"hello\");\n"; buf.String() != expected {
+		t.Errorf("expected extracted %q, got %q", expected, buf.String())
+	}
+
+	if err := run([]string{"inspect", "-extract", "/missing", filename}, &buf); err == nil {
+		t.Errorf("expected extracting a missing asset to fail")
+	}
+}
+
+func TestInspectPack(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+
+	g := &assets.Generator{PackFile: "assets.pack"}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	pack := filepath.Join(t.TempDir(), "shipped.pack")
+
+	if err := os.Rename(filepath.Join(dir, "assets.pack"), pack); err != nil {
+		t.Fatal(err)
+	}
+
+	// A shipped pack is verified on its own, or with its generated file
+	var buf bytes.Buffer
+
+	if err := run([]string{"inspect", pack}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), pack) {
+		t.Errorf("expected pack to be listed, got:\n%s", buf.String())
+	}
+
+	buf.Reset()
+
+	if err := run([]string{"inspect", "-pack", pack, "-extract", "/static/app.js", filename}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "console.log(\"hello\");\n"; buf.String() != expected {
+		t.Errorf("expected extracted %q, got %q", expected, buf.String())
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.pack")
+
+	if err := ioutil.WriteFile(corrupt, []byte("GOASPACK corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"inspect", corrupt}, ioutil.Discard); err == nil {
+		t.Errorf("expected inspecting a corrupt pack to fail")
+	}
+}
+
+func TestSizes(t *testing.T) {
+	g := &assets.Generator{}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"sizes", filename}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 5 || !strings.HasPrefix(lines[0], "SIZE") || !strings.Contains(lines[1], "Assets") {
+		t.Errorf("expected a size per asset, got:\n%s", buf.String())
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	args := []string{"generate", "-p", "web", "-v", "Web", "-s", "/testdata", "-x", "*.css", "-c", "gzip", "-o", filename, "../../testdata"}
+
+	if err := run(args, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := assets.ParseFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs, ok := fss["Web"]
+
+	if !ok {
+		t.Fatalf("expected file system Web, got %v", fss)
+	}
+
+	if data, err := fs.ReadFile("/static/app.js"); err != nil || string(data) != "console.log(\"hello\");\n" {
+		t.Errorf("unexpected /static/app.js %q (%v)", data, err)
+	}
+
+	if _, ok := fs.Files["/static/css/app.css"]; ok {
+		t.Errorf("expected css to be excluded")
+	}
+
+	if data, err := ioutil.ReadFile(filename); err != nil || !strings.Contains(string(data), "package web") {
+		t.Errorf("expected package web (%v)", err)
+	}
+
+	if err := run([]string{"generate", "-c", "lzma", "../../testdata"}, ioutil.Discard); err == nil {
+		t.Errorf("expected unknown compression to fail")
+	}
+}
+
+func TestGenerateConfig(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "assets.go")
+	config := fmt.Sprintf(`{"package": "web", "output": %q, "inputs": ["../../testdata"], "strip_prefix": "/testdata"}`, output)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "assets.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"generate", "-config", filepath.Join(dir, "assets.json")}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := assets.ParseFile(output)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fss["Assets"].Files["/static/app.js"]; !ok {
+		t.Errorf("expected /static/app.js to exist")
+	}
+
+	// YAML configs are supported out of the box
+	config = fmt.Sprintf("package: web\noutput: %q\ninputs:\n- ../../testdata\nstrip_prefix: /testdata\n", output)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "assets.yaml"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"generate", "-config", filepath.Join(dir, "assets.yaml")}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if fss, err = assets.ParseFile(output); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fss["Assets"].ReadFile("/static/app.js"); err != nil || string(data) != "console.log(\"hello\");\n" {
+		t.Errorf("unexpected /static/app.js %q (%v)", data, err)
+	}
+}
+
+func TestList(t *testing.T) {
+	dir := t.TempDir()
+	g := &assets.Generator{PackFile: "assets.pack"}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(dir, "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"ls", filename}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"SHA256", "/static/app.js", "-rw-"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected listing to contain %q, got:\n%s", expected, buf.String())
+		}
+	}
+
+	// The pack can be listed from a different location
+	pack := filepath.Join(t.TempDir(), "moved.pack")
+
+	if err := os.Rename(filepath.Join(dir, "assets.pack"), pack); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"ls", filename}, ioutil.Discard); err == nil {
+		t.Errorf("expected listing without the pack to fail")
+	}
+
+	var moved bytes.Buffer
+
+	if err := run([]string{"ls", "-pack", pack, filename}, &moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if moved.String() != buf.String() {
+		t.Errorf("expected identical listings, got:\n%s", moved.String())
+	}
+
+	// The pack can be listed on its own
+	var listing bytes.Buffer
+
+	if err := run([]string{"ls", pack}, &listing); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(pack)
+	digest := fmt.Sprintf("%x", data[len("GOASPACK"):len("GOASPACK")+sha256.Size])
+
+	for _, expected := range []string{"SHA256", digest, pack} {
+		if !strings.Contains(listing.String(), expected) {
+			t.Errorf("expected pack listing to contain %q, got:\n%s", expected, listing.String())
+		}
+	}
+
+	if err := ioutil.WriteFile(pack, []byte("GOASPACK corrupt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := run([]string{"ls", pack}, ioutil.Discard); err == nil {
+		t.Errorf("expected listing a corrupt pack to fail")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	g := &assets.Generator{}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+
+	if err := run([]string{"extract", "-o", dir, filename}, ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("../../testdata/static/css/app.css")
+
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "static", "css", "app.css")); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected extracted %q, got %q (%v)", expected, data, err)
+	}
+
+	if err := run([]string{"extract", filename}, ioutil.Discard); err == nil {
+		t.Errorf("expected extracting without an output directory to fail")
+	}
+}
+
+func TestCat(t *testing.T) {
+	g := &assets.Generator{Compressed: true}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata"}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"cat", "-f", filename, "/static/app.js", "/static/app.js"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "console.log(\"hello\");\nconsole.log(\"hello\");\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	if err := run([]string{"cat", "-f", filename, "/missing"}, ioutil.Discard); err == nil {
+		t.Errorf("expected reading a missing asset to fail")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	g := &assets.Generator{}
+
+	if err := g.AddRoot(assets.Root{Dir: "../../testdata", Exclude: []string{"*.css"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"diff", filename, "../../testdata"}, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := os.Stat("../../testdata/static/css/app.css")
+	expected := fmt.Sprintf("+ /static/css/app.css (+%d bytes)\n1 change, +%d bytes\n", info.Size(), info.Size())
+
+	if buf.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+
+	buf.Reset()
+
+	if err := run([]string{"diff", filename, filename}, &buf); err != nil || buf.String() != "0 changes, +0 bytes\n" {
+		t.Errorf("expected no changes, got %q (%v)", buf.String(), err)
+	}
+}
+
+func TestDiffModes(t *testing.T) {
+	src := t.TempDir()
+
+	// Modes as checked out with a umask of 002
+	if err := ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "run.sh"), []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	os.Chmod(filepath.Join(src, "a.txt"), 0664)
+	os.Chmod(filepath.Join(src, "run.sh"), 0775)
+
+	generators := []*assets.Generator{
+		{Hermetic: true},
+		{ModeOverrides: []assets.ModeOverride{{Pattern: "*.txt", Mode: 0600}, {Pattern: "*.sh", Mode: 0700}}},
+	}
+
+	var filenames []string
+
+	for i, g := range generators {
+		if err := g.AddRoot(assets.Root{Dir: src}); err != nil {
+			t.Fatal(err)
+		}
+
+		filename := filepath.Join(t.TempDir(), "assets.go")
+
+		if err := g.WriteFile(filename); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := run([]string{"diff", filename, src}, &buf); err != nil || buf.String() != "0 changes, +0 bytes\n" {
+			t.Errorf("%d: expected no changes, got %q (%v)", i, buf.String(), err)
+		}
+
+		filenames = append(filenames, filename)
+	}
+
+	// Files which are no longer executable are modified
+	os.Chmod(filepath.Join(src, "run.sh"), 0664)
+
+	var buf bytes.Buffer
+
+	if err := run([]string{"diff", filenames[0], src}, &buf); err != nil || buf.String() != "M /run.sh (+0 bytes)\n1 change, +0 bytes\n" {
+		t.Errorf("expected run.sh to be modified, got %q (%v)", buf.String(), err)
+	}
+}
@@ -0,0 +1,170 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A compression algorithm for asset data.
+type Compression int
+
+const (
+	// No compression, see Generator.CompressionPolicy
+	NoCompression Compression = iota - 1
+
+	// Gzip compression (the default)
+	Gzip
+
+	// Snappy compression, which compresses less than gzip but decompresses
+	// much faster. Use it for assets which are decompressed frequently at
+	// runtime (e.g. templates parsed per request). Snappy compressed assets
+	// are always decompressed before being served over HTTP.
+	Snappy
+)
+
+var compressionNames = map[Compression]string{
+	NoCompression: "NoCompression",
+	Gzip:          "Gzip",
+	Snappy:        "Snappy",
+}
+
+func (c Compression) String() string {
+	if name, ok := compressionNames[c]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("Compression(%d)", int(c))
+}
+
+// Implementation of encoding.TextMarshaler, such that compression algorithms
+// are written to configuration files by name.
+func (c Compression) MarshalText() ([]byte, error) {
+	if _, ok := compressionNames[c]; !ok {
+		return nil, fmt.Errorf("unknown compression %d", int(c))
+	}
+
+	return []byte(strings.ToLower(c.String())), nil
+}
+
+// Implementation of encoding.TextUnmarshaler, accepting the (case
+// insensitive) names of the compression algorithms and "none".
+func (c *Compression) UnmarshalText(text []byte) error {
+	name := string(text)
+
+	if strings.EqualFold(name, "none") {
+		*c = NoCompression
+		return nil
+	}
+
+	for k, v := range compressionNames {
+		if strings.EqualFold(name, v) {
+			*c = k
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown compression %q", name)
+}
+
+// The implementation of a compression algorithm.
+type Codec struct {
+	// The HTTP content coding of compressed data (e.g. gzip or br), used to
+	// serve compressed assets as is to clients accepting the coding. Empty
+	// if there is no content coding for the compression algorithm.
+	Encoding string
+
+	// Compress data at the given level, where 0 selects the default level.
+	Compress func(data []byte, level int) ([]byte, error)
+
+	// Open a reader on the decompressed data read from r.
+	Decompress func(r io.Reader) (io.ReadCloser, error)
+}
+
+// A map of compression algorithms to their implementation. Gzip and Snappy
+// are supported out of the box. The codecs may be replaced, for example to
+// use a faster gzip implementation, but must remain compatible with the data
+// of existing file systems.
+var Codecs = map[Compression]Codec{
+	Gzip: {
+		Encoding: "gzip",
+		Compress: gzipCompress,
+		Decompress: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	},
+	Snappy: snappyCodec,
+}
+
+func (c Compression) codec() (Codec, error) {
+	codec, ok := Codecs[c]
+
+	if !ok {
+		return codec, fmt.Errorf("%s compression is not available, register a codec in assets.Codecs", strings.ToLower(c.String()))
+	}
+
+	return codec, nil
+}
+
+// Compress data using the given compression algorithm and level.
+func compress(data []byte, c Compression, level int) ([]byte, error) {
+	codec, err := c.codec()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codec.Compress(data, level)
+}
+
+// Compress data using gzip with the given compression level, where 0 means
+// gzip.DefaultCompression.
+func gzipCompress(data []byte, level int) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	w, err := gzip.NewWriterLevel(&buf, level)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Check whether the Accept-Encoding header value of a request accepts the
+// given content coding.
+func acceptsEncoding(header string, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(part, ";")
+
+		if !strings.EqualFold(strings.TrimSpace(coding), encoding) {
+			continue
+		}
+
+		for _, param := range strings.Split(params, ";") {
+			if q, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				v, err := strconv.ParseFloat(q, 64)
+				return err == nil && v > 0
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,224 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Wrap the snappy codec, counting how often data is decompressed
+func countSnappyDecompressions(t *testing.T) *int {
+	codec := Codecs[Snappy]
+	decompressed := 0
+
+	Codecs[Snappy] = Codec{
+		Compress: codec.Compress,
+		Decompress: func(r io.Reader) (io.ReadCloser, error) {
+			decompressed++
+			return codec.Decompress(r)
+		},
+	}
+
+	t.Cleanup(func() {
+		Codecs[Snappy] = codec
+	})
+
+	return &decompressed
+}
+
+func TestCompressionUnavailable(t *testing.T) {
+	codec := Codecs[Snappy]
+	delete(Codecs, Snappy)
+	defer func() { Codecs[Snappy] = codec }()
+
+	g := &Generator{StripPrefix: "/testdata", Compressed: true, Compression: Snappy}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected compression to fail without a registered codec")
+	}
+}
+
+func TestServeCompressed(t *testing.T) {
+	dir := t.TempDir()
+	expected := strings.Repeat("console.log(\"hello\");\n", 100)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.js"), []byte(expected), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Compressed: true, Compression: Gzip}
+
+	if err := g.AddRoot(Root{Dir: dir, Mount: "/static"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if f := fs.Files["/static/app.js"]; f == nil || !f.Compressed || f.Compression != Gzip {
+		t.Fatalf("expected gzip compressed asset, got %v", f)
+	}
+
+	if data, err := fs.ReadFile("/static/app.js"); err != nil || string(data) != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+		body           string
+	}{
+		{"gzip, br", "gzip", string(fs.Files["/static/app.js"].Data)},
+		{"gzip;q=0, br", "", expected},
+		{"", "", expected},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("GET", "/static/app.js", nil)
+		r.Header.Set("Accept-Encoding", test.acceptEncoding)
+
+		w := httptest.NewRecorder()
+		fs.Handler().ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK || w.Header().Get("Content-Encoding") != test.encoding || w.Body.String() != test.body {
+			t.Errorf("%q: expected %q encoded %q, got %d %q encoded %q", test.acceptEncoding, test.encoding, test.body, w.Code, w.Header().Get("Content-Encoding"), w.Body.String())
+		}
+	}
+}
+
+func TestCompressedFileSystem(t *testing.T) {
+	compressed, err := gzipCompress([]byte("compressed"), -1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// File systems generated by older versions are compressed as a whole
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/a.txt", FileMode: 0644, Data: string(compressed)},
+	}, "")
+
+	fs.Compressed = true
+
+	if data, err := fs.ReadFile("/a.txt"); err != nil || string(data) != "compressed" {
+		t.Errorf("expected %q, got %q (%v)", "compressed", data, err)
+	}
+}
+
+func TestDecompressionLimit(t *testing.T) {
+	data := bytes.Repeat([]byte{0}, 1<<20)
+	compressed, err := gzipCompress(data, -1)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		maxSize  int64
+		maxRatio float64
+		err      error
+	}{
+		{0, 0, nil},
+		{1 << 20, 0, nil},
+		{1<<20 - 1, 0, ErrDecompressionLimit},
+		{0, 10, ErrDecompressionLimit},
+		{1 << 20, 10, ErrDecompressionLimit},
+	}
+
+	for _, test := range tests {
+		fs := NewFileSystemFromEntries([]FileEntry{
+			{Path: "/", FileMode: os.ModeDir | 0755},
+			{Path: "/zeros", FileMode: 0644, Data: string(compressed), Compressed: true},
+		}, "")
+
+		fs.MaxDecompressedSize = test.maxSize
+		fs.MaxExpansionRatio = test.maxRatio
+
+		actual, err := fs.ReadFile("/zeros")
+
+		if err != test.err {
+			t.Errorf("%d %v: expected %v, got %v", test.maxSize, test.maxRatio, test.err, err)
+		} else if err == nil && !bytes.Equal(actual, data) {
+			t.Errorf("%d %v: unexpected decompressed data", test.maxSize, test.maxRatio)
+		}
+	}
+}
+
+func TestCacheDecompressed(t *testing.T) {
+	decompressed := countSnappyDecompressions(t)
+
+	g := &Generator{Compressed: true, Compression: Snappy, CacheDecompressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata/templates/index.html"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if !bytes.Contains(src, []byte("Assets.CacheDecompressed = true")) {
+		t.Errorf("expected generated file caching decompressed data, got:\n%s", src)
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if !fs.CacheDecompressed {
+		t.Fatalf("expected parsed file system caching decompressed data")
+	}
+
+	for i := 0; i < 3; i++ {
+		data, err := fs.ReadFile("/templates/index.html")
+
+		if err != nil || !bytes.Equal(data, expected) {
+			t.Fatalf("expected %q, got %q (%v)", expected, data, err)
+		}
+
+		// Modifying the returned data must not affect the cache
+		data[0] = 0
+
+		f, err := fs.Open("/templates/index.html")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rd, err := f.(*File).Reader()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data, _ := ioutil.ReadAll(rd); !bytes.Equal(data, expected) {
+			t.Errorf("expected %q, got %q", expected, data)
+		}
+
+		rd.Close()
+		f.Close()
+	}
+
+	if *decompressed != 1 {
+		t.Errorf("expected data to be decompressed once, got %d", *decompressed)
+	}
+}
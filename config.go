@@ -0,0 +1,151 @@
+package assets
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+)
+
+// A map of file extensions to the unmarshal functions used by LoadConfig.
+// JSON and YAML (see UnmarshalYAML) are supported out of the box, other
+// formats can be supported by registering an unmarshal function for their
+// extension(s).
+var ConfigFormats = map[string]UnmarshalFunc{
+	".json": json.Unmarshal,
+	".yaml": UnmarshalYAML,
+	".yml":  UnmarshalYAML,
+}
+
+// A declarative description of an asset generation run.
+type Config struct {
+	// The package name to generate assets in.
+	Package string `json:"package"`
+
+	// The variable name containing the asset filesystem.
+	Variable string `json:"variable"`
+
+	// The go file to write the generated assets to.
+	Output string `json:"output"`
+
+	// The files and directories to add (see Generator.Add).
+	Inputs []string `json:"inputs"`
+
+	// Additional roots with their own path mapping (see Generator.AddRoot).
+	Roots []Root `json:"roots"`
+
+	// Glob patterns of paths to exclude (see Generator.Exclude).
+	Excludes []string `json:"excludes"`
+
+	// Glob patterns of test-only assets (see Generator.TestOnly).
+	TestOnly []string `json:"test_only"`
+
+	// The prefix to strip from all paths.
+	StripPrefix string `json:"strip_prefix"`
+
+	// Compress the asset data using gzip.
+	Compressed bool `json:"compressed"`
+
+	// The compression algorithm by name: none, gzip or snappy (see
+	// Compression). When set, it takes precedence over Compressed.
+	Compression *Compression `json:"compression,omitempty"`
+
+	// The compression level (see Generator.CompressionLevel).
+	CompressionLevel int `json:"compression_level"`
+
+	// The representation of the asset data in the generated code: string
+	// (the default) or bytes (see Generator.ByteSlices).
+	Data string `json:"data"`
+
+	// Logical asset keys mapping to asset paths (see Generator.AddKey).
+	Keys map[string]string `json:"keys"`
+}
+
+// Load a generation config from the file at path. The format is determined
+// by the file extension, see ConfigFormats. Relative paths in the config are
+// interpreted relative to the current working directory, which for
+// go:generate is the directory of the package being generated.
+func LoadConfig(path string) (*Config, error) {
+	unmarshal, ok := ConfigFormats[filepath.Ext(path)]
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported config format %s", filepath.Ext(path))
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+
+	if err := unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("%s: %s", path, err)
+	}
+
+	if len(c.Output) == 0 {
+		return nil, fmt.Errorf("%s: no output file specified", path)
+	}
+
+	return c, nil
+}
+
+// Create a generator from the config with all inputs added.
+func (c *Config) Generator() (*Generator, error) {
+	g := &Generator{
+		PackageName:      c.Package,
+		VariableName:     c.Variable,
+		StripPrefix:      c.StripPrefix,
+		Exclude:          c.Excludes,
+		TestOnly:         c.TestOnly,
+		Compressed:       c.Compressed,
+		CompressionLevel: c.CompressionLevel,
+	}
+
+	if c.Compression != nil {
+		g.Compressed = *c.Compression != NoCompression
+
+		if g.Compressed {
+			g.Compression = *c.Compression
+		}
+	}
+
+	switch c.Data {
+	case "", "string":
+	case "bytes":
+		g.ByteSlices = true
+	default:
+		return nil, fmt.Errorf("unsupported data representation %q (expected string or bytes)", c.Data)
+	}
+
+	for _, input := range c.Inputs {
+		if err := g.Add(input); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, root := range c.Roots {
+		if err := g.AddRoot(root); err != nil {
+			return nil, err
+		}
+	}
+
+	for key, p := range c.Keys {
+		g.AddKey(key, p)
+	}
+
+	return g, nil
+}
+
+// Run the generator described by the config, writing the generated assets to
+// the configured output file.
+func (c *Config) Generate() error {
+	g, err := c.Generator()
+
+	if err != nil {
+		return err
+	}
+
+	return g.WriteFile(c.Output)
+}
@@ -0,0 +1,80 @@
+package assets
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A file or directory which could not be embedded, see
+// Generator.ContinueOnError.
+type Failure struct {
+	// The path of the file in the generated file system
+	Path string
+
+	// The error reading the file
+	Err error
+}
+
+// The error returned by Write and WriteFile when files or directories could
+// not be embedded with Generator.ContinueOnError. The generated file has been
+// written without them.
+type PartialError struct {
+	Failures []Failure
+}
+
+func (e *PartialError) Error() string {
+	var msg strings.Builder
+
+	if len(e.Failures) == 1 {
+		msg.WriteString("1 file could not be embedded:")
+	} else {
+		fmt.Fprintf(&msg, "%d files could not be embedded:", len(e.Failures))
+	}
+
+	for _, f := range e.Failures {
+		fmt.Fprintf(&msg, "\n  %s: %s", f.Path, f.Err)
+	}
+
+	return msg.String()
+}
+
+// Get the errors of the failures, for errors.Is and errors.As.
+func (e *PartialError) Unwrap() []error {
+	ret := make([]error, len(e.Failures))
+
+	for i, f := range e.Failures {
+		ret[i] = f.Err
+	}
+
+	return ret
+}
+
+// Record that the directory at path p could not be read.
+func (x *Generator) fail(p string, err error) {
+	vp, ok := x.virtualPath(p)
+
+	if !ok {
+		vp = p
+	}
+
+	x.failures = append(x.failures, Failure{Path: vp, Err: err})
+}
+
+// Get the error reporting the directories which could not be added to the
+// generator and its groups, and the files which could not be written to the
+// data output, or nil if there are none.
+func (x *Generator) partialError(out *dataOutput) error {
+	var failures []Failure
+
+	for _, g := range append([]*Generator{x}, x.groups...) {
+		failures = append(failures, g.failures...)
+	}
+
+	failures = append(failures, out.failures...)
+
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return &PartialError{Failures: failures}
+}
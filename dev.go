@@ -0,0 +1,86 @@
+package assets
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+)
+
+// The function written to disk-backed files (see Generator.DevTag), which
+// gets the directory of the file at runtime such that asset sources can be
+// referenced relative to it.
+const devDirFunc = `// The directory of this file, which asset sources are relative to
+func _assetsDevDir() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Dir(filename)
+}
+`
+
+// Create a new file system from a table of file entries which reads the data
+// of assets from their source files at runtime, referenced by the Source of
+// each entry relative to dir (see Generator.DevTag). Changes to the contents
+// of the source files are visible without regenerating, while the set of
+// assets and their metadata remain those of the generation.
+func NewDevFileSystem(version int, entries []FileEntry, dir string) *FileSystem {
+	fs := NewVersionedFileSystem(version, entries, "")
+	fs.sources = make(map[string]string)
+
+	for _, e := range entries {
+		if len(e.Source) != 0 {
+			fs.sources[e.Path] = filepath.Join(dir, filepath.FromSlash(e.Source))
+		}
+	}
+
+	return fs
+}
+
+// Get the source of the file at generator path k written to the disk-backed
+// file, relative to its directory. Files which do not originate from disk
+// are embedded instead.
+func (x *Generator) devSource(k string, out *dataOutput) (string, bool) {
+	f := x.fsFilesMap[k]
+
+	if len(out.dev) == 0 || len(f.path) == 0 || f.data != nil || f.info.IsDir() {
+		return "", false
+	}
+
+	src, err := filepath.Abs(f.path)
+
+	if err != nil {
+		return "", false
+	}
+
+	if rel, err := filepath.Rel(out.dev, src); err == nil {
+		src = rel
+	}
+
+	return filepath.ToSlash(src), true
+}
+
+// Get the name of the disk-backed file belonging to filename, see DevTag.
+func devFilename(filename string, tag string) string {
+	return strings.TrimSuffix(filename, ".go") + "_" + tag + ".go"
+}
+
+// Write the disk-backed version of the file systems belonging to filename,
+// see DevTag.
+func (x *Generator) writeDevFile(filename string) error {
+	dir, err := filepath.Abs(filepath.Dir(filename))
+
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	out := &dataOutput{
+		dev:        dir,
+		constraint: x.DevTag,
+	}
+
+	if err := x.write(&buf, x.fileSystems(), &Stats{}, out); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(devFilename(filename, x.DevTag), buf.Bytes())
+}
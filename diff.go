@@ -0,0 +1,141 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// The kind of a change between two file systems, see DiffFS.
+type ChangeKind int
+
+const (
+	// The path only exists in the new file system
+	Added ChangeKind = iota
+
+	// The path only exists in the old file system
+	Removed
+
+	// The contents or the mode of the file at the path changed, or a file
+	// was replaced by a directory (or vice versa)
+	Modified
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Modified:
+		return "modified"
+	}
+
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// A change of a single path between two file systems, see DiffFS.
+type Change struct {
+	Kind ChangeKind
+
+	// The path of the changed file or directory
+	Path string
+
+	// The (decompressed) sizes of the file in the old and the new file
+	// system, zero for directories and missing files
+	OldSize int64
+	NewSize int64
+}
+
+// Get the change in size of the file in bytes.
+func (c Change) Delta() int64 {
+	return c.NewSize - c.OldSize
+}
+
+// Compare two file systems (e.g. parsed from the generated files of two
+// releases), returning the paths which were added, removed or modified in
+// newer compared to older, sorted by path. Files are compared by their
+// decompressed contents and their modes, modification times are ignored.
+func DiffFS(older *FileSystem, newer *FileSystem) ([]Change, error) {
+	var ret []Change
+
+	for _, p := range sortedKeys(older.Files) {
+		o := older.Files[p]
+		n, ok := newer.Files[p]
+
+		if !ok {
+			size, err := diffSize(older, o)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, Change{Kind: Removed, Path: p, OldSize: size})
+			continue
+		}
+
+		if o.IsDir() && n.IsDir() {
+			continue
+		}
+
+		c := Change{Kind: Modified, Path: p}
+
+		var odata, ndata []byte
+		var err error
+
+		if !o.IsDir() {
+			if odata, err = older.ReadFile(p); err != nil {
+				return nil, err
+			}
+		}
+
+		if !n.IsDir() {
+			if ndata, err = newer.ReadFile(p); err != nil {
+				return nil, err
+			}
+		}
+
+		if o.IsDir() == n.IsDir() && o.Mode() == n.Mode() && bytes.Equal(odata, ndata) {
+			continue
+		}
+
+		c.OldSize, c.NewSize = int64(len(odata)), int64(len(ndata))
+		ret = append(ret, c)
+	}
+
+	for _, p := range sortedKeys(newer.Files) {
+		if _, ok := older.Files[p]; ok {
+			continue
+		}
+
+		size, err := diffSize(newer, newer.Files[p])
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, Change{Kind: Added, Path: p, NewSize: size})
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		return ret[i].Path < ret[j].Path
+	})
+
+	return ret, nil
+}
+
+// Get the decompressed size of the file fi of the file system fs, or zero
+// for directories.
+func diffSize(fs *FileSystem, fi *File) (int64, error) {
+	if fi.IsDir() {
+		return 0, nil
+	}
+
+	data, err := fs.ReadFile(fi.Path)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(len(data)), nil
+}
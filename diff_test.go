@@ -0,0 +1,51 @@
+package assets
+
+import (
+	"testing"
+)
+
+func TestDiffFS(t *testing.T) {
+	a, b := mergeTestFileSystems()
+	changes, err := DiffFS(a, b)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Change{
+		{Kind: Removed, Path: "/a.css", OldSize: 1},
+		{Kind: Modified, Path: "/app.js", OldSize: 1, NewSize: 1},
+		{Kind: Added, Path: "/b.css", NewSize: 1},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, changes)
+	}
+
+	for i, c := range changes {
+		if c != expected[i] {
+			t.Errorf("expected %v, got %v", expected[i], c)
+		}
+	}
+
+	if changes, err := DiffFS(a, a); err != nil || len(changes) != 0 {
+		t.Errorf("expected no changes, got %v (%v)", changes, err)
+	}
+}
+
+func TestDiffFSMode(t *testing.T) {
+	a, _ := mergeTestFileSystems()
+	b, _ := mergeTestFileSystems()
+
+	b.Files["/app.js"].FileMode = 0755
+
+	changes, err := DiffFS(a, b)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := (Change{Kind: Modified, Path: "/app.js", OldSize: 1, NewSize: 1}); len(changes) != 1 || changes[0] != expected {
+		t.Errorf("expected %v, got %v", expected, changes)
+	}
+}
@@ -0,0 +1,366 @@
+package assets
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+// The path at which DocSite serves search results.
+const DocSearchPath = "/_search"
+
+// The default layout of documentation sites, see DocSite.Layout.
+var DefaultDocLayout = template.Must(template.New("layout").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}} - {{end}}{{.SiteTitle}}</title>
+</head>
+<body>
+<nav>
+<a href="{{.HomeURL}}">{{.SiteTitle}}</a>
+<form action="{{.SearchURL}}"><input type="search" name="q" value="{{.Query}}"></form>
+{{template "nav" .Nav}}
+</nav>
+<main>
+{{if .Results}}<ul>{{range .Results}}<li><a href="{{.URL}}">{{.Title}}</a><p>{{.Excerpt}}</p></li>{{end}}</ul>
+{{else if .Query}}<p>No results for {{.Query}}</p>
+{{end}}{{.Content}}
+</main>
+</body>
+</html>
+{{define "nav"}}{{if .}}<ul>{{range .}}<li>{{if .Page}}<a href="{{.URL}}"{{if .Current}} aria-current="page"{{end}}>{{.Title}}</a>{{else}}{{.Title}}{{end}}{{template "nav" .Children}}</li>{{end}}</ul>{{end}}{{end}}`))
+
+// An HTTP handler serving an asset tree as a documentation site. Pages (see
+// PageExtensions) are rendered into the layout together with a navigation
+// tree built from the directory structure (see FileSystem.Navigation), a
+// request for a directory renders its index page and DocSearchPath serves
+// search results for the q query parameter. All other assets are served by
+// Handler.
+type DocSite struct {
+	// The file system containing the documentation.
+	FS *FileSystem
+
+	// The title of the site.
+	Title string
+
+	// The file extensions of pages (defaults to .md).
+	PageExtensions []string
+
+	// Render the source of the page at path p to HTML, for example using a
+	// markdown renderer. By default, the source is shown as preformatted
+	// text.
+	Render func(p string, source []byte) (template.HTML, error)
+
+	// The layout template, executed with a DocPage (defaults to
+	// DefaultDocLayout).
+	Layout *template.Template
+
+	// The path prefix the site is served at (e.g. /docs when using
+	// http.StripPrefix), prepended to the links of the site.
+	Prefix string
+
+	// The configuration used to serve assets other than pages.
+	ServerConfig
+}
+
+// The data a DocSite layout is executed with.
+type DocPage struct {
+	// The title of the site.
+	SiteTitle string
+
+	// The title and path of the page, empty for search results.
+	Title string
+	Path  string
+
+	// The rendered page.
+	Content template.HTML
+
+	// The navigation tree of the site.
+	Nav []DocNavItem
+
+	// The URLs of the home page and of search requests.
+	HomeURL   string
+	SearchURL string
+
+	// The search query and its results, for search results.
+	Query   string
+	Results []DocSearchResult
+}
+
+// An entry of the navigation tree of a DocSite.
+type DocNavItem struct {
+	// The title and asset path of the entry, and the URL it links to.
+	Title string
+	Path  string
+	URL   string
+
+	// Whether the entry links to a page, rather than a directory without
+	// index page.
+	Page bool
+
+	// Whether the entry is the page being shown.
+	Current bool
+
+	Children []DocNavItem
+}
+
+// A page matching a search query.
+type DocSearchResult struct {
+	Title string
+	Path  string
+	URL   string
+
+	// The text around the first match in the page.
+	Excerpt string
+}
+
+// Create a new documentation site serving the pages of the file system.
+func (f *FileSystem) DocSite(title string) *DocSite {
+	return &DocSite{FS: f, Title: title}
+}
+
+func (d *DocSite) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := path.Clean("/" + r.URL.Path)
+
+	if p == DocSearchPath {
+		q := strings.TrimSpace(r.URL.Query().Get("q"))
+
+		d.render(w, &DocPage{
+			Title:   q,
+			Query:   q,
+			Results: d.search(q),
+		})
+
+		return
+	}
+
+	f, ok := d.FS.Files[p]
+
+	if ok && f.IsDir() {
+		f, ok = d.FS.Files[d.index(p)]
+	}
+
+	if !ok || f.IsDir() || f.IsPrivate() || !d.isPage(f.Path) {
+		(&Handler{FS: d.FS, ServerConfig: d.ServerConfig}).ServeHTTP(w, r)
+		return
+	}
+
+	source, err := d.FS.ReadFile(f.Path)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	render := d.Render
+
+	if render == nil {
+		render = renderPreformatted
+	}
+
+	content, err := render(f.Path, source)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	d.render(w, &DocPage{
+		Title:   docTitle(f),
+		Path:    f.Path,
+		Content: content,
+	})
+}
+
+func (d *DocSite) render(w http.ResponseWriter, page *DocPage) {
+	layout := d.Layout
+
+	if layout == nil {
+		layout = DefaultDocLayout
+	}
+
+	page.SiteTitle = d.Title
+	page.HomeURL = d.url("/")
+	page.SearchURL = d.url(DocSearchPath)
+	page.Nav = d.nav("/", page.Path)
+
+	var buf bytes.Buffer
+
+	if err := layout.Execute(&buf, page); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// Get the URL of the asset at p.
+func (d *DocSite) url(p string) string {
+	if len(d.Prefix) == 0 {
+		return p
+	}
+
+	return path.Join("/", d.Prefix, p)
+}
+
+func renderPreformatted(p string, source []byte) (template.HTML, error) {
+	return template.HTML("<pre>" + template.HTMLEscapeString(string(source)) + "</pre>"), nil
+}
+
+func (d *DocSite) isPage(p string) bool {
+	exts := d.PageExtensions
+
+	if len(exts) == 0 {
+		exts = []string{".md"}
+	}
+
+	return containsString(exts, path.Ext(p))
+}
+
+// Get the path of the index page of the directory dir, or dir itself if it
+// has no index page.
+func (d *DocSite) index(dir string) string {
+	for _, name := range d.FS.Dirs[dir] {
+		p := path.Join(dir, name)
+
+		if strings.TrimSuffix(name, path.Ext(name)) == "index" && d.isPage(p) {
+			return p
+		}
+	}
+
+	return dir
+}
+
+// Build the navigation tree of the directory dir, marking the page at
+// current.
+func (d *DocSite) nav(dir string, current string) []DocNavItem {
+	files, _ := d.FS.Navigation(dir)
+
+	var ret []DocNavItem
+
+	for _, f := range files {
+		if f.IsPrivate() {
+			continue
+		}
+
+		item := DocNavItem{
+			Title: docTitle(f),
+			Path:  f.Path,
+		}
+
+		if f.IsDir() {
+			index := d.index(f.Path)
+
+			item.Children = d.nav(f.Path, current)
+			item.Page = index != f.Path
+			item.Current = index == current
+
+			if len(item.Children) == 0 && !item.Page {
+				continue
+			}
+		} else if d.isPage(f.Path) && f.Path != d.index(dir) {
+			item.Page = true
+			item.Current = f.Path == current
+		} else {
+			// Index pages are represented by their directory, other
+			// assets are not part of the navigation
+			continue
+		}
+
+		item.URL = d.url(item.Path)
+		ret = append(ret, item)
+	}
+
+	return ret
+}
+
+// Search the pages for the query, case insensitively. Pages matching in their
+// title are listed before pages matching in their contents.
+func (d *DocSite) search(q string) []DocSearchResult {
+	if len(q) == 0 {
+		return nil
+	}
+
+	q = strings.ToLower(q)
+
+	var titles, contents []DocSearchResult
+
+	for _, p := range sortedKeys(d.FS.Files) {
+		f := d.FS.Files[p]
+
+		if f.IsDir() || f.IsPrivate() || !d.isPage(p) {
+			continue
+		}
+
+		source, err := d.FS.ReadFile(p)
+
+		if err != nil {
+			continue
+		}
+
+		result := DocSearchResult{Title: docTitle(f), Path: p, URL: d.url(p)}
+		text := string(source)
+
+		if i := strings.Index(strings.ToLower(text), q); i >= 0 {
+			result.Excerpt = excerpt(text, i, len(q))
+		}
+
+		if strings.Contains(strings.ToLower(result.Title), q) {
+			titles = append(titles, result)
+		} else if len(result.Excerpt) != 0 {
+			contents = append(contents, result)
+		}
+	}
+
+	return append(titles, contents...)
+}
+
+// Get an excerpt of text around the match of length n at offset i.
+func excerpt(text string, i int, n int) string {
+	const context = 60
+
+	if i > len(text) {
+		i = len(text)
+	}
+
+	start, end := i-context, i+n+context
+
+	if start < 0 {
+		start = 0
+	}
+
+	if end > len(text) {
+		end = len(text)
+	}
+
+	// Avoid cutting multi-byte characters
+	for start > 0 && !utf8.RuneStart(text[start]) {
+		start--
+	}
+
+	for end < len(text) && !utf8.RuneStart(text[end]) {
+		end++
+	}
+
+	return strings.Join(strings.Fields(text[start:end]), " ")
+}
+
+// Get the title of a page or directory: its title from the metadata files,
+// or its name without extension.
+func docTitle(f *File) string {
+	if len(f.Title) != 0 {
+		return f.Title
+	}
+
+	if f.Path == "/" {
+		return ""
+	}
+
+	name := f.Name()
+	return strings.TrimSuffix(name, path.Ext(name))
+}
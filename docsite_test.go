@@ -0,0 +1,68 @@
+package assets
+
+import (
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDocSite(t *testing.T) {
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/index.md", FileMode: 0644, Data: "Welcome"},
+		{Path: "/guide", FileMode: os.ModeDir | 0755, Title: "User guide"},
+		{Path: "/guide/setup.md", FileMode: 0644, Data: "Run the installer", Order: 1},
+		{Path: "/guide/intro.md", FileMode: 0644, Data: "Read <this> first", Title: "Introduction"},
+		{Path: "/guide/logo.png", FileMode: 0644, Data: "png"},
+		{Path: "/secret.md", FileMode: 0644, Data: "installer secret", Tags: []string{PrivateTag}},
+	}, "")
+
+	site := fs.DocSite("Docs")
+	site.Prefix = "/docs"
+
+	tests := []struct {
+		path     string
+		contains []string
+		absent   []string
+	}{
+		{"/", []string{"Welcome", `href="/docs/guide/setup.md"`, "User guide"}, []string{"secret", "logo"}},
+		{"/guide/intro.md", []string{"Read &lt;this&gt; first", `aria-current="page">Introduction`}, nil},
+		{"/_search?q=INSTALLER", []string{`<a href="/docs/guide/setup.md">setup</a>`, "Run the installer"}, []string{"secret"}},
+		{"/guide/logo.png", []string{"png"}, []string{"<nav>"}},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		site.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+
+		body := w.Body.String()
+
+		for _, s := range test.contains {
+			if !strings.Contains(body, s) {
+				t.Errorf("%s: expected response to contain %q, got:\n%s", test.path, s, body)
+			}
+		}
+
+		for _, s := range test.absent {
+			if strings.Contains(body, s) {
+				t.Errorf("%s: expected response to not contain %q", test.path, s)
+			}
+		}
+	}
+
+	// Ordered pages come first
+	body := httptest.NewRecorder()
+	site.ServeHTTP(body, httptest.NewRequest("GET", "/", nil))
+
+	if s := body.Body.String(); strings.Index(s, "setup") > strings.Index(s, "Introduction") {
+		t.Errorf("expected ordered pages to come first")
+	}
+
+	w := httptest.NewRecorder()
+	site.ServeHTTP(w, httptest.NewRequest("GET", "/secret.md", nil))
+
+	if w.Code != 404 {
+		t.Errorf("expected private page to not be served, got %d", w.Code)
+	}
+}
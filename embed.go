@@ -0,0 +1,143 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// The asset data written to the embedded directory, see Generator.EmbedDir.
+type embedWriter struct {
+	// The directory written to the generated code, relative to the
+	// generated file
+	dir string
+
+	// The data of the files in the directory by name
+	files map[string][]byte
+}
+
+// Add data to the embedded directory, returning the name of its file. Files
+// are named after the SHA-256 digest of their data, which avoids restrictions
+// of go:embed on file names and stores identical data once.
+func (e *embedWriter) add(data []byte) string {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+
+	if e.files == nil {
+		e.files = make(map[string][]byte)
+	}
+
+	e.files[name] = data
+	return name
+}
+
+// Write the declarations embedding the files of the directory to w. Each file
+// is embedded in a []byte variable, which unlike reading from an embed.FS
+// does not copy the data at runtime, and the variables are collected in the
+// _assetsEmbed map passed to NewEmbedFileSystem.
+func (e *embedWriter) writeVars(w io.Writer) {
+	names := make([]string, 0, len(e.files))
+
+	for name := range e.files {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "//go:embed %s\n", path.Join(e.dir, name))
+		fmt.Fprintf(w, "var _assetsEmbed%s []byte\n\n", name)
+	}
+
+	fmt.Fprintln(w, "var _assetsEmbed = map[string][]byte{")
+
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%q: _assetsEmbed%s,\n", name, name)
+	}
+
+	fmt.Fprintln(w, "}")
+}
+
+// Write the files to the directory dir, leaving unchanged files alone and
+// removing files of previous generations which are no longer needed.
+func (e *embedWriter) writeDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for name, data := range e.files {
+		filename := filepath.Join(dir, name)
+
+		if existing, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			return err
+		}
+	}
+
+	existing, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		return err
+	}
+
+	for _, info := range existing {
+		name := info.Name()
+
+		if _, ok := e.files[name]; ok || !isEmbedName(name) || info.IsDir() {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check whether name is the name of a file written by embedWriter.
+func isEmbedName(name string) bool {
+	if len(name) != 2*sha256.Size {
+		return false
+	}
+
+	_, err := hex.DecodeString(name)
+	return err == nil
+}
+
+// Create a new file system from a table of file entries whose data is stored
+// in the files of an embedded directory (see Generator.EmbedDir), referenced
+// by the EmbedName of each entry. The files map the names of the files in
+// the embedded directory dir to their data, which the generated code embeds
+// in []byte variables. The file system references this data rather than
+// copying it, such that asset data is only paged in from the executable when
+// it is read.
+func NewEmbedFileSystem(version int, entries []FileEntry, files map[string][]byte, dir string) *FileSystem {
+	for i := range entries {
+		e := &entries[i]
+
+		if len(e.EmbedName) == 0 {
+			continue
+		}
+
+		data, ok := files[e.EmbedName]
+
+		if !ok {
+			panic(fmt.Sprintf("assets: missing embedded data of %s in %s", e.Path, dir))
+		}
+
+		e.Bytes = data
+	}
+
+	return NewVersionedFileSystem(version, entries, "")
+}
@@ -0,0 +1,295 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A source file or directory of an embedded asset, see VerifySources.
+type SourceEntry struct {
+	// The path of the asset in the file system
+	Path string
+
+	// The path of the source relative to the directory passed to
+	// VerifySources, slash separated
+	Source string
+
+	// The file mode of the source file at generation time. Only the file
+	// type and whether the file is executable are verified, since the
+	// remaining permissions depend on the umask of the machine the sources
+	// were checked out on. Zero for directories and for files whose mode is
+	// set by Generator.ModeOverrides, which are not verified
+	FileMode os.FileMode
+
+	// The names of the entries of a source directory which were not
+	// embedded at generation time (e.g. excluded files)
+	Ignored []string
+
+	// The asset data is derived from the source by a transform (see
+	// Generator.Transforms), such that the data is not compared
+	Transformed bool
+}
+
+// Verify that the assets of the file system still match their sources, which
+// are given relative to dir. VerifySources checks that the sources still
+// exist with the same file type and executable bit, that the data of the assets equals the data
+// of their sources and that source directories contain no files which have
+// not been embedded. The first mismatch found is returned. This is used by the
+// tests written with Generator.EmbeddedTest.
+func VerifySources(fs *FileSystem, dir string, sources []SourceEntry) error {
+	known := make(map[string]bool, len(sources))
+
+	for _, e := range sources {
+		known[e.Source] = true
+	}
+
+	for _, e := range sources {
+		src := filepath.Join(dir, filepath.FromSlash(e.Source))
+		info, err := os.Lstat(src)
+
+		if err != nil {
+			return fmt.Errorf("source of asset %s: %s", e.Path, err)
+		}
+
+		if e.FileMode != 0 && (info.Mode().Type() != e.FileMode.Type() || isExecutable(info.Mode()) != isExecutable(e.FileMode)) {
+			return fmt.Errorf("mode of %s changed from %s to %s", e.Source, e.FileMode, info.Mode())
+		}
+
+		if fi, ok := fs.Files[e.Path]; !ok || fi.IsDir() != info.IsDir() {
+			return fmt.Errorf("asset %s of %s is missing", e.Path, e.Source)
+		}
+
+		if e.Transformed {
+			continue
+		}
+
+		if info.IsDir() {
+			names, err := readDirNames(src)
+
+			if err != nil {
+				return err
+			}
+
+			for _, name := range names {
+				if !known[path.Join(e.Source, name)] && !containsString(e.Ignored, name) {
+					return fmt.Errorf("%s is not embedded", path.Join(e.Source, name))
+				}
+			}
+
+			continue
+		}
+
+		data, err := ioutil.ReadFile(src)
+
+		if err != nil {
+			return err
+		}
+
+		embedded, err := fs.ReadFile(e.Path)
+
+		if err != nil {
+			return fmt.Errorf("asset %s: %s", e.Path, err)
+		}
+
+		if !bytes.Equal(data, embedded) {
+			return fmt.Errorf("asset %s does not match %s", e.Path, e.Source)
+		}
+	}
+
+	return nil
+}
+
+// Check whether the mode of a file allows anyone to execute it.
+func isExecutable(mode os.FileMode) bool {
+	return mode&0111 != 0
+}
+
+// Get the names of the entries of the directory dir.
+func readDirNames(dir string) ([]string, error) {
+	fd, err := os.Open(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+	return fd.Readdirnames(-1)
+}
+
+// Write a test verifying that the embedded assets still match their source
+// files (see VerifySources) to the given writer. The written go file is
+// meant to be saved as a _test.go file in the package of the assets
+// generated by Write, located in dir. The test of each file system is named
+// after the file system (e.g. TestAssetsEmbedded). Assets which do not
+// originate from disk are not verified.
+func (x *Generator) WriteEmbeddedTest(wr io.Writer, dir string) error {
+	if x.SelfContained {
+		return fmt.Errorf("cannot combine SelfContained and embedded tests")
+	}
+
+	dir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return err
+	}
+
+	banner, err := x.banner()
+
+	if err != nil {
+		return err
+	}
+
+	writer := &bytes.Buffer{}
+
+	fmt.Fprint(writer, banner)
+	fmt.Fprintf(writer, "package %s\n\n", x.packageName())
+	fmt.Fprintln(writer, "import (")
+	fmt.Fprintln(writer, "\t\"testing\"")
+	fmt.Fprintln(writer)
+
+	if len(x.RuntimeImport) != 0 {
+		fmt.Fprintf(writer, "\tassets %q\n", x.RuntimeImport)
+	} else {
+		fmt.Fprintf(writer, "\t%q\n", DefaultRuntimeImport)
+	}
+
+	fmt.Fprintln(writer, ")")
+
+	for _, g := range x.fileSystems() {
+		variableName := g.VariableName
+
+		if len(variableName) == 0 {
+			variableName = "Assets"
+		}
+
+		entries, err := g.sourceEntries(dir)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(writer)
+		fmt.Fprintf(writer, "func Test%sEmbedded(t *testing.T) {\n", variableName)
+		fmt.Fprintf(writer, "\terr := assets.VerifySources(%s, \".\", []assets.SourceEntry{\n", variableName)
+
+		for _, e := range entries {
+			fmt.Fprintf(writer, "\t\t%s,\n", e)
+		}
+
+		fmt.Fprintln(writer, "\t})")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "\tif err != nil {")
+		fmt.Fprintln(writer, "\t\tt.Error(err)")
+		fmt.Fprintln(writer, "\t}")
+		fmt.Fprintln(writer, "}")
+	}
+
+	ret, err := x.formatSource(writer.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(ret)
+	return err
+}
+
+// Get the composite literals of the source entries of the assets originating
+// from disk, with sources relative to dir.
+func (x *Generator) sourceEntries(dir string) ([]string, error) {
+	var ret []string
+
+	for _, k := range x.sortedPaths() {
+		f := x.fsFilesMap[k]
+		vp, ok := x.virtualPath(k)
+
+		if !ok || len(f.path) == 0 || f.data != nil {
+			continue
+		}
+
+		src, err := filepath.Abs(f.path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if rel, err := filepath.Rel(dir, src); err == nil {
+			src = rel
+		}
+
+		var entry strings.Builder
+
+		if f.info.IsDir() {
+			fmt.Fprintf(&entry, "{Path: %#v, Source: %#v", vp, filepath.ToSlash(src))
+
+			names, err := readDirNames(f.path)
+
+			if err != nil {
+				return nil, err
+			}
+
+			var ignored []string
+
+			for _, name := range names {
+				if !containsString(x.fsDirsMap[k], name) {
+					ignored = append(ignored, name)
+				}
+			}
+
+			if len(ignored) != 0 {
+				sort.Strings(ignored)
+				fmt.Fprintf(&entry, ", Ignored: %#v", ignored)
+			}
+		} else {
+			data, err := x.read(k)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if x.fingerprinted(k) {
+				vp = fingerprintPath(vp, fingerprintOf(data))
+			}
+
+			fmt.Fprintf(&entry, "{Path: %#v, Source: %#v", vp, filepath.ToSlash(src))
+
+			// The mode of overridden files does not derive from the source
+			if _, ok := x.modeOverride(k); !ok {
+				fmt.Fprintf(&entry, ", FileMode: %#v", f.info.Mode())
+			}
+
+			if x.transformed(k) {
+				entry.WriteString(", Transformed: true")
+			}
+		}
+
+		entry.WriteString("}")
+		ret = append(ret, entry.String())
+	}
+
+	return ret, nil
+}
+
+// Get the name of the file the embedded test is written to by WriteFile for
+// assets written to filename, see EmbeddedTest.
+func embeddedTestFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_embedded_test.go"
+}
+
+// Write the embedded test belonging to filename, see EmbeddedTest.
+func (x *Generator) writeEmbeddedTestFile(filename string) error {
+	var buf bytes.Buffer
+
+	if err := x.WriteEmbeddedTest(&buf, filepath.Dir(filename)); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(embeddedTestFilename(filename), buf.Bytes())
+}
@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The environment variable holding the hex encoded key of encrypted assets,
+// used when a file system has no DecryptionKey function.
+const KeyEnv = "GO_ASSETS_KEY"
+
+// The error returned when reading encrypted assets without a key, see
+// FileSystem.DecryptionKey.
+var ErrNoDecryptionKey = errors.New("no decryption key for encrypted assets")
+
+// Create the AES-GCM cipher of key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// Encrypt data using AES-GCM, prepending the nonce. The nonce is derived from
+// the key and the data such that generating the same assets produces the same
+// output, at the cost of revealing which assets have identical data.
+func encryptData(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+
+	nonce := mac.Sum(nil)[:gcm.NonceSize()]
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
+
+// Decrypt data encrypted by encryptData.
+func decryptData(key []byte, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted asset data is truncated")
+	}
+
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// Get the key of the encrypted assets of the file system.
+func (f *FileSystem) key() ([]byte, error) {
+	if f.DecryptionKey != nil {
+		return f.DecryptionKey()
+	}
+
+	env := os.Getenv(KeyEnv)
+
+	if len(env) == 0 {
+		return nil, ErrNoDecryptionKey
+	}
+
+	key, err := hex.DecodeString(env)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", KeyEnv, err)
+	}
+
+	return key, nil
+}
+
+// Get the stored asset data, decrypting it on first access if the asset is
+// encrypted. Like Data, the returned data may be compressed and must not be
+// modified.
+func (f *File) data() ([]byte, error) {
+	if !f.Encrypted {
+		return f.stored()
+	}
+
+	if f.fs == nil {
+		return nil, ErrNoDecryptionKey
+	}
+
+	file := f
+
+	if f.orig != nil {
+		file = f.orig
+	}
+
+	if v, ok := f.fs.decrypted.Load(file); ok {
+		return v.([]byte), nil
+	}
+
+	// Failures are not cached, the key may become available later
+	key, err := f.fs.key()
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := file.stored()
+
+	if err == nil {
+		data, err = decryptData(key, data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file.Path, err)
+	}
+
+	v, _ := f.fs.decrypted.LoadOrStore(file, data)
+	return v.([]byte), nil
+}
@@ -0,0 +1,90 @@
+// Command examples demonstrates the typical use of go-assets: selecting and
+// compressing assets from a directory, overlaying generated assets, and
+// serving the result. Run it from the repository root:
+//
+//	go run ./examples -dir testdata -listen :8080
+//
+// and browse to http://localhost:8080/assets/. When -output is given, the
+// assets are also generated into a go file, as go:generate would do.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/jessevdk/go-assets"
+)
+
+// Build the asset file system from the directory dir, optionally generating
+// it into a go file at output, and create a mux serving it below /assets/.
+func run(dir string, output string) (*http.ServeMux, error) {
+	g := &assets.Generator{
+		PackageName: "main",
+
+		// Leave out source maps and hidden files
+		Exclude: []string{"*.map", ".*"},
+
+		Compressed: true,
+	}
+
+	if err := g.AddRoot(assets.Root{Dir: dir}); err != nil {
+		return nil, err
+	}
+
+	if len(output) != 0 {
+		if err := g.WriteFile(output); err != nil {
+			return nil, err
+		}
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Overlay assets created by the application itself
+	overlay := assets.NewFileSystemFromEntries([]assets.FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/version.txt", FileMode: 0644, Mtime: time.Now().UnixNano(), Data: "example\n"},
+	}, "")
+
+	if fs, err = assets.MergeFS(fs, overlay, assets.ConflictPreferB); err != nil {
+		return nil, err
+	}
+
+	return assets.NewServeMux("/assets/", fs, &assets.ServerConfig{
+		Cache: []assets.CacheRule{
+			{Pattern: "*.css", CacheControl: "public, max-age=3600"},
+			{Pattern: "*.js", CacheControl: "public, max-age=3600"},
+		},
+		Headers: map[string]string{
+			"X-Content-Type-Options": "nosniff",
+		},
+	})
+}
+
+func main() {
+	dir := flag.String("dir", "testdata", "the directory to serve assets from")
+	output := flag.String("output", "", "generate the assets into this go file")
+	listen := flag.String("listen", ":8080", "the address to listen on")
+
+	flag.Parse()
+
+	mux, err := run(*dir, *output)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Serving %s at http://%s/assets/\n", *dir, *listen)
+
+	if err := http.ListenAndServe(*listen, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jessevdk/go-assets"
+)
+
+func TestRun(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "assets.go")
+	mux, err := run("../testdata", output)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		body string
+	}{
+		{"/assets/static/app.js", "console.log(\"hello\");\n"},
+		{"/assets/version.txt", "example\n"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+
+		if w.Code != http.StatusOK || w.Body.String() != test.body {
+			t.Errorf("%s: expected %q, got %d %q", test.path, test.body, w.Code, w.Body.String())
+		}
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		t.Errorf("expected assets to be generated: %s", err)
+	}
+
+	fss, err := assets.ParseFile(output)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fss["Assets"].Files["/templates/index.html"]; !ok {
+		t.Errorf("expected generated assets to contain /templates/index.html")
+	}
+}
@@ -0,0 +1,82 @@
+package assets
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Write the assets of the file system to the directory dir, restoring their
+// file modes (including the executable bit of embedded scripts and binaries)
+// and modification times. Existing files are overwritten. Directories are
+// given their modes after all files have been written, such that read-only
+// directories can be extracted.
+func (f *FileSystem) Extract(dir string) error {
+	var dirs []*File
+
+	for _, p := range sortedKeys(f.Files) {
+		fi := f.Files[p]
+		target := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+p)))
+
+		if fi.IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+
+			dirs = append(dirs, fi)
+			continue
+		}
+
+		data, err := f.ReadFile(p)
+
+		if err != nil {
+			return err
+		}
+
+		if err := WriteFileMode(target, data, fi); err != nil {
+			return err
+		}
+	}
+
+	// Parents are sorted before their children
+	for i := len(dirs) - 1; i >= 0; i-- {
+		target := filepath.Join(dir, filepath.FromSlash(path.Clean("/"+dirs[i].Path)))
+
+		if err := restoreMode(target, dirs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Write data to the file at filename, giving it the file mode and
+// modification time of the asset info. Unlike ioutil.WriteFile, the
+// permissions of the written file are not subject to the umask, such that
+// executable assets stay executable.
+func WriteFileMode(filename string, data []byte, info os.FileInfo) error {
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	if err := ioutil.WriteFile(filename, data, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return restoreMode(filename, info)
+}
+
+// Restore the permissions and modification time of info on the file at
+// filename.
+func restoreMode(filename string, info os.FileInfo) error {
+	if err := os.Chmod(filename, info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	if mtime := info.ModTime(); !mtime.IsZero() {
+		return os.Chtimes(filename, mtime, mtime)
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+)
+
+// The default timeout for fetching remote assets.
+const DefaultFetchTimeout = 30 * time.Second
+
+// Fetch a remote asset and add it to the generator at the virtual path
+// mountPath. Like the mount points of roots (see AddRoot), mountPath is a path
+// in the generated file system, StripPrefix is not stripped from it. If a
+// checksum for the url is present in URLChecksums, the
+// fetched contents are verified against it and an error is returned on
+// mismatch. The modification time of the asset is taken from the
+// Last-Modified header if present. Otherwise, it is set to MaxModTime (or
+// SOURCE_DATE_EPOCH) if set, or left zero, such that regenerating the assets
+// does not depend on the time of generation.
+func (x *Generator) AddURL(url string, mountPath string) error {
+	timeout := x.FetchTimeout
+
+	if timeout == 0 {
+		timeout = DefaultFetchTimeout
+	}
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch %s: %s", url, resp.Status)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+
+	if err != nil {
+		return err
+	}
+
+	if expected, ok := x.URLChecksums[url]; ok {
+		sum := sha256.Sum256(data)
+
+		if actual := hex.EncodeToString(sum[:]); actual != strings.ToLower(expected) {
+			return fmt.Errorf("checksum mismatch for %s: expected %s, got %s", url, expected, actual)
+		}
+	}
+
+	mtime, err := http.ParseTime(resp.Header.Get("Last-Modified"))
+
+	if err != nil {
+		if mtime, err = x.maxModTime(); err != nil {
+			return err
+		}
+	}
+
+	x.addVirtual(path.Join("/", x.StripPrefix, mountPath), 0644, mtime, data)
+	return nil
+}
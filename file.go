@@ -2,11 +2,29 @@ package assets
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"io/ioutil"
 	"os"
 	"path"
+	"sync"
 	"time"
 )
 
+// The error returned when reading compressed asset data which decompresses
+// to more data than allowed, see FileSystem.MaxDecompressedSize.
+var ErrDecompressionLimit = errors.New("decompressed asset data exceeds limit")
+
+// Pool of file handles returned by FileSystem.Open.
+var filePool = sync.Pool{
+	New: func() interface{} {
+		return &File{}
+	},
+}
+
 // An asset file.
 type File struct {
 	// The full asset file path
@@ -18,12 +36,58 @@ type File struct {
 	// The asset modification time
 	Mtime time.Time
 
-	// The asset data. Note that this data might be in gzip compressed form.
+	// The asset data. Note that this data might be in compressed form.
 	Data []byte
 
+	// The tags assigned to the asset at generation time.
+	Tags []string
+
+	// The title of the asset, see DirMeta.
+	Title string
+
+	// The position of the asset in the navigation order of its directory
+	// (starting at 1), or 0 if the asset is not ordered, see DirMeta.
+	Order int
+
+	// Whether the asset data is stored in compressed form.
+	Compressed bool
+
+	// The compression algorithm of the asset data, if compressed. Gzip (the
+	// zero value) selects the compression algorithm of the file system.
+	Compression Compression
+
+	// The hex encoded SHA-256 digest of the (decompressed) asset data, or
+	// empty when not computed at generation time (see Generator.Digests).
+	Digest string
+
+	// The CRC-32 (IEEE) checksum of the (decompressed) asset data, or 0 when
+	// not computed at generation time (see Generator.Checksums).
+	Checksum uint32
+
+	// Whether the asset data is encrypted, see Generator.EncryptionKey. The
+	// data is decrypted transparently when reading the asset.
+	Encrypted bool
+
+	// Whether the asset is stored under a fingerprinted name, see
+	// Generator.Fingerprint. Handler serves fingerprinted assets with
+	// far-future caching.
+	Fingerprinted bool
+
+	// The location of the asset data in the pack file, see
+	// NewPackFileSystem, and the asset data of the loaded pack the data is
+	// read from instead of Data
+	packOffset int64
+	packLength int64
+	pack       *io.SectionReader
+
 	fs       *FileSystem
-	buf      *bytes.Reader
+	buf      bytes.Reader
+	section  io.SectionReader
+	bufInit  bool
 	dirIndex int
+
+	// The shared file a handle returned by FileSystem.Open was copied from
+	orig *File
 }
 
 // Implementation of os.FileInfo
@@ -45,6 +109,10 @@ func (f *File) IsDir() bool {
 }
 
 func (f *File) Size() int64 {
+	if f.pack != nil {
+		return f.packLength
+	}
+
 	return int64(len(f.Data))
 }
 
@@ -55,20 +123,30 @@ func (f *File) Sys() interface{} {
 // Implementation of http.File
 
 func (f *File) Close() error {
-	f.buf = nil
+	f.bufInit = false
 	f.dirIndex = 0
 
+	if f.orig != nil {
+		// Recycle handles returned by FileSystem.Open
+		*f = File{}
+		filePool.Put(f)
+	}
+
 	return nil
 }
 
 func (f *File) Stat() (os.FileInfo, error) {
+	if f.orig != nil {
+		return f.orig, nil
+	}
+
 	return f, nil
 }
 
 func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 	if f.IsDir() {
-		ret, err := f.fs.readDir(f.Path, f.dirIndex, count)
-		f.dirIndex += len(ret)
+		ret, next, err := f.fs.readDir(f.Path, f.dirIndex, count)
+		f.dirIndex = next
 
 		return ret, err
 	} else {
@@ -76,18 +154,292 @@ func (f *File) Readdir(count int) ([]os.FileInfo, error) {
 	}
 }
 
-func (f *File) Read(data []byte) (int, error) {
-	if f.buf == nil {
-		f.buf = bytes.NewReader(f.Data)
+func (f *File) reader() io.ReadSeeker {
+	if f.pack != nil {
+		if !f.bufInit {
+			f.section = *f.packReader()
+			f.bufInit = true
+		}
+
+		return &f.section
 	}
 
-	return f.buf.Read(data)
+	if !f.bufInit {
+		f.buf.Reset(f.Data)
+		f.bufInit = true
+	}
+
+	return &f.buf
+}
+
+// Get a reader of the stored (possibly compressed and encrypted) asset data.
+func (f *File) storedReader() io.ReadSeeker {
+	if f.pack != nil {
+		return f.packReader()
+	}
+
+	return bytes.NewReader(f.Data)
+}
+
+// Get a reader of the asset data in the pack.
+func (f *File) packReader() *io.SectionReader {
+	return io.NewSectionReader(f.pack, f.packOffset, f.packLength)
+}
+
+// Get the stored (possibly compressed and encrypted) asset data. The data of
+// assets in a pack is read from the pack.
+func (f *File) stored() ([]byte, error) {
+	if f.pack == nil {
+		return f.Data, nil
+	}
+
+	data := make([]byte, f.packLength)
+
+	if n, err := f.pack.ReadAt(data, f.packOffset); n != len(data) {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Get the SHA-256 digest of the stored asset data, reading data in a pack
+// through the hash.
+func (f *File) storedSum() ([sha256.Size]byte, error) {
+	var ret [sha256.Size]byte
+
+	if f.pack == nil {
+		return sha256.Sum256(f.Data), nil
+	}
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f.packReader()); err != nil {
+		return ret, err
+	}
+
+	copy(ret[:], h.Sum(nil))
+	return ret, nil
+}
+
+// Get a reader of the stored asset data like data, which may be compressed.
+// Unencrypted data is not read into memory.
+func (f *File) dataReader() (io.ReadSeeker, error) {
+	if !f.Encrypted {
+		return f.storedReader(), nil
+	}
+
+	data, err := f.data()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bytes.NewReader(data), nil
+}
+
+func (f *File) Read(data []byte) (int, error) {
+	return f.reader().Read(data)
 }
 
 func (f *File) Seek(offset int64, whence int) (int64, error) {
-	if f.buf == nil {
-		f.buf = bytes.NewReader(f.Data)
+	return f.reader().Seek(offset, whence)
+}
+
+// Open a new reader on the asset data. If the asset data is compressed, the
+// returned reader decompresses the data while it is being read, without
+// first inflating the whole asset in memory. Reading fails with
+// ErrDecompressionLimit when the decompressed data exceeds the limits of the
+// file system.
+func (f *File) Reader() (io.ReadCloser, error) {
+	if f.fs != nil && f.fs.packErr != nil {
+		return nil, f.fs.packErr
+	}
+
+	if f.fs != nil {
+		if src, ok := f.fs.sources[f.Path]; ok {
+			return os.Open(src)
+		}
+	}
+
+	if !f.compressed() {
+		rd, err := f.dataReader()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ioutil.NopCloser(rd), nil
 	}
 
-	return f.buf.Seek(offset, whence)
+	if f.fs != nil && f.fs.CacheDecompressed {
+		data, err := f.cachedData()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return f.decompressor()
+}
+
+// Open a reader decompressing the asset data.
+func (f *File) decompressor() (io.ReadCloser, error) {
+	codec, err := f.compression().codec()
+
+	if err != nil {
+		return nil, err
+	}
+
+	rd, err := f.dataReader()
+
+	if err != nil {
+		return nil, err
+	}
+
+	drd, err := codec.Decompress(rd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if limit := f.decompressionLimit(); limit >= 0 {
+		return &limitedReader{ReadCloser: drd, remaining: limit}, nil
+	}
+
+	return drd, nil
+}
+
+// Data derived from asset data on first access, such as decompressed data
+// (see FileSystem.CacheDecompressed).
+type lazyData struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// Get the decompressed data of a compressed asset, decompressing it on first
+// access. The returned data is shared and must not be modified.
+func (f *File) cachedData() ([]byte, error) {
+	key := f
+
+	if f.orig != nil {
+		key = f.orig
+	}
+
+	v, ok := f.fs.decompressed.Load(key)
+
+	if !ok {
+		v, _ = f.fs.decompressed.LoadOrStore(key, &lazyData{})
+	}
+
+	d := v.(*lazyData)
+
+	d.once.Do(func() {
+		rd, err := key.decompressor()
+
+		if err != nil {
+			d.err = err
+			return
+		}
+
+		defer rd.Close()
+		d.data, d.err = ioutil.ReadAll(rd)
+	})
+
+	return d.data, d.err
+}
+
+// Get the maximum size of the decompressed asset data, or -1 for no limit.
+func (f *File) decompressionLimit() int64 {
+	if f.fs == nil {
+		return -1
+	}
+
+	limit := f.fs.MaxDecompressedSize
+
+	if limit <= 0 {
+		limit = -1
+	}
+
+	if f.fs.MaxExpansionRatio > 0 {
+		r := int64(f.fs.MaxExpansionRatio * float64(f.Size()))
+
+		if limit < 0 || r < limit {
+			limit = r
+		}
+	}
+
+	return limit
+}
+
+// A reader failing with ErrDecompressionLimit when reading more than
+// remaining bytes.
+type limitedReader struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (l *limitedReader) Read(data []byte) (int, error) {
+	// Read one byte more than allowed to detect exceeding the limit
+	if int64(len(data)) > l.remaining+1 {
+		data = data[:l.remaining+1]
+	}
+
+	n, err := l.ReadCloser.Read(data)
+
+	if int64(n) > l.remaining {
+		l.remaining = 0
+		return 0, ErrDecompressionLimit
+	}
+
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// Get the compression algorithm of the asset data, if compressed.
+func (f *File) compression() Compression {
+	if f.Compression != Gzip || f.fs == nil {
+		return f.Compression
+	}
+
+	return f.fs.Compression
+}
+
+func (f *File) compressed() bool {
+	return f.Compressed || (f.fs != nil && f.fs.Compressed)
+}
+
+// Get the entity tag of the asset (a quoted string), derived from its Digest.
+// Returns an empty string when the asset has no digest.
+func (f *File) ETag() string {
+	if len(f.Digest) == 0 {
+		return ""
+	}
+
+	return `"` + f.Digest + `"`
+}
+
+// Get the subresource integrity value of the asset (e.g. for the integrity
+// attribute of script and link elements), derived from its Digest. Returns
+// an empty string when the asset has no digest.
+func (f *File) Integrity() string {
+	sum, err := hex.DecodeString(f.Digest)
+
+	if err != nil || len(sum) == 0 {
+		return ""
+	}
+
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum)
+}
+
+// Check whether the asset has the given tag.
+func (f *File) HasTag(tag string) bool {
+	return containsString(f.Tags, tag)
+}
+
+// Check whether the asset is private, i.e. tagged with PrivateTag.
+func (f *File) IsPrivate() bool {
+	return f.HasTag(PrivateTag)
 }
@@ -1,10 +1,15 @@
 package assets
 
 import (
-	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"sort"
+	"sync"
 	"time"
 )
 
@@ -19,6 +24,75 @@ type FileSystem struct {
 
 	// Override loading assets from local path. Useful for development.
 	LocalPath string
+
+	// Whether the asset data of all assets is stored in compressed form, for
+	// file systems generated by older versions of go-assets. Assets generated
+	// by current versions are marked Compressed individually instead.
+	Compressed bool
+
+	// The compression algorithm of compressed asset data, unless assets
+	// specify their own compression algorithm.
+	Compression Compression
+
+	// The maximum size of decompressed asset data (0 for no limit). Reading
+	// compressed assets which decompress to more data fails with
+	// ErrDecompressionLimit, which protects against decompression bombs in
+	// file systems from untrusted sources.
+	MaxDecompressedSize int64
+
+	// The maximum ratio of the decompressed to the compressed size of
+	// asset data (0 for no limit), see MaxDecompressedSize.
+	MaxExpansionRatio float64
+
+	// A map of logical asset keys to file paths.
+	Keys map[string]string
+
+	// A map of the original paths of fingerprinted assets to the
+	// fingerprinted paths they are stored under, see Generator.Fingerprint.
+	Fingerprints map[string]string
+
+	// Glob patterns (see Generator.Exclude) of assets in already compressed
+	// formats, which Handler does not compress when serving them (defaults
+	// to DefaultPrecompressed). Generated file systems set the patterns of
+	// Generator.Precompressed.
+	Precompressed []string
+
+	// Whether compressed assets are decompressed once, on first access, and
+	// their decompressed data is kept in memory. Subsequent reads do not
+	// decompress the data again, at the cost of memory.
+	CacheDecompressed bool
+
+	// The ed25519 signature of the assets, see VerifySignature.
+	Signature []byte
+
+	// Get the key of encrypted assets (see Generator.EncryptionKey). When
+	// nil, the hex encoded key in the environment variable named by KeyEnv
+	// is used. Reading encrypted assets without a key fails with
+	// ErrNoDecryptionKey.
+	DecryptionKey func() ([]byte, error)
+
+	// Decoded assets, see Load
+	loaded sync.Map
+
+	// Decompressed asset data by file, see CacheDecompressed
+	decompressed sync.Map
+
+	// Decrypted asset data by file, see DecryptionKey
+	decrypted sync.Map
+
+	// Gzip compressed asset data by file and its total size, see
+	// ServerConfig.CompressResponses
+	gzipped     map[*File][]byte
+	gzippedSize int64
+	gzippedMu   sync.Mutex
+
+	// The digest of the pack file holding the asset data and the error
+	// loading it, see NewPackFileSystem
+	packDigest string
+	packErr    error
+
+	// The source files of the assets by path, see NewDevFileSystem
+	sources map[string]string
 }
 
 func NewFileSystem(dirs map[string][]string, files map[string]*File, localPath string) *FileSystem {
@@ -35,6 +109,154 @@ func NewFileSystem(dirs map[string][]string, files map[string]*File, localPath s
 	return fs
 }
 
+// The version of the format of the code written by the Generator. Version 1
+// files describe their file system using NewFileSystem, version 2 files using
+// a table of FileEntry values passed to NewVersionedFileSystem. File systems
+// generated in older versions remain supported, such that a program can
+// contain file systems generated by different versions of this package.
+const FormatVersion = 2
+
+// A compact description of an asset file. Generated code describes assets
+// using a statically initialized table of entries, which compiles much faster
+// than equivalent map literals for large file systems.
+type FileEntry struct {
+	// The full asset file path
+	Path string
+
+	// The asset file mode
+	FileMode os.FileMode
+
+	// The asset modification time in nanoseconds since the unix epoch, or 0
+	// when unknown. Integer times keep generated files from importing (and
+	// initializing values of) the time package
+	Mtime int64
+
+	// The asset data
+	Data string
+
+	// The asset data is base64 encoded
+	Base64 bool
+
+	// The offset and length of the asset data in the pack file, see
+	// NewPackFileSystem
+	Offset int64
+	Length int64
+
+	// The name of the file holding the asset data in the embedded
+	// directory, see NewEmbedFileSystem
+	EmbedName string
+
+	// The path of the asset source file relative to the directory of the
+	// generated file, see NewDevFileSystem
+	Source string
+
+	// The asset data as a byte slice, used instead of Data when not nil.
+	// The file system shares the slice rather than copying it
+	Bytes []byte
+
+	// The tags assigned to the asset
+	Tags []string
+
+	// The title and navigation order of the asset
+	Title string
+	Order int
+
+	// The asset data is stored in compressed form
+	Compressed bool
+
+	// The compression algorithm of the asset data, if compressed. Gzip
+	// selects the compression algorithm of the file system
+	Compression Compression
+
+	// The hex encoded SHA-256 digest of the (decompressed) asset data
+	Digest string
+
+	// The CRC-32 (IEEE) checksum of the (decompressed) asset data
+	Checksum uint32
+
+	// The asset is stored under a fingerprinted name
+	Fingerprinted bool
+
+	// The asset data is encrypted
+	Encrypted bool
+}
+
+// Create a new file system from a table of file entries written in the given
+// format version. This panics if the version is newer than FormatVersion,
+// i.e. if the entries were generated by a newer version of this package.
+func NewVersionedFileSystem(version int, entries []FileEntry, localPath string) *FileSystem {
+	if version < 2 || version > FormatVersion {
+		panic(fmt.Sprintf("assets: unsupported file system format version %d (supported up to %d)", version, FormatVersion))
+	}
+
+	return NewFileSystemFromEntries(entries, localPath)
+}
+
+// Create a new file system from a table of file entries. The directory
+// listings of the file system are derived from the paths of the entries.
+func NewFileSystemFromEntries(entries []FileEntry, localPath string) *FileSystem {
+	dirs := make(map[string][]string)
+	files := make(map[string]*File, len(entries))
+
+	for i := range entries {
+		e := &entries[i]
+
+		f := &File{
+			Path:     e.Path,
+			FileMode: e.FileMode,
+			Tags:     e.Tags,
+			Title:    e.Title,
+			Order:    e.Order,
+
+			Compressed:  e.Compressed,
+			Compression: e.Compression,
+			Digest:      e.Digest,
+			Checksum:    e.Checksum,
+
+			Fingerprinted: e.Fingerprinted,
+			Encrypted:     e.Encrypted,
+
+			packOffset: e.Offset,
+			packLength: e.Length,
+		}
+
+		if e.Mtime != 0 {
+			f.Mtime = time.Unix(0, e.Mtime)
+		}
+
+		if f.IsDir() {
+			if _, ok := dirs[e.Path]; !ok {
+				dirs[e.Path] = []string{}
+			}
+		} else if e.Bytes != nil {
+			f.Data = e.Bytes
+		} else if e.Base64 {
+			data, err := base64.StdEncoding.DecodeString(e.Data)
+
+			if err != nil {
+				panic(fmt.Sprintf("assets: invalid base64 data of %s: %s", e.Path, err))
+			}
+
+			f.Data = data
+		} else {
+			f.Data = []byte(e.Data)
+		}
+
+		if e.Path != "/" {
+			dir := path.Dir(e.Path)
+			dirs[dir] = append(dirs[dir], path.Base(e.Path))
+		}
+
+		files[e.Path] = f
+	}
+
+	for _, names := range dirs {
+		sort.Strings(names)
+	}
+
+	return NewFileSystem(dirs, files, localPath)
+}
+
 func (f *FileSystem) NewFile(path string, filemode os.FileMode, mtime time.Time, data []byte) *File {
 	return &File{
 		Path:     path,
@@ -54,37 +276,203 @@ func (f *FileSystem) Open(p string) (http.File, error) {
 		return http.Dir(f.LocalPath).Open(p)
 	}
 
+	if f.packErr != nil {
+		return nil, f.packErr
+	}
+
+	if src, ok := f.sources[p]; ok {
+		return os.Open(src)
+	}
+
 	if fi, ok := f.Files[p]; ok {
-		if !fi.IsDir() {
-			// Make a copy for reading
-			ret := fi
-			ret.buf = bytes.NewReader(ret.Data)
+		// Handles hold decrypted data, such that reading them does not
+		// require decrypting. Unencrypted data in a pack is read from
+		// the pack instead.
+		var data []byte
+
+		packed := fi.pack != nil && !fi.Encrypted
 
-			return ret, nil
+		if !packed {
+			var err error
+
+			if data, err = fi.data(); err != nil {
+				return nil, err
+			}
 		}
 
-		return fi, nil
+		// Return a private copy holding the read and directory state of
+		// this handle. Handles are recycled on Close, such that opening
+		// assets does not allocate.
+		ret := filePool.Get().(*File)
+
+		ret.Path = fi.Path
+		ret.FileMode = fi.FileMode
+		ret.Mtime = fi.Mtime
+		ret.Data = data
+		ret.Tags = fi.Tags
+		ret.Title = fi.Title
+		ret.Order = fi.Order
+		ret.Compressed = fi.Compressed
+		ret.Compression = fi.Compression
+		ret.Digest = fi.Digest
+		ret.Checksum = fi.Checksum
+		ret.Fingerprinted = fi.Fingerprinted
+		ret.fs = fi.fs
+		ret.orig = fi
+
+		if packed {
+			ret.pack = fi.pack
+			ret.packOffset = fi.packOffset
+			ret.packLength = fi.packLength
+		}
+
+		return ret, nil
 	}
 
 	return nil, os.ErrNotExist
 }
 
-func (f *FileSystem) readDir(p string, index int, count int) ([]os.FileInfo, error) {
-	if d, ok := f.Dirs[p]; ok {
-		maxl := index + count
+// Read the full (decompressed) contents of the asset at the given path.
+func (f *FileSystem) ReadFile(p string) ([]byte, error) {
+	p = path.Clean(p)
 
-		if maxl > len(d) {
-			maxl = len(d)
+	if len(f.LocalPath) != 0 {
+		fd, err := http.Dir(f.LocalPath).Open(p)
+
+		if err != nil {
+			return nil, err
 		}
 
-		ret := make([]os.FileInfo, 0, maxl-index)
+		defer fd.Close()
+		return ioutil.ReadAll(fd)
+	}
+
+	fi, ok := f.Files[p]
 
-		for i := index; i < maxl; i++ {
-			ret = append(ret, f.Files[path.Join(p, d[i])])
+	if !ok || fi.IsDir() {
+		return nil, os.ErrNotExist
+	}
+
+	if f.CacheDecompressed && fi.compressed() && f.packErr == nil && f.sources == nil {
+		data, err := fi.cachedData()
+
+		if err != nil {
+			return nil, err
 		}
 
-		return ret, nil
+		// Return a copy, which the caller is allowed to modify
+		return append([]byte(nil), data...), nil
+	}
+
+	rd, err := fi.Reader()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rd.Close()
+	return ioutil.ReadAll(rd)
+}
+
+// Get the path under which the asset at p is served, which is its
+// fingerprinted path for fingerprinted assets (see Generator.Fingerprint)
+// and p otherwise. Use this to refer to assets, e.g. from templates.
+func (f *FileSystem) URL(p string) string {
+	if url, ok := f.Fingerprints[path.Clean(p)]; ok {
+		return url
+	}
+
+	return p
+}
+
+// Read the full (decompressed) contents of the asset at the given path like
+// ReadFile, panicking when the asset cannot be read. This is used by the
+// accessor functions of generated files, see Generator.Accessors.
+func (f *FileSystem) MustReadFile(p string) []byte {
+	data, err := f.ReadFile(p)
+
+	if err != nil {
+		panic(fmt.Sprintf("assets: failed to read %s: %s", p, err))
+	}
+
+	return data
+}
+
+// Open the asset registered under the given logical key. Keys are registered
+// at generation time using Generator.AddKey.
+func (f *FileSystem) Lookup(key string) (http.File, error) {
+	if p, ok := f.Keys[key]; ok {
+		return f.Open(p)
 	}
 
 	return nil, os.ErrNotExist
 }
+
+// Read the entries of the directory p, starting at the given index. At most
+// count entries are returned, or all remaining entries if count <= 0. Returns
+// the index of the next entry to read.
+func (f *FileSystem) readDir(p string, index int, count int) ([]os.FileInfo, int, error) {
+	var ret []os.FileInfo
+
+	next, err := f.visitDir(p, index, count, func(n int) {
+		ret = make([]os.FileInfo, 0, n)
+	}, func(fi *File) {
+		ret = append(ret, fi)
+	})
+
+	return ret, next, err
+}
+
+// Visit the files of the directory at p starting at index, visiting at most
+// count files when count > 0. Since directories may contain many files,
+// files are visited without allocating intermediate slices: alloc is called
+// first with an upper bound of the number of files visited, followed by
+// visit for each file. Returns the index to continue at, and io.EOF when
+// count > 0 and no files are left.
+func (f *FileSystem) visitDir(p string, index int, count int, alloc func(n int), visit func(fi *File)) (int, error) {
+	d, ok := f.Dirs[p]
+
+	if !ok {
+		return index, os.ErrNotExist
+	}
+
+	n := len(d) - index
+
+	if count > 0 && count < n {
+		n = count
+	}
+
+	if n > 0 {
+		alloc(n)
+	}
+
+	// Look up files by a reused path buffer, which unlike path.Join does
+	// not allocate for every file
+	var scratch [256]byte
+
+	buf := append(scratch[:0], p...)
+
+	if p != "/" {
+		buf = append(buf, '/')
+	}
+
+	dirLen := len(buf)
+	visited := 0
+
+	for ; index < len(d) && (count <= 0 || visited < count); index++ {
+		buf = append(buf[:dirLen], d[index]...)
+		fi := f.Files[string(buf)]
+
+		// Never expose private assets in directory listings
+		if fi != nil && !fi.IsPrivate() {
+			visit(fi)
+			visited++
+		}
+	}
+
+	if count > 0 && visited == 0 {
+		return index, io.EOF
+	}
+
+	return index, nil
+}
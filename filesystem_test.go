@@ -0,0 +1,444 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFileSystem() *FileSystem {
+	mtime := time.Unix(1500000000, 0)
+
+	return NewFileSystem(map[string][]string{
+		"/":    []string{"css", "index.html"},
+		"/css": []string{"app.css"},
+	}, map[string]*File{
+		"/": &File{
+			Path:     "/",
+			FileMode: 0x800001ed,
+			Mtime:    mtime,
+		},
+		"/index.html": &File{
+			Path:     "/index.html",
+			FileMode: 0x1a4,
+			Mtime:    mtime,
+			Data:     []byte("<html><body>hello</body></html>\n"),
+		},
+		"/css": &File{
+			Path:     "/css",
+			FileMode: 0x800001ed,
+			Mtime:    mtime,
+		},
+		"/css/app.css": &File{
+			Path:     "/css/app.css",
+			FileMode: 0x1a4,
+			Mtime:    mtime,
+			Data:     []byte("body { margin: 0; }\n"),
+		},
+	}, "")
+}
+
+// A minimal response writer which can be reused between requests
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w *discardResponseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *discardResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w *discardResponseWriter) WriteHeader(int) {
+}
+
+func (w *discardResponseWriter) reset() {
+	for k := range w.header {
+		delete(w.header, k)
+	}
+}
+
+func openAndRead(fs *FileSystem, buf []byte) {
+	f, err := fs.Open("/css/app.css")
+
+	if err != nil {
+		panic(err)
+	}
+
+	for {
+		if _, err := f.Read(buf); err == io.EOF {
+			break
+		}
+	}
+
+	f.Close()
+}
+
+func TestOpenAllocs(t *testing.T) {
+	fs := testFileSystem()
+	buf := make([]byte, 8)
+
+	openAndRead(fs, buf)
+
+	if n := testing.AllocsPerRun(100, func() { openAndRead(fs, buf) }); n > 0 {
+		t.Errorf("expected Open, Read and Close to not allocate, got %v allocations", n)
+	}
+}
+
+func TestServeHTTPAllocs(t *testing.T) {
+	// Allocations made by http.ServeContent for the response header values
+	// it sets, the handler itself should not allocate
+	const budget = 8
+
+	h := testFileSystem().Handler()
+	w := &discardResponseWriter{header: make(http.Header)}
+	r, _ := http.NewRequest("GET", "/css/app.css", nil)
+
+	serve := func() {
+		w.reset()
+		h.ServeHTTP(w, r)
+	}
+
+	serve()
+
+	if n := testing.AllocsPerRun(100, serve); n > budget {
+		t.Errorf("expected ServeHTTP to make at most %d allocations, got %v", budget, n)
+	}
+}
+
+func BenchmarkOpenRead(b *testing.B) {
+	fs := testFileSystem()
+	buf := make([]byte, 512)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		openAndRead(fs, buf)
+	}
+}
+
+// A file system with a single directory containing n files.
+func largeDirFileSystem(n int) *FileSystem {
+	entries := []FileEntry{{Path: "/", FileMode: os.ModeDir | 0755}, {Path: "/dir", FileMode: os.ModeDir | 0755}}
+
+	for i := 0; i < n; i++ {
+		entries = append(entries, FileEntry{Path: fmt.Sprintf("/dir/%05d.txt", i), FileMode: 0644, Data: "data"})
+	}
+
+	return NewFileSystemFromEntries(entries, "")
+}
+
+func TestReaddirAllocs(t *testing.T) {
+	fs := largeDirFileSystem(1000)
+	visited := 0
+
+	// Visiting the entries of a directory looks up files by a reused path
+	// buffer, without allocating per entry
+	allocs := testing.AllocsPerRun(10, func() {
+		visited = 0
+
+		fs.visitDir("/dir", 0, -1, func(n int) {}, func(fi *File) {
+			visited++
+		})
+	})
+
+	if visited != 1000 {
+		t.Errorf("expected 1000 visited entries, got %d", visited)
+	}
+
+	if allocs != 0 {
+		t.Errorf("expected visiting a directory to not allocate, got %v allocations", allocs)
+	}
+
+	// Listing a directory only allocates the returned slice
+	allocs = testing.AllocsPerRun(10, func() {
+		fs.readDir("/dir", 0, -1)
+	})
+
+	if allocs > 1 {
+		t.Errorf("expected listing a directory to allocate once, got %v allocations", allocs)
+	}
+}
+
+func BenchmarkReaddir(b *testing.B) {
+	fs := largeDirFileSystem(10000)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d, _ := fs.Open("/dir")
+		d.Readdir(-1)
+		d.Close()
+	}
+}
+
+func BenchmarkReadDirFS(b *testing.B) {
+	fsys := largeDirFileSystem(10000).FS()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		iofs.ReadDir(fsys, "dir")
+	}
+}
+
+func BenchmarkReadDirFSBatched(b *testing.B) {
+	fsys := largeDirFileSystem(10000).FS()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		d, _ := fsys.Open("dir")
+
+		for {
+			if _, err := d.(iofs.ReadDirFile).ReadDir(100); err != nil {
+				break
+			}
+		}
+
+		d.Close()
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	h := testFileSystem().Handler()
+	w := &discardResponseWriter{header: make(http.Header)}
+	r, _ := http.NewRequest("GET", "/css/app.css", nil)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		w.reset()
+		h.ServeHTTP(w, r)
+	}
+}
+
+func TestBearerTokenAuthorizer(t *testing.T) {
+	authorize := BearerTokenAuthorizer("secret")
+
+	for auth, expected := range map[string]bool{
+		"Bearer secret":  true,
+		"Bearer secret2": false,
+		"Bearer secre":   false,
+		"Bearer ":        false,
+		"secret":         false,
+	} {
+		r, _ := http.NewRequest("GET", "/", nil)
+		r.Header.Set("Authorization", auth)
+
+		if authorize(r, nil) != expected {
+			t.Errorf("expected authorization of %q to be %v", auth, expected)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	fs := testFileSystem()
+
+	if err := fs.Verify(); err != nil {
+		t.Errorf("expected file system to verify, got %s", err)
+	}
+
+	fs.Dirs["/css"] = append(fs.Dirs["/css"], "missing.css")
+
+	if err := fs.Verify(); err == nil {
+		t.Errorf("expected listing of a missing asset to fail verification")
+	}
+
+	fs = testFileSystem()
+	fs.Compressed = true
+
+	if err := fs.Verify(); err == nil {
+		t.Errorf("expected corrupt compressed data to fail verification")
+	}
+}
+
+func TestExtract(t *testing.T) {
+	src := t.TempDir()
+	script := filepath.Join(src, "bin", "run.sh")
+
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho run\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	mtime := time.Unix(1500000000, 0)
+
+	if err := os.Chtimes(script, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, hermetic := range []bool{false, true} {
+		g := &Generator{Hermetic: hermetic, Compressed: true}
+
+		if err := g.AddRoot(Root{Dir: src}); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := g.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		fss, err := Parse(buf.Bytes())
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dir := t.TempDir()
+
+		if err := fss["Assets"].Extract(dir); err != nil {
+			t.Fatal(err)
+		}
+
+		info, err := os.Stat(filepath.Join(dir, "bin", "run.sh"))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Mode().Perm() != 0755 {
+			t.Errorf("expected extracted script to be executable, got %s", info.Mode())
+		}
+
+		if !hermetic && !info.ModTime().Equal(mtime) {
+			t.Errorf("expected modification time %s, got %s", mtime, info.ModTime())
+		}
+
+		data, _ := ioutil.ReadFile(filepath.Join(dir, "bin", "run.sh"))
+
+		if string(data) != "#!/bin/sh\necho run\n" {
+			t.Errorf("unexpected extracted data %q", data)
+		}
+	}
+}
+
+func TestSorted(t *testing.T) {
+	names := []string{
+		"10_add_index.sql",
+		"2_add_users.sql",
+		"1_init.sql",
+		"v1.10.0_tags.sql",
+		"v1.2.0_users.sql",
+		"v1.2.0-rc.1_users.sql",
+		"v1.2.0-rc.10_users.sql",
+		"v1.2.0-rc.2_users.sql",
+		"readme.txt",
+	}
+
+	entries := []FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/migrations", FileMode: os.ModeDir | 0755},
+		{Path: "/migrations/sub", FileMode: os.ModeDir | 0755},
+	}
+
+	for _, name := range names {
+		entries = append(entries, FileEntry{Path: "/migrations/" + name, FileMode: 0644})
+	}
+
+	fs := NewFileSystemFromEntries(entries, "")
+
+	tests := []struct {
+		ordering Ordering
+		expected []string
+	}{
+		{LexicalOrder, []string{"10_add_index.sql", "1_init.sql", "2_add_users.sql", "readme.txt", "v1.10.0_tags.sql", "v1.2.0-rc.10_users.sql", "v1.2.0-rc.1_users.sql", "v1.2.0-rc.2_users.sql", "v1.2.0_users.sql"}},
+		{NumericOrder, []string{"1_init.sql", "2_add_users.sql", "10_add_index.sql", "readme.txt", "v1.2.0-rc.1_users.sql", "v1.2.0-rc.2_users.sql", "v1.2.0-rc.10_users.sql", "v1.2.0_users.sql", "v1.10.0_tags.sql"}},
+		{SemverOrder, []string{"1_init.sql", "v1.2.0-rc.1_users.sql", "v1.2.0-rc.2_users.sql", "v1.2.0-rc.10_users.sql", "v1.2.0_users.sql", "v1.10.0_tags.sql", "2_add_users.sql", "10_add_index.sql", "readme.txt"}},
+	}
+
+	for _, test := range tests {
+		sorted, err := Sorted(fs, "/migrations", test.ordering)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []string
+
+		for _, p := range sorted {
+			got = append(got, path.Base(p))
+		}
+
+		if strings.Join(got, " ") != strings.Join(test.expected, " ") {
+			t.Errorf("ordering %d: expected %v, got %v", test.ordering, test.expected, got)
+		}
+	}
+
+	if sorted, err := SortedByName(fs, "/migrations"); err != nil || len(sorted) != len(names) || sorted[0] != "/migrations/10_add_index.sql" {
+		t.Errorf("expected lexically sorted paths, got %v (%v)", sorted, err)
+	}
+
+	if _, err := Sorted(fs, "/missing", LexicalOrder); !os.IsNotExist(err) {
+		t.Errorf("expected missing directory to not exist, got %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, data := range map[string]string{"index.html": "<p>index</p>", "css/app.css": "body {}", "js/app.js": "run()"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs, err := Snapshot(dir, func(g *Generator) {
+		g.Exclude = []string{"*.css"}
+		g.Compressed = true
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Later changes on disk do not affect the snapshot
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.html"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fs.ReadFile("/index.html"); err != nil || string(data) != "<p>index</p>" {
+		t.Errorf("expected snapshot contents, got %q (%v)", data, err)
+	}
+
+	if data, err := fs.ReadFile("/js/app.js"); err != nil || string(data) != "run()" {
+		t.Errorf("expected nested file in snapshot, got %q (%v)", data, err)
+	}
+
+	if _, ok := fs.Files["/css/app.css"]; ok {
+		t.Errorf("expected options to apply to the snapshot")
+	}
+
+	if names := strings.Join(fs.Dirs["/"], " "); names != "css index.html js" {
+		t.Errorf("expected root listing css index.html js, got %s", names)
+	}
+
+	if _, err := Snapshot(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("expected missing directory to not exist, got %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/scanner"
+	"io/ioutil"
+	"strings"
+)
+
+// The number of lines shown before and after the line of a formatting
+// error, see formatSource.
+const formatErrorContext = 2
+
+// Format the generated code src, unless SkipFormat is set. When formatting
+// fails, which indicates a bug in the generator or in a custom Template, the
+// unformatted code is written to a temporary file for inspection, and the
+// returned error includes the lines surrounding the error.
+func (x *Generator) formatSource(src []byte) ([]byte, error) {
+	if x.SkipFormat {
+		return src, nil
+	}
+
+	ret, err := format.Source(src)
+
+	if err == nil {
+		return ret, nil
+	}
+
+	var msg strings.Builder
+
+	fmt.Fprintf(&msg, "failed to format generated code: %s", err)
+
+	var list scanner.ErrorList
+
+	if errors.As(err, &list) && len(list) != 0 {
+		writeErrorContext(&msg, src, list[0].Pos.Line)
+	}
+
+	if fd, ferr := ioutil.TempFile("", "go-assets-*.go"); ferr == nil {
+		_, ferr = fd.Write(src)
+
+		if cerr := fd.Close(); ferr == nil && cerr == nil {
+			fmt.Fprintf(&msg, "\nthe unformatted code was written to %s", fd.Name())
+		}
+	}
+
+	return nil, errors.New(msg.String())
+}
+
+// Write the lines of src surrounding the (1-based) line to msg, marking the
+// line itself.
+func writeErrorContext(msg *strings.Builder, src []byte, line int) {
+	lines := bytes.Split(src, []byte("\n"))
+
+	for i := line - formatErrorContext; i <= line+formatErrorContext; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+
+		marker := " "
+
+		if i == line {
+			marker = ">"
+		}
+
+		l := lines[i-1]
+
+		// Lines holding asset data can be very long
+		if len(l) > 120 {
+			l = append(l[:120:120], "..."...)
+		}
+
+		fmt.Fprintf(msg, "\n%s %4d | %s", marker, i, l)
+	}
+}
@@ -1,79 +1,677 @@
 package assets
 
 import (
+	"bufio"
 	"bytes"
+	"crypto/ed25519"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
-	"go/format"
+	"hash"
+	"hash/crc32"
 	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
+	"unicode/utf8"
 )
 
 type file struct {
 	info os.FileInfo
 	path string
+
+	// In-memory data for files which do not originate from disk
+	data []byte
+
+	// The compression policy of the root the file was added from, see
+	// Root.CompressionPolicy
+	policy map[string]Compression
+}
+
+func (f file) read() ([]byte, error) {
+	if f.data != nil {
+		return f.data, nil
+	}
+
+	fd, err := os.Open(f.path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+	return ioutil.ReadAll(fd)
+}
+
+// A virtual os.FileInfo for files and directories not originating from disk.
+type virtualFileInfo struct {
+	name  string
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
 }
 
+func (v *virtualFileInfo) Name() string       { return v.name }
+func (v *virtualFileInfo) Size() int64        { return v.size }
+func (v *virtualFileInfo) Mode() os.FileMode  { return v.mode }
+func (v *virtualFileInfo) ModTime() time.Time { return v.mtime }
+func (v *virtualFileInfo) IsDir() bool        { return v.mode.IsDir() }
+func (v *virtualFileInfo) Sys() interface{}   { return nil }
+
+// The default patterns of files in already compressed formats, see
+// Generator.Precompressed.
+var DefaultPrecompressed = []string{
+	"*.png", "*.jpg", "*.jpeg", "*.gif", "*.webp", "*.avif",
+	"*.woff", "*.woff2",
+	"*.mp3", "*.mp4", "*.ogg", "*.webm",
+	"*.gz", "*.br", "*.zip", "*.xz", "*.zst", "*.bz2",
+}
+
+// The default maximum size of a single string literal in the generated file,
+// see Generator.ChunkSize.
+const DefaultChunkSize = 64 * 1024
+
+// The import path of this package used by generated code, see
+// Generator.RuntimeImport.
+const DefaultRuntimeImport = "github.com/jessevdk/go-assets"
+
 // An asset generator. The generator can be used to generate an asset go file
 // with all the assets that were added to the generator embedded into it.
 // The generated assets are made available by the specified go variable
 // VariableName which is of type assets.FileSystem.
 type Generator struct {
-	// The package name to generate assets in,
+	// The package name to generate assets in. When empty, WriteFile infers
+	// the package name from the target directory (see WriteFile), other
+	// writers default to main,
 	PackageName string
 
 	// The variable name containing the asset filesystem (defaults to Assets),
 	VariableName string
 
+	// The package name to generate asset path constants in (defaults to
+	// assetpaths), see WritePaths,
+	PathsPackageName string
+
+	// Declare a constant holding the path of every asset in the generated
+	// file (e.g. AssetTemplatesIndexHTML = "/templates/index.html"), such
+	// that code referencing assets is checked at compile time. Constants
+	// are prefixed with Asset, preceded by the variable name for file
+	// systems not named Assets (e.g. StaticAssetAppJS for /app.js in a
+	// group named Static). Constants of fingerprinted assets hold the
+	// fingerprinted path (see Fingerprint),
+	PathConstants bool
+
+	// Generate a function returning the (decompressed) contents of every
+	// asset (e.g. TemplatesIndexHTML() []byte), prefixed with the variable
+	// name for file systems not named Assets. Accessors panic when the asset
+	// cannot be read (see FileSystem.MustReadFile),
+	Accessors bool
+
 	// Strip the specified prefix from all paths,
 	StripPrefix string
 
-	fsDirsMap  map[string][]string
-	fsFilesMap map[string]file
+	// The maximum depth of the files added below a directory added with Add
+	// or AddRoot (e.g. 1 adds its files and subdirectories, but not their
+	// contents). Directories at the maximum depth are added without their
+	// contents, and are reported by Truncated and in Stats, such that deeply
+	// nested vendored trees or recursive mounts are not embedded by
+	// accident. Zero means unlimited,
+	MaxDepth int
+
+	// Glob patterns (see path.Match) of files and directories to exclude
+	// when adding directories. Patterns without a slash are matched against
+	// the base name (e.g. *.map), other patterns against the full path
+	// (e.g. web/drafts/*),
+	Exclude []string
+
+	// How added files which look like secrets (e.g. .env files or private
+	// keys, see SecretPatterns) are handled: a warning is written to
+	// SecretOutput (SecretWarn, the default), adding them fails
+	// (SecretError) or they are added silently (SecretIgnore),
+	SecretPolicy SecretPolicy
+
+	// Glob patterns (see Exclude) of files which look like secrets
+	// (defaults to DefaultSecretPatterns),
+	SecretPatterns []string
+
+	// Glob patterns (see Exclude) of files which match SecretPatterns but
+	// are meant to be embedded, such as public certificates (e.g.
+	// certs/*.pem),
+	AllowSecrets []string
+
+	// The writer warnings about secrets are written to (defaults to
+	// os.Stderr),
+	SecretOutput io.Writer
+
+	// Compress the asset data (using Compression),
+	Compressed bool
+
+	// The compression algorithm used when Compressed is set (defaults to
+	// Gzip),
+	Compression Compression
+
+	// Write the SHA-256 digest of the data of each asset (see File.Digest),
+	// which Handler uses as entity tag and templates can use for
+	// subresource integrity attributes (see File.Integrity),
+	Digests bool
+
+	// Write the CRC-32 (IEEE) checksum of the data of each asset (see
+	// File.Checksum), a cheap way for tools to detect changed assets.
+	// Checksums (and digests) are verified by FileSystem.Verify,
+	Checksums bool
+
+	// Decompress compressed assets once, on first access, and keep their
+	// decompressed data in memory (see FileSystem.CacheDecompressed),
+	CacheDecompressed bool
+
+	// Write the asset data as base64 encoded strings, which are decoded
+	// when the file system is initialized. This keeps the generated file
+	// plain ASCII for tools which cannot handle escaped binary data, at the
+	// cost of a larger generated file and decoding at startup,
+	Base64 bool
+
+	// Write the asset data as byte slice literals instead of string
+	// constants. String constants are stored in read-only memory and cannot
+	// be modified accidentally, but are copied to the File.Data of their
+	// assets when the file system is initialized. Byte slices are used by
+	// the file system as is, avoiding the copy, at the cost of a much larger
+	// generated file. Cannot be combined with Base64,
+	ByteSlices bool
+
+	// The maximum size of a single string literal in the generated file
+	// (defaults to DefaultChunkSize, negative for no limit). Larger asset
+	// data is split into several literals which are concatenated by the
+	// compiler, since very long literals slow down editors and code review
+	// tools,
+	ChunkSize int
+
+	// A comment written at the top of generated files containing code, for
+	// example a license header. Lines which are not comments yet are
+	// turned into line comments,
+	Header string
+
+	// A comment written at the top of generated data files instead of
+	// Header (see DataShardSize), for example third-party attribution
+	// which must not apply to the generated code,
+	DataHeader string
+
+	// A text/template of a comment written at the top of generated files,
+	// below the standard generated code marker, executed with a BannerData.
+	// For example "Generated by {{.Command}} at commit {{.Commit}}",
+	Banner string
+
+	// When set, WriteFile writes the asset data to separate data files of
+	// about this size in bytes (e.g. assets_data1.go, assets_data2.go for
+	// assets.go), next to the file containing the code,
+	DataShardSize int64
+
+	// Share the data of identical assets between all file systems written
+	// together (see Group), rather than only within each file system. With
+	// DataShardSize, shared data is written to the data files once and
+	// referenced by all file systems. Groups inherit this option and must
+	// not change it,
+	SharedData bool
+
+	// When set, WriteFile writes the asset data to this binary pack file
+	// (relative to the directory of the generated file) instead of
+	// embedding it in the generated code, which then loads the pack at
+	// runtime (see NewPackFileSystem). This keeps large assets out of the
+	// compiler, at the cost of shipping the pack next to the executable,
+	PackFile string
+
+	// With PackFile, the generated code loads the pack from the end of the
+	// executable, after it has been appended to the built executable using
+	// AppendPack (or go-assets append-pack). This allows distributing a
+	// single file, while the pack file is still used when it has not been
+	// appended (e.g. for go run and go test),
+	AppendPack bool
+
+	// Write a generated file which does not import this package, by
+	// inlining a minimal runtime (supporting Open, ReadFile and Lookup) into
+	// the generated file. The file systems are then of an unexported type
+	// rather than *assets.FileSystem. Only gzip compression is supported, and
+	// a package can contain a single self-contained generated file,
+	SelfContained bool
+
+	// The import path of this package used by the generated code (defaults
+	// to DefaultRuntimeImport), for forks, vanity import paths or vendored
+	// copies of the package. The package is imported under the name assets,
+	RuntimeImport string
+
+	// The template of the generated file (defaults to DefaultTemplate),
+	// executed with an OutputFile. A custom template can add helper
+	// functions, a different initialization style or company specific
+	// headers to the generated file,
+	Template *template.Template
+
+	// Write generated code without formatting it. When formatting fails,
+	// Write reports the lines surrounding the error and writes the
+	// unformatted code to a temporary file. Skipping formatting allows
+	// inspecting the full generated file instead,
+	SkipFormat bool
+
+	// When set, WriteFile additionally writes a disk-backed version of the
+	// file systems, which reads the assets from their source files at
+	// runtime (see NewDevFileSystem), to a file named after the generated
+	// file with this suffix (e.g. assets_dev.go for assets.go and dev). The
+	// disk-backed file is only built with this build tag, and the generated
+	// file only without it. This allows editing assets without regenerating
+	// or rebuilding during development (go run -tags dev),
+	DevTag string
+
+	// When set, WriteFile writes the asset data to files in this directory
+	// (relative to the directory of the generated file) which are embedded
+	// using go:embed, rather than to go literals. This keeps the generated
+	// code small and fast to compile. The directory is owned by the
+	// generator: data files no longer needed are removed from it,
+	EmbedDir string
+
+	// The hash function used to identify identical asset data (defaults to
+	// SHA-1),
+	DedupHash func() hash.Hash
+
+	// A map of file extensions (e.g. .css) to the compression algorithm
+	// used for files with the extension, overriding Compressed and
+	// Compression. Map extensions to NoCompression to store files
+	// uncompressed. This allows a single file system to mix compressed
+	// text and raw binary assets,
+	CompressionPolicy map[string]Compression
+
+	// The compression level used when Compressed is set (defaults to the
+	// default level of the compression algorithm). For gzip, use
+	// gzip.BestSpeed for faster builds or gzip.BestCompression for smaller
+	// binaries,
+	CompressionLevel int
+
+	// Glob patterns (see Exclude) of files in already compressed formats,
+	// which are stored as is when Compressed is set since compressing them
+	// again wastes time and may even grow them (defaults to
+	// DefaultPrecompressed). The patterns are kept by the generated file
+	// system, see FileSystem.Precompressed,
+	Precompressed []string
+
+	// The minimum percentage (e.g. 10) and the minimum number of bytes
+	// compression has to save for a file to be stored compressed. Other
+	// files are stored uncompressed, since decompressing them is not worth
+	// the cost. Files which grow when compressed are always stored
+	// uncompressed,
+	MinCompressionSavings float64
+	MinCompressionBytes   int64
+
+	// Glob patterns (see Exclude) of test-only assets, such as test
+	// fixtures. Patterns are matched against the paths of the generated file
+	// system and their parent directories. Test-only assets are not written
+	// by Write, but by WriteTest,
+	TestOnly []string
+
+	// Glob patterns (see Exclude) of assets stored under fingerprinted
+	// names, which contain a hash of their contents (e.g. app.js is stored
+	// as app.3fa92c1d.js). Since the contents of a fingerprinted asset never
+	// change, it can be cached indefinitely. The generated file system maps
+	// the original paths to the fingerprinted paths, see FileSystem.URL,
+	Fingerprint []string
+
+	// Let WriteFile record a digest of its inputs (the options and the
+	// paths, metadata and contents of the assets) in the generated file, and
+	// leave the generated file and the files written along with it alone
+	// when the recorded digest matches. This keeps go:generate from
+	// rewriting unchanged files, which would trigger rebuilds. All files
+	// are regenerated when one of the files written along with the
+	// generated file (e.g. the PackFile) is missing, changes made to them by
+	// hand are not detected. Function options (such as DedupHash) only
+	// contribute whether they are set, remove the generated file after
+	// changing them,
+	SkipUnchanged bool
+
+	// Let WriteFile write a test along with the generated file (see
+	// WriteEmbeddedTest), which fails when the embedded assets no longer
+	// match their source files, catching stale generated files in CI,
+	EmbeddedTest bool
+
+	// Transforms applied to the contents of files before they are embedded
+	// (e.g. minifiers), in order. This avoids a separate build step writing
+	// the transformed files to a temporary directory,
+	Transforms []Transform
+
+	// The banner (e.g. a license comment) written at the start of the assets
+	// created by Bundle, and the separator written between their inputs,
+	BundleBanner    string
+	BundleSeparator string
+
+	// Minify text assets (HTML, CSS and JSON) after applying Transforms and
+	// before compressing them, using the minifiers in Minifiers. Files which
+	// are already minified (e.g. app.min.js) are left alone, and files which
+	// cannot be minified are embedded as is,
+	Minify bool
+
+	// Glob patterns (see Exclude) of the JavaScript files minified by the
+	// built-in JavaScript minifier when Minify is set. The built-in minifier
+	// only tells regular expressions from divisions by heuristics, such that
+	// it is only applied to the scripts it is known to handle. Scripts are
+	// minified by a minifier registered for their extension in Minifiers
+	// instead, if any,
+	MinifyJS []string
+
+	// An AES key (16, 24 or 32 bytes) with which the asset data is
+	// encrypted (using AES-GCM), for assets which must not be readable from
+	// the binary. The generated file system decrypts assets transparently
+	// on first access using the key provided at runtime, see
+	// FileSystem.DecryptionKey,
+	EncryptionKey []byte
+
+	// An ed25519 key with which the assets are signed, such that tampering
+	// with the assets of a binary can be detected at runtime before they are
+	// used, see FileSystem.VerifySignature,
+	SigningKey ed25519.PrivateKey
+
+	// The path prefix under which the assets are served (e.g. /assets),
+	// used for the URLs written by WriteURLs,
+	URLPrefix string
+
+	// The maximum number of files read and compressed concurrently
+	// (defaults to GOMAXPROCS),
+	Concurrency int
+
+	// Skip files and directories which cannot be read (e.g. because of
+	// their permissions) instead of failing on the first of them. The
+	// generated file is written without the skipped files, after which Write
+	// and WriteFile return a *PartialError listing all of them,
+	ContinueOnError bool
+
+	// When set, a statistics report is printed to this writer after each
+	// successful Write, see Stats,
+	StatsOutput io.Writer
+
+	// A map of glob patterns (see Exclude) to tags applied to the files
+	// matching the pattern. Patterns are matched against the paths of the
+	// generated file system. Files tagged with PrivateTag are private,
+	Tags map[string][]string
+
+	// The unicode normalization applied to asset paths (defaults to
+	// NormalizeComposed). Files added on macOS have decomposed (NFD) names
+	// which otherwise would not match composed paths used in lookups,
+	Normalization Normalization
+
+	// The licenses (SPDX identifiers) assets may be embedded under. When not
+	// empty, Write fails if an asset has a detected license which is not
+	// in the list, see Licenses,
+	AllowedLicenses []string
+
+	// The timeout for fetching assets added with AddURL (defaults to
+	// DefaultFetchTimeout),
+	FetchTimeout time.Duration
+
+	// A map of URLs added with AddURL to the expected hex encoded sha256
+	// checksums of their contents,
+	URLChecksums map[string]string
+
+	// Clamp the modification times of all assets to at most this time, such
+	// that regenerating unchanged assets produces identical output. When
+	// zero, the time given by the SOURCE_DATE_EPOCH environment variable
+	// is used if set (see https://reproducible-builds.org/specs/source-date-epoch/),
+	MaxModTime time.Time
+
+	// Omit the modification times of all assets, resulting in smaller
+	// generated files which only change when the asset contents change.
+	// Served assets will not have a Last-Modified header,
+	OmitMTime bool
+
+	// Overrides of the permissions of files matching glob patterns (e.g.
+	// 0644 for * and 0755 for bin/*), such that the generated file system
+	// does not depend on the permissions the files happen to have on the
+	// build machine. When several overrides match a file, the last one
+	// applies. Directories are not affected,
+	ModeOverrides []ModeOverride
+
+	// Generate hermetically, such that generating the same assets on
+	// different machines results in identical output. File modes are
+	// normalized (to 0644, or 0755 for directories and executables),
+	// modification times are omitted (or set to MaxModTime, see
+	// SOURCE_DATE_EPOCH) and Write fails if asset paths contain host
+	// specific paths, such as the working or home directory,
+	Hermetic bool
+
+	// The name of the optional metadata files in asset directories (e.g.
+	// _meta.json), describing the titles, navigation order and tags of the
+	// assets in the directory (see DirMeta). Metadata files are decoded
+	// using ConfigFormats according to their extension and are not added
+	// as assets themselves. JSON and YAML (e.g. _meta.yaml) are supported
+	// out of the box, other formats require registering an unmarshal
+	// function in ConfigFormats,
+	MetaFilename string
+
+	// The interval at which Watch polls the sources for changes (defaults
+	// to DefaultWatchInterval),
+	WatchInterval time.Duration
+
+	// When set, Watch reports regenerations and regeneration errors to this
+	// writer,
+	WatchOutput io.Writer
+
+	fsDirsMap   map[string][]string
+	fsDirsIndex map[string]map[string]bool
+	fsFilesMap  map[string]file
+	keys        map[string]string
+	groups      []*Generator
+	stats       *Stats
+	sources     []source
+	dirMeta     map[string]*DirMeta
+
+	// The directories which could not be read, see ContinueOnError
+	failures []Failure
+
+	// The directories added without their contents, see MaxDepth
+	truncatedDirs []string
+
+	// The package name inferred by WriteFile, see PackageName
+	inferredPackage string
+}
+
+// A source of assets added to the generator, which can be added again when
+// regenerating (see Watch).
+type source struct {
+	// The paths on disk of the source, empty for in-memory sources
+	paths []string
+
+	add func() error
+
+	// Check whether the file or directory at the path on disk p, below one
+	// of the paths of the source, is excluded from the source. Nil if
+	// nothing is excluded.
+	excluded func(p string, isDir bool) bool
 }
 
-func (x *Generator) addPath(parent string, prefix string, info os.FileInfo) error {
+// Add the file or directory info in the directory parent. The depth is the
+// depth of the file below the added directory, see MaxDepth.
+func (x *Generator) addPath(parent string, prefix string, info os.FileInfo, depth int) error {
 	p := path.Join(parent, info.Name())
 
+	if !info.IsDir() && x.isMetaFile(info.Name()) {
+		return x.loadMeta(parent, path.Join(prefix, p))
+	}
+
+	if x.excluded(p) {
+		return nil
+	}
+
+	if !info.IsDir() {
+		if err := x.checkSecret(p); err != nil {
+			return err
+		}
+	}
+
 	f := file{
 		info: info,
 		path: path.Join(prefix, p),
 	}
 
 	x.fsFilesMap[p] = f
+	x.appendFileInDir(parent, info.Name())
 
 	if info.IsDir() {
-		f, err := os.Open(f.path)
-		fi, err := f.Readdir(-1)
-		f.Close()
+		var fi []os.FileInfo
+		var err error
+
+		if x.truncated(depth) {
+			// The directory is kept, without its contents
+			x.truncate(p)
+		} else if fd, ferr := os.Open(f.path); ferr != nil {
+			err = ferr
+		} else {
+			fi, err = fd.Readdir(-1)
+			fd.Close()
+		}
+
 		if err != nil {
-			return err
+			if !x.ContinueOnError {
+				return err
+			}
+
+			// The directory is kept, without its contents
+			x.fail(p, err)
+			fi = nil
 		}
 
-		x.fsDirsMap[p] = make([]string, 0, len(fi))
+		if _, ok := x.fsDirsMap[p]; !ok {
+			x.fsDirsMap[p] = make([]string, 0, len(fi))
+		}
 
 		for _, f := range fi {
-			if err := x.addPath(p, prefix, f); err != nil {
+			if err := x.addPath(p, prefix, f, depth+1); err != nil {
 				return err
 			}
 		}
-	} else {
-		x.appendFileInDir(parent, info.Name())
 	}
 
 	return nil
 }
 
+// Check whether directories at the given depth are added without their
+// contents, see MaxDepth.
+func (x *Generator) truncated(depth int) bool {
+	return x.MaxDepth > 0 && depth >= x.MaxDepth
+}
+
+// Record that the directory at generator path p was added without its
+// contents.
+func (x *Generator) truncate(p string) {
+	if vp, ok := x.virtualPath(p); ok {
+		x.truncatedDirs = append(x.truncatedDirs, vp)
+	}
+}
+
+// Get the sorted paths of the directories of the generator and its groups
+// which were added without their contents, because they are nested deeper
+// than MaxDepth.
+func (x *Generator) Truncated() []string {
+	var ret []string
+
+	for _, g := range append([]*Generator{x}, x.groups...) {
+		ret = append(ret, g.truncatedDirs...)
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// Match a path against a glob pattern (see path.Match). Patterns without a
+// slash are matched against the base name of the path, other patterns against
+// the full path.
+func matchPattern(pattern string, p string) bool {
+	target := path.Base(p)
+
+	if strings.Contains(pattern, "/") {
+		target = strings.TrimPrefix(p, "/")
+		pattern = strings.TrimPrefix(pattern, "/")
+	}
+
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// Check whether the given path is excluded by any of the Exclude patterns.
+func (x *Generator) excluded(p string) bool {
+	for _, pattern := range x.Exclude {
+		if matchPattern(pattern, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the sorted tags for the file at generator path k, assigned by Tags and
+// by metadata files.
+func (x *Generator) tags(k string) []string {
+	var ret []string
+
+	vp, _ := x.virtualPath(k)
+
+	for _, tag := range x.meta(k).tags {
+		if !containsString(ret, tag) {
+			ret = append(ret, tag)
+		}
+	}
+
+	for _, pattern := range sortedKeys(x.Tags) {
+		if !matchPattern(pattern, vp) {
+			continue
+		}
+
+		for _, tag := range x.Tags[pattern] {
+			if !containsString(ret, tag) {
+				ret = append(ret, tag)
+			}
+		}
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
 func (x *Generator) appendFileInDir(dir string, file string) {
-	for _, v := range x.fsDirsMap[dir] {
-		if v == file {
-			return
+	if x.fsDirsIndex == nil {
+		x.fsDirsIndex = make(map[string]map[string]bool)
+	}
+
+	index, ok := x.fsDirsIndex[dir]
+
+	if !ok {
+		// Index the existing entries, avoiding quadratic behavior when
+		// adding many files to a single directory
+		index = make(map[string]bool)
+
+		for _, v := range x.fsDirsMap[dir] {
+			index[v] = true
 		}
+
+		x.fsDirsIndex[dir] = index
+	}
+
+	if index[file] {
+		return
 	}
 
+	index[file] = true
 	x.fsDirsMap[dir] = append(x.fsDirsMap[dir], file)
 }
 
@@ -124,9 +722,7 @@ func (x *Generator) splitRelPrefix(p string) (string, string) {
 	return path.Join(p[0:i], "."), path.Join("/", p[i:])
 }
 
-// Add a file or directory asset to the generator. Added directories will be
-// recursed automatically.
-func (x *Generator) Add(p string) error {
+func (x *Generator) init() {
 	if x.fsFilesMap == nil {
 		x.fsFilesMap = make(map[string]file)
 	}
@@ -134,6 +730,102 @@ func (x *Generator) Add(p string) error {
 	if x.fsDirsMap == nil {
 		x.fsDirsMap = make(map[string][]string)
 	}
+}
+
+// Add a file entry at path p. Parent directories which do not exist yet are
+// created virtually.
+func (x *Generator) addEntry(p string, f file) {
+	x.init()
+
+	p = path.Join("/", p)
+
+	for d := p; d != "/"; {
+		parent, name := path.Dir(d), path.Base(d)
+
+		if _, ok := x.fsFilesMap[parent]; !ok {
+			x.fsFilesMap[parent] = file{
+				info: &virtualFileInfo{
+					name:  path.Base(parent),
+					mode:  os.ModeDir | 0755,
+					mtime: f.info.ModTime(),
+				},
+			}
+		}
+
+		if _, ok := x.fsDirsMap[parent]; !ok {
+			x.fsDirsMap[parent] = []string{}
+		}
+
+		x.appendFileInDir(parent, name)
+		d = parent
+	}
+
+	x.fsFilesMap[p] = f
+
+	if f.info.IsDir() {
+		if _, ok := x.fsDirsMap[p]; !ok {
+			x.fsDirsMap[p] = []string{}
+		}
+	}
+}
+
+// Add an in-memory file or directory at the virtual path p. Parent
+// directories which do not exist yet are created virtually.
+func (x *Generator) addVirtual(p string, mode os.FileMode, mtime time.Time, data []byte) {
+	f := virtualFile(path.Base(p), mode, mtime, data)
+	x.addEntry(p, f)
+
+	x.sources = append(x.sources, source{
+		add: func() error {
+			x.addEntry(p, f)
+			return nil
+		},
+	})
+}
+
+// Create an in-memory file or directory with the given name.
+func virtualFile(name string, mode os.FileMode, mtime time.Time, data []byte) file {
+	info := &virtualFileInfo{
+		name:  name,
+		mode:  mode,
+		mtime: mtime,
+	}
+
+	f := file{info: info}
+
+	if !mode.IsDir() {
+		if data == nil {
+			data = []byte{}
+		}
+
+		info.size = int64(len(data))
+		f.data = data
+	}
+
+	return f
+}
+
+// Add a file or directory asset to the generator. Added directories will be
+// recursed automatically.
+func (x *Generator) Add(p string) error {
+	if err := x.add(p); err != nil {
+		return err
+	}
+
+	x.sources = append(x.sources, source{
+		paths: []string{p},
+		add:   func() error { return x.add(p) },
+		excluded: func(p string, isDir bool) bool {
+			_, k := x.splitRelPrefix(path.Clean(filepath.ToSlash(p)))
+			return x.excluded(k)
+		},
+	})
+
+	return nil
+}
+
+func (x *Generator) add(p string) error {
+	x.init()
 
 	p = path.Clean(p)
 
@@ -149,150 +841,1417 @@ func (x *Generator) Add(p string) error {
 		return err
 	}
 
-	return x.addPath(path.Dir(p), prefix, info)
+	return x.addPath(path.Dir(p), prefix, info, 0)
 }
 
-func (x *Generator) stripPrefix(p string) (string, bool) {
-	if len(x.StripPrefix) == 0 {
-		return p, true
-	}
+// The data of a file as it is stored in the generated file system.
+type storedFile struct {
+	data []byte
 
-	if strings.HasPrefix(p, x.StripPrefix) {
-		return p[len(x.StripPrefix):], true
-	} else {
-		return p, false
-	}
+	// The hex encoded SHA-256 digest and the CRC-32 checksum of the
+	// original data, see Digests and Checksums
+	digest   string
+	checksum uint32
+
+	// The fingerprint of the original data, see Fingerprint
+	fingerprint string
+
+	// Whether data is compressed, and using which compression algorithm
+	compressed  bool
+	compression Compression
+
+	// Whether data is encrypted, see EncryptionKey
+	encrypted bool
+
+	// The error reading the file, when skipped, see ContinueOnError
+	err error
 }
 
-// Write the asset tree specified in the generator to the given writer. The
-// written asset tree is a valid, standalone go file with the assets
-// embedded into it.
-func (x *Generator) Write(wr io.Writer) error {
-	p := x.PackageName
+// Get the data of the file at path k as it is stored in the generated file
+// system.
+func (x *Generator) storedData(k string) (storedFile, error) {
+	data, err := x.read(k)
 
-	if len(p) == 0 {
-		p = "main"
+	if err != nil {
+		return storedFile{}, err
 	}
 
-	variableName := x.VariableName
+	var digest string
 
-	if len(variableName) == 0 {
-		variableName = "Assets"
+	if x.Digests {
+		sum := sha256.Sum256(data)
+		digest = hex.EncodeToString(sum[:])
 	}
 
-	writer := &bytes.Buffer{}
+	var checksum uint32
 
-	// Write package and import
-	fmt.Fprintf(writer, "package %s\n\n", p)
-	fmt.Fprintln(writer, "import (")
-	fmt.Fprintln(writer, "\t\"time\"")
-	fmt.Fprintln(writer)
-	fmt.Fprintln(writer, "\t\"github.com/jessevdk/go-assets\"")
-	fmt.Fprintln(writer, ")")
-	fmt.Fprintln(writer)
+	if x.Checksums {
+		checksum = crc32.ChecksumIEEE(data)
+	}
 
-	vnames := make(map[string]string)
+	var fingerprint string
 
-	// Write file contents as const strings
-	if x.fsFilesMap != nil {
-		// Create mapping from full file path to asset variable name.
-		// This also reads the file and writes the contents as a const
-		// string
-		for k, v := range x.fsFilesMap {
-			if v.info.IsDir() {
-				continue
-			}
+	if x.fingerprinted(k) {
+		fingerprint = fingerprintOf(data)
+	}
 
-			f, err := os.Open(v.path)
+	enabled, compression := x.Compressed, x.Compression
 
-			if err != nil {
-				return err
-			}
+	if c, ok := x.CompressionPolicy[path.Ext(k)]; ok {
+		enabled, compression = c != NoCompression, c
+	}
 
-			data, err := ioutil.ReadAll(f)
+	if c, ok := x.fsFilesMap[k].policy[path.Ext(k)]; ok {
+		enabled, compression = c != NoCompression, c
+	}
 
-			f.Close()
+	ret := storedFile{data: data, digest: digest, checksum: checksum, fingerprint: fingerprint}
 
-			if err != nil {
-				return err
-			}
+	if enabled && !x.precompressed(k) {
+		compressed, err := compress(data, compression, x.CompressionLevel)
 
-			s := sha1.New()
-			io.WriteString(s, k)
+		if err != nil {
+			return storedFile{}, err
+		}
 
-			vname := fmt.Sprintf("_%s%x", variableName, s.Sum(nil))
-			vnames[k] = vname
+		// Files which do not shrink sufficiently are not worth decompressing
+		saved := int64(len(data)) - int64(len(compressed))
+
+		if saved >= x.MinCompressionBytes && float64(saved)*100 >= x.MinCompressionSavings*float64(len(data)) {
+			ret.data, ret.compressed, ret.compression = compressed, true, compression
+		}
+	}
+
+	// Data is compressed before being encrypted, since encrypted data does
+	// not compress
+	if len(x.EncryptionKey) != 0 {
+		encrypted, err := encryptData(x.EncryptionKey, ret.data)
 
-			fmt.Fprintf(writer, "var %s = %#v\n", vname, string(data))
+		if err != nil {
+			return storedFile{}, fmt.Errorf("%s: %s", k, err)
 		}
 
-		fmt.Fprintln(writer)
+		ret.data, ret.encrypted = encrypted, true
 	}
 
-	fmt.Fprintf(writer, "// %s returns go-assets FileSystem\n", variableName)
-	fmt.Fprintf(writer, "var %s = assets.NewFileSystem(", variableName)
+	return ret, nil
+}
+
+// Check whether the file at path k is stored under a fingerprinted name, see
+// Fingerprint.
+func (x *Generator) fingerprinted(k string) bool {
+	vp, _ := x.virtualPath(k)
 
-	if x.fsDirsMap == nil {
-		x.fsDirsMap = make(map[string][]string)
+	for _, pattern := range x.Fingerprint {
+		if matchPattern(pattern, vp) {
+			return true
+		}
 	}
 
-	if x.fsFilesMap == nil {
-		x.fsFilesMap = make(map[string]file)
+	return false
+}
+
+// Get the fingerprint of data, see Fingerprint.
+func fingerprintOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Get the fingerprinted path of the asset at p, which inserts the
+// fingerprint before the extension of the file name.
+func fingerprintPath(p string, fingerprint string) string {
+	ext := path.Ext(p)
+
+	if ext == path.Base(p) {
+		// Hidden files without an extension, such as .htaccess
+		ext = ""
 	}
 
-	dirmap := make(map[string][]string)
+	return p[:len(p)-len(ext)] + "." + fingerprint + ext
+}
+
+// Check whether the file at path k is in an already compressed format, see
+// Precompressed.
+func (x *Generator) precompressed(k string) bool {
+	vp, _ := x.virtualPath(k)
+	patterns := x.Precompressed
 
-	for k, v := range x.fsDirsMap {
-		if kk, ok := x.stripPrefix(k); ok {
-			if len(kk) == 0 {
-				kk = "/"
-			}
+	if patterns == nil {
+		patterns = DefaultPrecompressed
+	}
 
-			dirmap[kk] = v
+	for _, pattern := range patterns {
+		if matchPattern(pattern, vp) {
+			return true
 		}
 	}
 
-	fmt.Fprintf(writer, "%#v, ", dirmap)
-	fmt.Fprintf(writer, "map[string]*assets.File{\n")
+	return false
+}
 
-	// Write files
-	for k, v := range x.fsFilesMap {
-		kk, ok := x.stripPrefix(k)
+// Register a logical key for the asset at the given path. The path is the
+// path of the asset in the generated file system (i.e. after StripPrefix has
+// been applied). Keys can be resolved at runtime using FileSystem.Lookup,
+// insulating application code from changes to the asset directory layout.
+func (x *Generator) AddKey(key string, p string) {
+	if x.keys == nil {
+		x.keys = make(map[string]string)
+	}
 
-		if !ok {
-			continue
-		}
+	x.keys[key] = path.Join("/", p)
+}
+
+// Import the assets of a previously generated asset file, such that newly
+// added files can be merged with a previously generated bundle. The file
+// system imported is the one named by VariableName, use Group(name).Import
+// to import other file systems from the same file. Imported assets keep
+// their path in the generated file system, i.e. StripPrefix is taken into
+// account. Files added later replace imported files at the same path.
+func (x *Generator) Import(filename string) error {
+	fss, err := ParseFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	variableName := x.VariableName
+
+	if len(variableName) == 0 {
+		variableName = "Assets"
+	}
+
+	fs, ok := fss[variableName]
+
+	if !ok {
+		return fmt.Errorf("%s does not define asset file system %s", filename, variableName)
+	}
 
-		if len(kk) == 0 {
-			kk = "/"
+	for p, f := range fs.Files {
+		var data []byte
+
+		if !f.IsDir() {
+			if data, err = fs.ReadFile(p); err != nil {
+				return err
+			}
 		}
 
-		mt := v.info.ModTime()
+		x.addVirtual(path.Join("/", x.StripPrefix, p), f.FileMode, f.Mtime, data)
+	}
+
+	for key, p := range fs.Keys {
+		x.AddKey(key, p)
+	}
 
-		var dt string
+	return nil
+}
 
-		if !v.info.IsDir() {
-			dt = "[]byte(" + vnames[k] + ")"
-		} else {
-			dt = "nil"
+// Get the named asset group, creating it if it does not exist yet. Each group
+// is written as a separate assets.FileSystem variable, named after the group,
+// into the same generated file. Assets are added to a group using the
+// returned generator, which initially shares all options of x (as set at the
+// time the group is created). Package-level options (such as PackageName) of
+// groups are ignored.
+func (x *Generator) Group(name string) *Generator {
+	for _, g := range x.groups {
+		if g.VariableName == name {
+			return g
 		}
+	}
+
+	// Share all options, but none of the assets and other state of x
+	g := *x
+
+	g.VariableName = name
+	g.fsDirsMap = nil
+	g.fsDirsIndex = nil
+	g.fsFilesMap = nil
+	g.keys = nil
+	g.groups = nil
+	g.stats = nil
+	g.sources = nil
+	g.dirMeta = nil
+	g.failures = nil
+	g.truncatedDirs = nil
+	g.inferredPackage = ""
+
+	x.groups = append(x.groups, &g)
+	return &g
+}
+
+func (x *Generator) stripPrefix(p string) (string, bool) {
+	if len(x.StripPrefix) == 0 {
+		return p, true
+	}
 
-		fmt.Fprintf(writer, "\t\t%#v: &assets.File{\n", kk)
-		fmt.Fprintf(writer, "\t\t\tPath: %#v,\n", kk)
-		fmt.Fprintf(writer, "\t\t\tFileMode: %#v,\n", v.info.Mode())
-		fmt.Fprintf(writer, "\t\t\tMtime: time.Unix(%#v, %#v),\n", mt.Unix(), mt.UnixNano())
-		fmt.Fprintf(writer, "\t\t\tData: %s,\n", dt)
-		fmt.Fprintf(writer, "\t\t},")
+	if strings.HasPrefix(p, x.StripPrefix) {
+		return p[len(x.StripPrefix):], true
+	} else {
+		return p, false
 	}
+}
 
-	fmt.Fprintln(writer, "\t}, \"\")")
+// Get the path of an asset in the generated file system from its path in the
+// generator. Returns false if the asset is not part of the generated file
+// system.
+func (x *Generator) virtualPath(p string) (string, bool) {
+	p, ok := x.stripPrefix(p)
 
-	ret, err := format.Source(writer.Bytes())
+	if !ok {
+		return p, false
+	}
 
-	if err != nil {
+	if len(p) == 0 {
+		p = "/"
+	}
+
+	return x.Normalization.normalize(p), true
+}
+
+// Write the asset tree specified in the generator to the given writer. The
+// written asset tree is a valid, standalone go file with the assets
+// embedded into it.
+func (x *Generator) Write(wr io.Writer) error {
+	if len(x.PackFile) != 0 || len(x.EmbedDir) != 0 {
+		return fmt.Errorf("pack files and embedded directories can only be written by WriteFile")
+	}
+
+	out := &dataOutput{}
+
+	if err := x.writeAll(wr, out); err != nil {
 		return err
 	}
 
-	wr.Write(ret)
+	return x.partialError(out)
+}
+
+// Where the asset data is written to, other than the generated file itself.
+type dataOutput struct {
+	// Data files the asset data is written to, see DataShardSize
+	shards *dataShards
+
+	// The pack file the asset data is written to, see PackFile
+	pack *packWriter
+
+	// The embedded directory the asset data is written to, see EmbedDir
+	embed *embedWriter
+
+	// Whether the test-only assets are written, see WriteTest
+	test bool
+
+	// The directory of the disk-backed file written for development, see
+	// DevTag. Asset sources are referenced relative to this directory
+	dev string
+
+	// The build constraint of the generated file, see DevTag
+	constraint string
+
+	// The digest of the inputs recorded in the generated file, see
+	// SkipUnchanged
+	inputs string
+
+	// The files which could not be read, see ContinueOnError
+	failures []Failure
+
+	// The temporary files holding the asset data declarations of the file
+	// systems by variable name, see spool
+	spooled map[string]*os.File
+}
+
+// Write the asset tree to the given writer, writing the asset data to the
+// given data output.
+func (x *Generator) writeAll(wr io.Writer, out *dataOutput) error {
+	start := time.Now()
+	stats := &Stats{}
+
+	if err := x.write(wr, x.fileSystems(), stats, out); err != nil {
+		return err
+	}
+
+	stats.Elapsed = time.Since(start)
+	stats.Truncated = x.Truncated()
+	x.stats = stats
+
+	if x.StatsOutput != nil {
+		return stats.Print(x.StatsOutput)
+	}
+
+	return nil
+}
+
+// Get the generators of the file systems written to the generated file (the
+// generator itself and its groups), without test-only assets.
+func (x *Generator) fileSystems() []*Generator {
+	var fss []*Generator
+
+	if len(x.groups) == 0 || len(x.fsFilesMap) != 0 {
+		fss = append(fss, x.partition(false))
+	}
+
+	for _, g := range x.groups {
+		fss = append(fss, g.partition(false))
+	}
+
+	return fss
+}
+
+// Write a go file defining the file systems of the given generators. The
+// asset data is written to the data output.
+func (x *Generator) write(wr io.Writer, fss []*Generator, stats *Stats, out *dataOutput) error {
+	for _, g := range fss {
+		if g.SharedData != x.SharedData {
+			return fmt.Errorf("group %s: SharedData applies to all file systems and must match the generator", g.VariableName)
+		}
+	}
+
+	writer := &bytes.Buffer{}
+
+	var contents map[string]string
+
+	if x.SharedData {
+		contents = make(map[string]string)
+	}
+
+	if out.pack != nil {
+		out.pack.path = x.PackFile
+		out.pack.digestName = "_assetsPackDigest"
+		out.pack.appended = x.AppendPack
+	}
+
+	if out.embed != nil {
+		out.embed.dir = filepath.ToSlash(x.EmbedDir)
+	}
+
+	if x.SelfContained && (out.pack != nil || out.embed != nil || len(x.DevTag) != 0) {
+		return fmt.Errorf("cannot combine SelfContained with PackFile, EmbedDir or DevTag")
+	}
+
+	if x.SelfContained && len(x.EncryptionKey) != 0 {
+		return fmt.Errorf("cannot combine SelfContained and EncryptionKey")
+	}
+
+	// Assets read from their source in development cannot be verified
+	if len(x.SigningKey) != 0 && (x.SelfContained || len(x.DevTag) != 0) {
+		return fmt.Errorf("cannot combine SigningKey with SelfContained or DevTag")
+	}
+
+	banner, err := x.banner()
+
+	if err != nil {
+		return err
+	}
+
+	if len(out.inputs) != 0 {
+		banner = recordInputs(banner, out.inputs)
+	}
+
+	var imports bytes.Buffer
+	x.writeImports(&imports, out)
+
+	file := &OutputFile{
+		Banner:     banner,
+		Constraint: out.constraint,
+		Package:    x.packageName(),
+		Header:     commentBlock(x.Header),
+		Imports:    imports.String(),
+	}
+
+	defer out.removeSpooled()
+
+	for _, g := range fss {
+		fs, err := g.outputFileSystem(stats, out, contents)
+
+		if err != nil {
+			return err
+		}
+
+		file.FileSystems = append(file.FileSystems, fs)
+	}
+
+	var footer strings.Builder
+
+	if out.pack != nil {
+		fmt.Fprintf(&footer, "const %s = %q\n", out.pack.digestName, out.pack.digest())
+	}
+
+	if out.embed != nil {
+		out.embed.writeVars(&footer)
+	}
+
+	if len(out.dev) != 0 {
+		footer.WriteString(devDirFunc)
+	}
+
+	file.Footer = footer.String()
+
+	tmpl := x.Template
+
+	if tmpl == nil {
+		tmpl = DefaultTemplate
+	}
+
+	if err := tmpl.Execute(writer, file); err != nil {
+		return err
+	}
+
+	// Only the code surrounding the asset data is formatted, formatting the
+	// data would require several times its size in memory
+	ret, err := x.formatSource(writer.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	return writeData(wr, ret, out)
+}
+
+// The prefix of the marker comments written in place of the asset data
+// declarations of file systems, see OutputFileSystem.Data.
+const dataMarker = "//go-assets:data "
+
+// Get the declaration of the asset data variable name. Declarations are
+// formatted as gofmt would format them.
+func (x *Generator) dataDecl(name string, data []byte) string {
+	if x.ByteSlices {
+		return fmt.Sprintf("var %s = %s\n", name, byteSliceLiteral(data))
+	}
+
+	return fmt.Sprintf("const %s = %s\n", name, x.dataLiteral(data))
+}
+
+// Write the formatted code src to wr, replacing the data markers of the file
+// systems by their spooled asset data declarations (see dataOutput.spool).
+// The data is copied from the spool files, such that it is never held in
+// memory as a whole. Templates which do not write the data of a file system
+// (see OutputFileSystem.Data) fail.
+func writeData(wr io.Writer, src []byte, out *dataOutput) error {
+	bw := bufio.NewWriter(wr)
+	written := make(map[string]bool)
+
+	for len(src) != 0 {
+		i := bytes.Index(src, []byte("\n"+dataMarker))
+
+		if i < 0 {
+			bw.Write(src)
+			break
+		}
+
+		bw.Write(src[:i+1])
+		src = src[i+1+len(dataMarker):]
+
+		name := src
+
+		if end := bytes.IndexByte(src, '\n'); end >= 0 {
+			name, src = src[:end], src[end+1:]
+		} else {
+			src = nil
+		}
+
+		fd, ok := out.spooled[string(name)]
+
+		if !ok {
+			return fmt.Errorf("unknown asset data marker for %s", name)
+		}
+
+		if _, err := fd.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		if _, err := io.Copy(bw, fd); err != nil {
+			return err
+		}
+
+		written[string(name)] = true
+	}
+
+	for _, name := range sortedKeys(out.spooled) {
+		if !written[name] {
+			return fmt.Errorf("template does not write the asset data of %s, see OutputFileSystem.Data", name)
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Spool the declaration of asset data of the file system variable name to a
+// temporary file, from which it is copied to the output by writeData. This
+// keeps the data of only a single file in memory at a time.
+func (out *dataOutput) spool(name string, decl string) error {
+	fd, ok := out.spooled[name]
+
+	if !ok {
+		var err error
+
+		if fd, err = ioutil.TempFile("", "go-assets-data"); err != nil {
+			return err
+		}
+
+		if out.spooled == nil {
+			out.spooled = make(map[string]*os.File)
+		}
+
+		out.spooled[name] = fd
+	}
+
+	_, err := io.WriteString(fd, decl)
+	return err
+}
+
+// Remove the temporary files of the spooled asset data.
+func (out *dataOutput) removeSpooled() {
+	for name, fd := range out.spooled {
+		fd.Close()
+		os.Remove(fd.Name())
+		delete(out.spooled, name)
+	}
+}
+
+// Write the asset tree to the file at filename, as Write does. The file is
+// only written when generation succeeds and is replaced atomically, such that
+// a failed generation does not leave a broken asset file behind. Unless
+// PackageName is set, the package name is taken from the other go files in
+// the directory of filename, or derived from the name of the directory if
+// there are none. Test-only assets (see TestOnly) are
+// written to a corresponding _testonly_test.go file (e.g.
+// assets_testonly_test.go for assets.go), see WriteTest. The asset data is
+// written to separate data files when DataShardSize is set.
+func (x *Generator) WriteFile(filename string) error {
+	if len(x.PackageName) == 0 {
+		x.inferredPackage = inferPackageName(filepath.Dir(filename))
+		defer func() { x.inferredPackage = "" }()
+	}
+
+	out := &dataOutput{}
+
+	if len(x.PackFile) != 0 && len(x.EmbedDir) != 0 {
+		return fmt.Errorf("cannot combine PackFile and EmbedDir")
+	}
+
+	if len(x.PackFile) != 0 {
+		out.pack = &packWriter{}
+		defer out.pack.remove()
+	} else if len(x.EmbedDir) != 0 {
+		out.embed = &embedWriter{}
+	} else if x.DataShardSize > 0 {
+		out.shards = &dataShards{limit: x.DataShardSize}
+	}
+
+	if len(x.DevTag) != 0 {
+		out.constraint = "!" + x.DevTag
+	}
+
+	if x.SkipUnchanged {
+		digest, err := x.inputsDigest()
+
+		if err != nil {
+			return err
+		}
+
+		if len(digest) != 0 && recordedInputs(filename) == digest && x.companionFilesExist(filename) {
+			return nil
+		}
+
+		out.inputs = digest
+	}
+
+	// The generated file is streamed to disk, which keeps the memory used
+	// for generating large files low
+	err := writeFileAtomicFunc(filename, func(w io.Writer) error {
+		return x.writeAll(w, out)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if err := x.writeDataFiles(filename, out.shards); err != nil {
+		return err
+	}
+
+	if out.pack != nil {
+		if err := out.pack.writeFile(filepath.Join(filepath.Dir(filename), x.PackFile)); err != nil {
+			return err
+		}
+	}
+
+	if out.embed != nil {
+		if err := out.embed.writeDir(filepath.Join(filepath.Dir(filename), x.EmbedDir)); err != nil {
+			return err
+		}
+	}
+
+	if len(x.DevTag) != 0 {
+		if err := x.writeDevFile(filename); err != nil {
+			return err
+		}
+	}
+
+	if x.EmbeddedTest {
+		if err := x.writeEmbeddedTestFile(filename); err != nil {
+			return err
+		}
+	}
+
+	if err := x.writeTestFile(filename); err != nil {
+		return err
+	}
+
+	return x.partialError(out)
+}
+
+// Get the sorted paths of all files (not directories) in the generator which
+// are part of the generated file system.
+func (x *Generator) filePaths() []string {
+	var ret []string
+
+	for _, k := range x.sortedPaths() {
+		if x.fsFilesMap[k].info.IsDir() {
+			continue
+		}
+
+		if _, ok := x.virtualPath(k); ok {
+			ret = append(ret, k)
+		}
+	}
+
+	return ret
+}
+
+// Get the directory map of the generated file system, with sorted entries.
+func (x *Generator) virtualDirs() map[string][]string {
+	ret := make(map[string][]string)
+
+	for k, v := range x.fsDirsMap {
+		if kk, ok := x.virtualPath(k); ok {
+			names := make([]string, len(v))
+
+			for i, name := range v {
+				names[i] = x.Normalization.normalize(name)
+			}
+
+			sort.Strings(names)
+			ret[kk] = names
+		}
+	}
+
+	return ret
+}
+
+// Get the paths of all assets in the generator in sorted order, such that
+// the generated output is deterministic.
+func (x *Generator) sortedPaths() []string {
+	ret := make([]string, 0, len(x.fsFilesMap))
+
+	for k := range x.fsFilesMap {
+		ret = append(ret, k)
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	ret := make([]string, 0, len(m))
+
+	for k := range m {
+		ret = append(ret, k)
+	}
+
+	sort.Strings(ret)
+	return ret
+}
+
+// Get the time modification times are clamped to, see MaxModTime. Returns
+// the zero time if modification times are not clamped.
+func (x *Generator) maxModTime() (time.Time, error) {
+	if !x.MaxModTime.IsZero() {
+		return x.MaxModTime, nil
+	}
+
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+
+	if len(epoch) == 0 {
+		return time.Time{}, nil
+	}
+
+	sec, err := strconv.ParseInt(epoch, 10, 64)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %s", epoch, err)
+	}
+
+	return time.Unix(sec, 0), nil
+}
+
+// Get the modification time of an asset as written to the generated file
+// system, given the time modification times are clamped to.
+func (x *Generator) modTime(info os.FileInfo, max time.Time) time.Time {
+	if x.OmitMTime {
+		return time.Time{}
+	}
+
+	if x.Hermetic {
+		return max
+	}
+
+	if t := info.ModTime(); max.IsZero() || !t.After(max) {
+		return t
+	}
+
+	return max
+}
+
+// Get the output of the file system of the generator. Asset data is
+// deduplicated using contents, a map of data digests to the variables holding
+// the data, which is shared between file systems with SharedData.
+func (x *Generator) outputFileSystem(stats *Stats, out *dataOutput, contents map[string]string) (*OutputFileSystem, error) {
+	variableName := x.VariableName
+
+	if len(variableName) == 0 {
+		variableName = "Assets"
+	}
+
+	ret := &OutputFileSystem{VariableName: variableName}
+
+	if x.Base64 && x.ByteSlices {
+		return nil, fmt.Errorf("cannot combine Base64 and ByteSlices")
+	}
+
+	if err := x.checkLicenses(); err != nil {
+		return nil, err
+	}
+
+	maxModTime, err := x.maxModTime()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if x.Hermetic {
+		if err := x.checkHermetic(); err != nil {
+			return nil, err
+		}
+	}
+
+	vnames := make(map[string]string)
+	if contents == nil {
+		contents = make(map[string]string)
+	}
+	compressions := make(map[string]Compression)
+	digests := make(map[string]string)
+	checksums := make(map[string]uint32)
+	fingerprints := make(map[string]string)
+	encrypted := make(map[string]bool)
+	signed := make(map[string]signedFile)
+	failed := make(map[string]bool)
+
+	// Write file contents as const strings
+	if x.fsFilesMap != nil {
+		// Create mapping from full file path to asset variable name.
+		// This also reads the file and writes the contents as a const
+		// string
+		paths := x.filePaths()
+
+		err := x.eachStored(paths, func(i int, sf storedFile) error {
+			k := paths[i]
+			v := x.fsFilesMap[k]
+			vp, _ := x.virtualPath(k)
+			data := sf.data
+
+			if sf.err != nil {
+				out.failures = append(out.failures, Failure{Path: vp, Err: sf.err})
+				failed[k] = true
+				return nil
+			}
+
+			if sf.compressed {
+				compressions[k] = sf.compression
+			}
+
+			if len(sf.digest) != 0 {
+				digests[k] = sf.digest
+			}
+
+			if sf.checksum != 0 {
+				checksums[k] = sf.checksum
+			}
+
+			if len(sf.fingerprint) != 0 {
+				fingerprints[k] = sf.fingerprint
+			}
+
+			if sf.encrypted {
+				encrypted[k] = true
+			}
+
+			if len(x.SigningKey) != 0 {
+				compression := NoCompression
+
+				if sf.compressed {
+					compression = sf.compression
+				}
+
+				signed[k] = signedFile{mode: x.fileMode(k, v.info), compression: compression, encrypted: sf.encrypted, sum: sha256.Sum256(data)}
+			}
+
+			stats.add(vp, v.info.Size(), int64(len(data)))
+
+			// Files are read from their source in development
+			if src, ok := x.devSource(k, out); ok {
+				vnames[k] = fmt.Sprintf("Source: %q", src)
+				delete(compressions, k)
+				delete(digests, k)
+				delete(checksums, k)
+				delete(encrypted, k)
+				return nil
+			}
+
+			// Files with identical contents share a single variable
+			digest := x.digest(data)
+
+			if vname, ok := contents[digest]; ok {
+				vnames[k] = vname
+				return nil
+			}
+
+			if out.pack != nil {
+				offset, err := out.pack.add(data)
+
+				if err != nil {
+					return err
+				}
+
+				vnames[k] = fmt.Sprintf("Offset: %d, Length: %d", offset, len(data))
+				contents[digest] = vnames[k]
+				return nil
+			}
+
+			if out.embed != nil {
+				vnames[k] = fmt.Sprintf("EmbedName: %q", out.embed.add(data))
+				contents[digest] = vnames[k]
+				return nil
+			}
+
+			// Name the variable after the virtual path, since the
+			// generator path depends on how and where files were added
+			s := sha1.New()
+			io.WriteString(s, vp)
+
+			vname := fmt.Sprintf("_%s%x", variableName, s.Sum(nil))
+			vnames[k] = vname
+			contents[digest] = vname
+
+			if out.shards != nil {
+				out.shards.add(vname, x.dataDecl(vname, data))
+				return nil
+			}
+
+			return out.spool(variableName, x.dataDecl(vname, data))
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := out.spooled[variableName]; ok {
+		ret.Data = dataMarker + variableName + "\n"
+	}
+
+	if x.fsDirsMap == nil {
+		x.fsDirsMap = make(map[string][]string)
+	}
+
+	if x.fsFilesMap == nil {
+		x.fsFilesMap = make(map[string]file)
+	}
+
+	// Write the file entries as a statically initialized table. Unlike a
+	// map literal, this does not require any init code to be compiled,
+	// which keeps compilation fast for file systems with many files.
+	// Directory listings are derived from the entries at runtime.
+	entryType := "assets.FileEntry"
+
+	if x.SelfContained {
+		entryType = "assetsFileEntry"
+	}
+
+	ret.EntryType = entryType
+
+	written := make(map[string]bool)
+	skipped := make(map[string]bool)
+	urls := make(map[string]string)
+
+	// The logical and served paths of files, see PathConstants
+	var logical []string
+	served := make(map[string]string)
+
+	// The signed files by served path, see SigningKey
+	signedFiles := make(map[string]signedFile)
+
+	for _, k := range x.sortedPaths() {
+		v := x.fsFilesMap[k]
+		kk, ok := x.virtualPath(k)
+
+		if !ok {
+			continue
+		}
+
+		if failed[k] {
+			skipped[kk] = true
+			continue
+		}
+
+		written[kk] = true
+		lp := kk
+
+		if fingerprint, ok := fingerprints[k]; ok {
+			urls[kk] = fingerprintPath(kk, fingerprint)
+			kk = urls[kk]
+		}
+
+		if !v.info.IsDir() {
+			logical = append(logical, lp)
+			served[lp] = kk
+		}
+
+		if sf, ok := signed[k]; ok {
+			signedFiles[kk] = sf
+		}
+
+		var entry strings.Builder
+
+		fmt.Fprintf(&entry, "{Path: %#v, FileMode: %#v", kk, x.fileMode(k, v.info))
+
+		if mt := x.modTime(v.info, maxModTime); !mt.IsZero() {
+			fmt.Fprintf(&entry, ", Mtime: %#v", mt.UnixNano())
+		}
+
+		if _, ok := x.devSource(k, out); ok || (!v.info.IsDir() && (out.pack != nil || out.embed != nil)) {
+			fmt.Fprintf(&entry, ", %s", vnames[k])
+		} else if !v.info.IsDir() {
+			if x.ByteSlices {
+				fmt.Fprintf(&entry, ", Bytes: %s", vnames[k])
+			} else {
+				fmt.Fprintf(&entry, ", Data: %s", vnames[k])
+			}
+
+			if x.Base64 {
+				fmt.Fprint(&entry, ", Base64: true")
+			}
+		}
+
+		if c, ok := compressions[k]; ok {
+			fmt.Fprint(&entry, ", Compressed: true")
+
+			if c != Gzip && x.SelfContained {
+				return nil, fmt.Errorf("%s: self-contained file systems only support gzip compression", kk)
+			} else if c != Gzip {
+				fmt.Fprintf(&entry, ", Compression: assets.%s", c)
+			}
+		}
+
+		if digest, ok := digests[k]; ok {
+			fmt.Fprintf(&entry, ", Digest: %q", digest)
+		}
+
+		if _, ok := fingerprints[k]; ok {
+			fmt.Fprint(&entry, ", Fingerprinted: true")
+		}
+
+		if checksum, ok := checksums[k]; ok {
+			fmt.Fprintf(&entry, ", Checksum: 0x%08x", checksum)
+		}
+
+		if encrypted[k] {
+			fmt.Fprint(&entry, ", Encrypted: true")
+		}
+
+		if tags := x.tags(k); len(tags) != 0 {
+			fmt.Fprintf(&entry, ", Tags: %#v", tags)
+		}
+
+		m := x.meta(k)
+
+		if len(m.title) != 0 {
+			fmt.Fprintf(&entry, ", Title: %#v", m.title)
+		}
+
+		if m.order != 0 {
+			fmt.Fprintf(&entry, ", Order: %d", m.order)
+		}
+
+		entry.WriteString("}")
+		ret.Entries = append(ret.Entries, OutputEntry{Path: kk, Literal: entry.String()})
+	}
+
+	if x.PathConstants && len(logical) != 0 {
+		ret.Paths = pathConstants(variableName, logical, served)
+	}
+
+	if x.Accessors && len(logical) != 0 {
+		ret.Accessors = accessorFuncs(variableName, logical, served)
+	}
+
+	if out.pack != nil {
+		constructor := "NewPackFileSystem"
+
+		if out.pack.appended {
+			constructor = "NewAppendedPackFileSystem"
+		}
+
+		ret.Constructor = fmt.Sprintf("assets.%s(%d, _%sEntries[:], %q, %s)", constructor, FormatVersion, variableName, out.pack.path, out.pack.digestName)
+	} else if len(out.dev) != 0 {
+		ret.Constructor = fmt.Sprintf("assets.NewDevFileSystem(%d, _%sEntries[:], _assetsDevDir())", FormatVersion, variableName)
+	} else if x.SelfContained {
+		ret.Constructor = fmt.Sprintf("newAssetsFileSystem(_%sEntries[:])", variableName)
+	} else if out.embed != nil {
+		ret.Constructor = fmt.Sprintf("assets.NewEmbedFileSystem(%d, _%sEntries[:], _assetsEmbed, %q)", FormatVersion, variableName, out.embed.dir)
+	} else {
+		ret.Constructor = fmt.Sprintf("assets.NewVersionedFileSystem(%d, _%sEntries[:], \"\")", FormatVersion, variableName)
+	}
+
+	if x.CacheDecompressed {
+		ret.Init = append(ret.Init, fmt.Sprintf("%s.CacheDecompressed = true", variableName))
+	}
+
+	if x.Precompressed != nil && !x.SelfContained {
+		ret.Init = append(ret.Init, fmt.Sprintf("%s.Precompressed = %#v", variableName, x.Precompressed))
+	}
+
+	var keys map[string]string
+
+	if len(x.keys) != 0 {
+		keys = make(map[string]string)
+
+		for _, key := range sortedKeys(x.keys) {
+			p := x.Normalization.normalize(x.keys[key])
+
+			// Keys of skipped files are dropped with them
+			if skipped[p] {
+				continue
+			}
+
+			if !written[p] {
+				return nil, fmt.Errorf("asset key %q refers to non-existing asset %s", key, p)
+			}
+
+			if url, ok := urls[p]; ok {
+				p = url
+			}
+
+			keys[key] = p
+		}
+
+		ret.Init = append(ret.Init, fmt.Sprintf("%s.Keys = %#v", variableName, keys))
+	}
+
+	if len(urls) != 0 {
+		ret.Init = append(ret.Init, fmt.Sprintf("%s.Fingerprints = %#v", variableName, urls))
+	}
+
+	if len(x.SigningKey) != 0 {
+		if len(x.SigningKey) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid signing key size %d", len(x.SigningKey))
+		}
+
+		signature := ed25519.Sign(x.SigningKey, signedDigest(signedFiles, keys))
+		ret.Init = append(ret.Init, fmt.Sprintf("%s.Signature = %#v", variableName, signature))
+	}
+
+	return ret, nil
+}
+
+// Get a go string literal for data. Text is written as a raw string literal,
+// which is much smaller and faster to compile than an interpreted string
+// literal escaping every newline and quote. Data which cannot be represented
+// as a raw string literal (e.g. binary data, carriage returns which are
+// stripped from raw string literals, or backquotes) is escaped instead.
+func stringLiteral(data []byte) string {
+	if !utf8.Valid(data) {
+		return quoteBytes(data)
+	}
+
+	for _, r := range string(data) {
+		if r == '`' || r == '\uFEFF' || r == 0x7f || (r < 0x20 && r != '\n' && r != '\t') {
+			return quoteBytes(data)
+		}
+	}
+
+	return "`" + string(data) + "`"
+}
+
+// Get an interpreted string literal holding exactly the bytes of data.
+// Printable characters are written as is, all other bytes (including the
+// bytes of invalid UTF-8 sequences) are escaped individually, such that
+// arbitrary binary data round-trips regardless of its encoding.
+func quoteBytes(data []byte) string {
+	const hex = "0123456789abcdef"
+
+	var buf strings.Builder
+	buf.Grow(len(data) + len(data)/4 + 2)
+
+	buf.WriteByte('"')
+
+	for len(data) != 0 {
+		r, size := utf8.DecodeRune(data)
+
+		if (r == utf8.RuneError && size <= 1) || r == '\uFEFF' || !strconv.IsPrint(r) {
+			switch c := data[0]; c {
+			case '\a':
+				buf.WriteString(`\a`)
+			case '\b':
+				buf.WriteString(`\b`)
+			case '\f':
+				buf.WriteString(`\f`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			case '\v':
+				buf.WriteString(`\v`)
+			default:
+				buf.WriteString(`\x`)
+				buf.WriteByte(hex[c>>4])
+				buf.WriteByte(hex[c&0xf])
+			}
+
+			data = data[1:]
+			continue
+		}
+
+		if r == '"' || r == '\\' {
+			buf.WriteByte('\\')
+		}
+
+		buf.Write(data[:size])
+		data = data[size:]
+	}
+
+	buf.WriteByte('"')
+	return buf.String()
+}
+
+// Get the digest identifying identical asset data, see DedupHash.
+func (x *Generator) digest(data []byte) string {
+	newHash := x.DedupHash
+
+	if newHash == nil {
+		newHash = sha1.New
+	}
+
+	h := newHash()
+	h.Write(data)
+
+	// Data is only shared between file systems using the same encoding
+	return fmt.Sprintf("%t,%t,%s", x.Base64, x.ByteSlices, h.Sum(nil))
+}
+
+// Get the go string literal of the stored data of a file, split into chunks
+// of at most ChunkSize bytes.
+func (x *Generator) dataLiteral(data []byte) string {
+	if x.Base64 {
+		data = []byte(base64.StdEncoding.EncodeToString(data))
+	}
+
+	size := x.ChunkSize
+
+	if size == 0 {
+		size = DefaultChunkSize
+	}
+
+	if size < 0 || len(data) <= size {
+		return stringLiteral(data)
+	}
+
+	var chunks []string
+
+	for len(data) != 0 {
+		n := size
+
+		if n >= len(data) {
+			n = len(data)
+		} else {
+			// Avoid splitting multi-byte characters, such that text
+			// chunks can still be written as raw string literals
+			for n > 0 && !utf8.RuneStart(data[n]) {
+				n--
+			}
+
+			if n == 0 {
+				n = size
+			}
+		}
+
+		chunks = append(chunks, stringLiteral(data[:n]))
+		data = data[n:]
+	}
+
+	return strings.Join(chunks, " +\n\t")
+}
+
+// Get a go byte slice literal for data, with 16 bytes per line.
+func byteSliceLiteral(data []byte) string {
+	var buf bytes.Buffer
+
+	buf.WriteString("[]byte{")
+
+	for i, b := range data {
+		if i%16 == 0 {
+			buf.WriteString("\n\t")
+		} else {
+			buf.WriteString(" ")
+		}
+
+		fmt.Fprintf(&buf, "0x%02x,", b)
+	}
+
+	if len(data) != 0 {
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("}")
+	return buf.String()
+}
+
+// Read the stored data of the files at the given paths concurrently, see
+// parallel. The results are returned in the order of paths, such that the
+// output assembled from them is deterministic. With ContinueOnError, files
+// which cannot be read are returned with their error instead.
+func (x *Generator) readAll(paths []string) ([]storedFile, error) {
+	ret := make([]storedFile, len(paths))
+
+	err := x.parallel(len(paths), func(i int) error {
+		var err error
+
+		ret[i], err = x.storedData(paths[i])
+
+		if err != nil && x.ContinueOnError {
+			ret[i].err = err
+			return nil
+		}
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// Read the stored data of the files at the given paths like readAll, calling
+// fn with the results in the order of paths. Files are read concurrently in a
+// sliding window of one file per worker ahead of fn, such that only the data
+// of the files in the window is held in memory at a time, without waiting for
+// all files of the window to be read before reading the next ones.
+func (x *Generator) eachStored(paths []string, fn func(i int, sf storedFile) error) error {
+	type result struct {
+		sf  storedFile
+		err error
+	}
+
+	// The results of the files being read, in the order of paths
+	window := make(chan chan result, x.workers())
+	done := make(chan struct{})
+
+	defer close(done)
+
+	go func() {
+		defer close(window)
+
+		for _, p := range paths {
+			future := make(chan result, 1)
+
+			select {
+			case window <- future:
+			case <-done:
+				return
+			}
+
+			go func(p string) {
+				sf, err := x.storedData(p)
+
+				if err != nil && x.ContinueOnError {
+					sf.err, err = err, nil
+				}
+
+				future <- result{sf, err}
+			}(p)
+		}
+	}()
+
+	i := 0
+
+	for future := range window {
+		r := <-future
+
+		if r.err != nil {
+			return r.err
+		}
+
+		if err := fn(i, r.sf); err != nil {
+			return err
+		}
+
+		i++
+	}
+
+	return nil
+}
+
+// Get the number of workers reading files concurrently, see Concurrency.
+func (x *Generator) workers() int {
+	if x.Concurrency <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+
+	return x.Concurrency
+}
+
+// Call fn for the indices 0 to n-1 using a bounded pool of workers (see
+// Concurrency). Reading and compressing files dominates generation time, and
+// is therefore spread over all workers. If fn fails for any of the indices,
+// the error of the lowest failed index is returned, such that the result
+// does not depend on scheduling.
+func (x *Generator) parallel(n int, fn func(i int) error) error {
+	workers := x.workers()
+
+	if workers > n {
+		workers = n
+	}
+
+	errs := make([]error, n)
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range jobs {
+				errs[j] = fn(j)
+			}
+		}()
+	}
+
+	for j := 0; j < n; j++ {
+		jobs <- j
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
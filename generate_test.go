@@ -0,0 +1,3335 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"hash"
+	"hash/crc32"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func generate(t *testing.T, g *Generator) []byte {
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err != nil {
+		t.Fatalf("failed to write assets: %s", err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestWriteDeterministic(t *testing.T) {
+	var outputs [][]byte
+
+	for i := 0; i < 2; i++ {
+		g := &Generator{StripPrefix: "/testdata"}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, generate(t, g))
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("expected consecutive runs to produce identical output")
+	}
+}
+
+func TestWriteSourceDateEpoch(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1000")
+
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, f := range fss["Assets"].Files {
+		if !f.Mtime.Equal(time.Unix(1000, 0)) {
+			t.Errorf("expected mtime of %s to be clamped, got %s", p, f.Mtime)
+		}
+	}
+}
+
+func TestWriteVariableNames(t *testing.T) {
+	// The same assets added differently have the same variable names
+	var names [][]string
+
+	for _, test := range []struct {
+		dir string
+		add string
+	}{
+		{".", "testdata/templates"},
+		{"testdata", "templates"},
+	} {
+		g := &Generator{StripPrefix: "/" + test.add}
+		wd, _ := os.Getwd()
+
+		os.Chdir(test.dir)
+		err := g.Add(test.add)
+
+		var buf bytes.Buffer
+
+		if err == nil {
+			err = g.Write(&buf)
+		}
+
+		os.Chdir(wd)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		names = append(names, regexp.MustCompile(`_Assets[0-9a-f]{40}`).FindAllString(buf.String(), -1))
+	}
+
+	if len(names[0]) == 0 || strings.Join(names[0], " ") != strings.Join(names[1], " ") {
+		t.Errorf("expected identical variable names, got %v and %v", names[0], names[1])
+	}
+}
+
+func TestWriteOmitMTime(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", OmitMTime: true}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, f := range fss["Assets"].Files {
+		if !f.Mtime.IsZero() {
+			t.Errorf("expected mtime of %s to be omitted, got %s", p, f.Mtime)
+		}
+	}
+}
+
+func TestWriteNoTimeImport(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modification times are written as integers, decoded when the file
+	// system is created
+	src := generate(t, g)
+
+	if bytes.Contains(src, []byte(`"time"`)) || bytes.Contains(src, []byte("time.")) {
+		t.Errorf("expected generated file not to use the time package, got:\n%s", src)
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f := fss["Assets"].Files["/templates/index.html"]; f.Mtime.IsZero() {
+		t.Errorf("expected mtime to be set")
+	}
+}
+
+func TestWriteCompressionLevel(t *testing.T) {
+	var sizes []int
+
+	for _, level := range []int{gzip.BestSpeed, gzip.BestCompression} {
+		g := &Generator{StripPrefix: "/testdata", Compressed: true, CompressionLevel: level}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		fss, err := Parse(generate(t, g))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := fss["Assets"].ReadFile("/static/app.js"); err != nil {
+			t.Fatal(err)
+		}
+
+		sizes = append(sizes, len(fss["Assets"].Files["/static/app.js"].Data))
+	}
+
+	if sizes[0] == 0 || sizes[1] > sizes[0] {
+		t.Errorf("expected best compression to be at least as small as best speed, got %v", sizes)
+	}
+
+	g := &Generator{StripPrefix: "/testdata", Compressed: true, CompressionLevel: 42}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected invalid compression level to fail")
+	}
+}
+
+func TestWritePrecompressed(t *testing.T) {
+	dir := t.TempDir()
+	data := strings.Repeat("compressible ", 1000)
+
+	for _, name := range []string{"app.js", "app.css"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Compressed: true, Precompressed: []string{"*.js"}}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if !reflect.DeepEqual(fs.Precompressed, g.Precompressed) {
+		t.Errorf("expected precompressed patterns %v, got %v", g.Precompressed, fs.Precompressed)
+	}
+
+	for p, uncompressed := range map[string]bool{"/app.js": true, "/app.css": false} {
+		if fs.Files[p].Compressed == uncompressed {
+			t.Errorf("expected %s to be stored uncompressed: %v", p, uncompressed)
+		}
+
+		if actual, err := fs.ReadFile(p); err != nil || string(actual) != data {
+			t.Errorf("unexpected contents of %s (%v)", p, err)
+		}
+	}
+}
+
+func TestWriteCompressionPolicy(t *testing.T) {
+	dir := t.TempDir()
+	data := strings.Repeat("compressible ", 1000)
+
+	for _, name := range []string{"app.js", "app.css", "index.html"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{
+		Compressed: true,
+		CompressionPolicy: map[string]Compression{
+			".css": Snappy,
+			".js":  NoCompression,
+		},
+	}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	tests := []struct {
+		path        string
+		compressed  bool
+		compression Compression
+	}{
+		{"/app.js", false, Gzip},
+		{"/app.css", true, Snappy},
+		{"/index.html", true, Gzip},
+	}
+
+	for _, test := range tests {
+		f := fs.Files[test.path]
+
+		if f.Compressed != test.compressed || f.Compression != test.compression {
+			t.Errorf("expected %s to be stored with %v %s, got %v %s", test.path, test.compressed, test.compression, f.Compressed, f.Compression)
+		}
+
+		if actual, err := fs.ReadFile(test.path); err != nil || string(actual) != data {
+			t.Errorf("unexpected contents of %s (%v)", test.path, err)
+		}
+	}
+}
+
+func TestWriteRootOptions(t *testing.T) {
+	dir := t.TempDir()
+	data := strings.Repeat("compressible ", 1000)
+
+	for _, name := range []string{"web/app.js", "web/app.css", "web/drafts/new.js", "docs/index.md", "docs/notes.txt"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Compressed: true}
+
+	roots := []Root{
+		{Dir: filepath.Join(dir, "web"), Exclude: []string{"/drafts"}, CompressionPolicy: map[string]Compression{".js": NoCompression}},
+		{Dir: filepath.Join(dir, "docs"), Mount: "/docs", Include: []string{"*.md"}},
+	}
+
+	for _, root := range roots {
+		if err := g.AddRoot(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	for p, compressed := range map[string]bool{"/app.js": false, "/app.css": true, "/docs/index.md": true} {
+		f, ok := fs.Files[p]
+
+		if !ok {
+			t.Errorf("expected %s to exist", p)
+		} else if f.Compressed != compressed {
+			t.Errorf("expected %s to be compressed: %v", p, compressed)
+		}
+	}
+
+	for _, p := range []string{"/drafts", "/drafts/new.js", "/docs/notes.txt"} {
+		if _, ok := fs.Files[p]; ok {
+			t.Errorf("expected %s to be filtered", p)
+		}
+	}
+}
+
+func TestWriteTestOnly(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", TestOnly: []string{"templates"}}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filename string
+		variable string
+		present  string
+		absent   string
+	}{
+		{filename, "Assets", "/static/app.js", "/templates/index.html"},
+		{testFilename(filename), "AssetsTest", "/templates/index.html", "/static/app.js"},
+	}
+
+	for _, test := range tests {
+		fss, err := ParseFile(test.filename)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fs, ok := fss[test.variable]
+
+		if !ok {
+			t.Fatalf("expected %s to define %s", test.filename, test.variable)
+		}
+
+		if _, ok := fs.Files[test.present]; !ok {
+			t.Errorf("expected %s to contain %s", test.variable, test.present)
+		}
+
+		if _, ok := fs.Files[test.absent]; ok {
+			t.Errorf("expected %s to not contain %s", test.variable, test.absent)
+		}
+
+		if _, ok := fs.Files["/"]; !ok {
+			t.Errorf("expected %s to contain the root directory", test.variable)
+		}
+	}
+
+	// Regenerating without test-only assets removes the stale test file
+	g = &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(testFilename(filename)); !os.IsNotExist(err) {
+		t.Errorf("expected stale %s to be removed, got %v", testFilename(filename), err)
+	}
+}
+
+func TestWriteMinCompression(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"small.txt": "small",
+		"large.txt": strings.Repeat("compressible ", 1000),
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		g            *Generator
+		uncompressed map[string]bool
+	}{
+		{&Generator{Compressed: true}, map[string]bool{"/small.txt": true, "/large.txt": false}},
+		{&Generator{Compressed: true, MinCompressionSavings: 99.9}, map[string]bool{"/small.txt": true, "/large.txt": true}},
+		{&Generator{Compressed: true, MinCompressionBytes: 20000}, map[string]bool{"/small.txt": true, "/large.txt": true}},
+	}
+
+	for _, test := range tests {
+		if err := test.g.AddRoot(Root{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+
+		fss, err := Parse(generate(t, test.g))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for p, uncompressed := range test.uncompressed {
+			f := fss["Assets"].Files[p]
+
+			if f.Compressed == uncompressed {
+				t.Errorf("expected %s to be stored uncompressed: %v", p, uncompressed)
+			}
+
+			if data, err := fss["Assets"].ReadFile(p); err != nil || string(data) != files[p[1:]] {
+				t.Errorf("unexpected contents of %s (%v)", p, err)
+			}
+		}
+	}
+}
+
+func TestWriteStringLiterals(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"text.txt":   "line \"one\"\n\tline two\n",
+		"crlf.txt":   "line one\r\nline two\r\n",
+		"quote.txt":  "a `quoted` word",
+		"binary.bin": "\x00\x01\xff",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if !bytes.Contains(src, []byte("`"+files["text.txt"]+"`")) {
+		t.Errorf("expected text to be written as a raw string literal")
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, expected := range files {
+		if data, err := fss["Assets"].ReadFile("/" + name); err != nil || string(data) != expected {
+			t.Errorf("expected %q, got %q (%v)", expected, data, err)
+		}
+	}
+}
+
+func TestWriteBase64(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", Compressed: true, Base64: true}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	for i, c := range src {
+		if c > 0x7f {
+			t.Fatalf("expected plain ASCII output, got %q at offset %d", c, i)
+		}
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "console.log(\"hello\");\n"
+
+	if data, err := fss["Assets"].ReadFile("/static/app.js"); err != nil || string(data) != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+}
+
+func TestWriteByteSlices(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", ByteSlices: true}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if !bytes.Contains(src, []byte("= []byte{")) {
+		t.Errorf("expected data to be written as byte slice literals")
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "console.log(\"hello\");\n"
+
+	if data, err := fss["Assets"].ReadFile("/static/app.js"); err != nil || string(data) != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+
+	g.Base64 = true
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected combining Base64 and ByteSlices to fail")
+	}
+}
+
+func TestWriteChunkSize(t *testing.T) {
+	dir := t.TempDir()
+	data := strings.Repeat("héllo wörld\n", 10)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{ChunkSize: 16}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if n := bytes.Count(src, []byte("` +\n")); n < len(data)/16 {
+		t.Errorf("expected data to be split into raw string chunks, got %d chunks", n)
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual, err := fss["Assets"].ReadFile("/a.txt"); err != nil || string(actual) != data {
+		t.Errorf("expected %q, got %q (%v)", data, actual, err)
+	}
+}
+
+func TestWriteDataShards(t *testing.T) {
+	g := &Generator{
+		StripPrefix:   "/testdata",
+		Header:        "Copyright (c) Example",
+		DataHeader:    "Third-party assets, see NOTICE",
+		DataShardSize: 1,
+	}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	// A data file left behind by a previous generation with more shards
+	stale := dataFilename(filename, 100)
+
+	if err := ioutil.WriteFile(stale, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	code, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(code), generatedMarker+"\n\n// Copyright (c) Example\n\npackage main") || strings.Contains(string(code), "const _") {
+		t.Errorf("expected code file with header and without data, got:\n%s", code)
+	}
+
+	shards, _ := filepath.Glob(filepath.Join(filepath.Dir(filename), "assets_data*.go"))
+
+	if len(shards) < 2 {
+		t.Fatalf("expected several data files, got %v", shards)
+	}
+
+	for _, shard := range shards {
+		if shard == stale {
+			t.Errorf("expected stale data file to be removed")
+			continue
+		}
+
+		data, err := ioutil.ReadFile(shard)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(string(data), generatedMarker+"\n\n// Third-party assets, see NOTICE\n\npackage main") {
+			t.Errorf("expected data file with data header, got:\n%s", data)
+		}
+	}
+}
+
+func TestWriteSharedData(t *testing.T) {
+	var counts []int
+
+	for _, shared := range []bool{false, true} {
+		g := &Generator{StripPrefix: "/testdata", SharedData: shared}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Group("Plugin").Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		src := generate(t, g)
+		counts = append(counts, bytes.Count(src, []byte("\nconst _")))
+
+		fss, err := Parse(src)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, name := range []string{"Assets", "Plugin"} {
+			if _, err := fss[name].ReadFile("/static/app.js"); err != nil {
+				t.Errorf("%s: %s", name, err)
+			}
+		}
+	}
+
+	if counts[1]*2 != counts[0] {
+		t.Errorf("expected shared data to be written once, got %v declarations", counts)
+	}
+
+	// Groups inherit SharedData, which applies to all file systems
+	g := &Generator{SharedData: true}
+
+	if !g.Group("Plugin").SharedData {
+		t.Errorf("expected group to inherit SharedData")
+	}
+
+	g.Group("Plugin").SharedData = false
+
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err == nil || !strings.Contains(err.Error(), "SharedData") {
+		t.Errorf("expected error for a group not matching SharedData, got %v", err)
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := t.TempDir()
+
+	stale := filepath.Join(dir, "Assetsstale.go")
+	user := filepath.Join(dir, "doc.go")
+
+	if err := ioutil.WriteFile(stale, []byte(generatedMarker+"\n\npackage static\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(user, []byte("package static\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{PackageName: "static", StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := ioutil.ReadFile(filepath.Join(dir, "index.go"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(index, []byte("const _")) || !bytes.Contains(index, []byte("package static")) {
+		t.Errorf("expected index without asset data, got:\n%s", index)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "Assets*.go"))
+
+	if len(files) < 2 {
+		t.Errorf("expected a file per asset, got %v", files)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale generated file to be removed")
+	}
+
+	if _, err := os.Stat(user); err != nil {
+		t.Errorf("expected other files to be kept: %s", err)
+	}
+}
+
+func TestPackFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+
+	g := &Generator{PackFile: "assets.pack", Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(src, []byte("Data:")) || !bytes.Contains(src, []byte("assets.NewPackFileSystem(")) {
+		t.Errorf("expected code loading the pack file, got:\n%s", src)
+	}
+
+	fss, err := ParseFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+	data, err := fss["Assets"].ReadFile("/templates/index.html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	// The asset data is read from the open pack rather than held in memory
+	if f := fss["Assets"].Files["/templates/index.html"]; f.Data != nil || f.pack == nil {
+		t.Errorf("expected asset data to be read from the pack")
+	}
+
+	// Packs are verified against their digest
+	pack, _ := ioutil.ReadFile(filepath.Join(dir, "assets.pack"))
+	pack[len(pack)-1] ^= 1
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "corrupt.pack"), pack, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fss["Assets"].LoadPack(filepath.Join(dir, "corrupt.pack")); err == nil || !strings.Contains(err.Error(), "corrupt") {
+		t.Errorf("expected error loading corrupt pack file, got %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err == nil {
+		t.Errorf("expected error writing pack file mode without WriteFile")
+	}
+
+	// Overwriting the pack with different data invalidates it
+	other := &Generator{PackFile: "assets.pack"}
+
+	if err := other.Add("generate.go"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := other.WriteFile(filepath.Join(dir, "other.go")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fss["Assets"].LoadPack(filepath.Join(dir, "assets.pack")); err == nil {
+		t.Errorf("expected error loading mismatching pack file")
+	}
+}
+
+func TestAppendPack(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+	pack := filepath.Join(dir, "assets.pack")
+	exe := filepath.Join(dir, "app")
+
+	g := &Generator{PackFile: "assets.pack", AppendPack: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := ParseFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if err := ioutil.WriteFile(exe, []byte("executable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.loadAppendedPack(exe); err == nil {
+		t.Errorf("expected error loading pack from executable without pack")
+	}
+
+	// Corrupt packs are not appended
+	corrupt := filepath.Join(dir, "corrupt.pack")
+	corruptData, _ := ioutil.ReadFile(pack)
+	corruptData[len(corruptData)-1] ^= 1
+
+	if err := ioutil.WriteFile(corrupt, corruptData, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AppendPack(exe, corrupt); err == nil {
+		t.Errorf("expected error appending corrupt pack")
+	}
+
+	// Appending twice appends the pack once
+	for i := 0; i < 2; i++ {
+		if err := AppendPack(exe, pack); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	packData, _ := ioutil.ReadFile(pack)
+	exeData, _ := ioutil.ReadFile(exe)
+
+	if expected := len("executable") + len(packData) + packTrailerSize; len(exeData) != expected {
+		t.Errorf("expected executable of %d bytes, got %d", expected, len(exeData))
+	}
+
+	if err := os.Remove(pack); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, f := range fs.Files {
+		f.Data = nil
+		f.pack = nil
+	}
+
+	if err := fs.loadAppendedPack(exe); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if data, err := fs.ReadFile("/templates/index.html"); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+}
+
+func TestSharedPack(t *testing.T) {
+	pack := filepath.Join(t.TempDir(), "assets.pack")
+
+	w := &packWriter{}
+	defer w.remove()
+
+	data := []byte("shared pack " + pack)
+	offset, err := w.add(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile(pack); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := func() []FileEntry {
+		return []FileEntry{
+			{Path: "/", FileMode: os.ModeDir | 0755},
+			{Path: "/a.txt", FileMode: 0644, Offset: offset, Length: int64(len(data))},
+		}
+	}
+
+	a := NewPackFileSystem(FormatVersion, entries(), pack, w.digest())
+
+	// Other file systems of the same pack (e.g. groups) share its data,
+	// without reading the pack again
+	if err := os.Remove(pack); err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewPackFileSystem(FormatVersion, entries(), pack, w.digest())
+
+	for _, fs := range []*FileSystem{a, b} {
+		if data, err := fs.ReadFile("/a.txt"); err != nil || string(data) != "shared pack "+pack {
+			t.Errorf("unexpected data %q (%v)", data, err)
+		}
+	}
+
+	if a.Files["/a.txt"].pack == nil || a.Files["/a.txt"].pack != b.Files["/a.txt"].pack {
+		t.Errorf("expected file systems to share the pack")
+	}
+}
+
+func TestSharedAppendedPack(t *testing.T) {
+	dir := t.TempDir()
+	pack := filepath.Join(dir, "assets.pack")
+	exe := filepath.Join(dir, "app")
+
+	w := &packWriter{}
+	defer w.remove()
+
+	data := []byte("shared appended pack " + pack)
+	offset, err := w.add(data)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.writeFile(pack); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(exe, []byte("executable"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AppendPack(exe, pack); err != nil {
+		t.Fatal(err)
+	}
+
+	var fss []*FileSystem
+
+	// The file systems of all groups read the pack appended to the
+	// executable, but share a single copy of its data
+	for i := 0; i < 2; i++ {
+		fs := NewFileSystemFromEntries([]FileEntry{
+			{Path: "/", FileMode: os.ModeDir | 0755},
+			{Path: "/a.txt", FileMode: 0644, Offset: offset, Length: int64(len(data))},
+		}, "")
+
+		fs.packDigest = w.digest()
+
+		if err := fs.loadAppendedPack(exe); err != nil {
+			t.Fatal(err)
+		}
+
+		fss = append(fss, fs)
+	}
+
+	if fss[0].Files["/a.txt"].pack == nil || fss[0].Files["/a.txt"].pack != fss[1].Files["/a.txt"].pack {
+		t.Errorf("expected file systems to share the appended pack")
+	}
+
+	// Once loaded, constructing file systems does not read the executable
+	fs := NewAppendedPackFileSystem(FormatVersion, []FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/a.txt", FileMode: 0644, Offset: offset, Length: int64(len(data))},
+	}, "missing.pack", w.digest())
+
+	if data, err := fs.ReadFile("/a.txt"); err != nil || string(data) != "shared appended pack "+pack {
+		t.Errorf("unexpected data %q (%v)", data, err)
+	}
+}
+
+func TestEmbedDir(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+	stale := filepath.Join(dir, "assets_data", strings.Repeat("0", 64))
+	user := filepath.Join(dir, "assets_data", "README")
+
+	if err := os.MkdirAll(filepath.Dir(stale), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{stale, user} {
+		if err := ioutil.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{EmbedDir: "assets_data", Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(src, []byte("//go:embed assets_data/")) || bytes.Contains(src, []byte("Data:")) {
+		t.Errorf("expected code embedding the data directory, got:\n%s", src)
+	}
+
+	fss, err := ParseFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if data, err := fss["Assets"].ReadFile("/templates/index.html"); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected stale data file to be removed")
+	}
+
+	if _, err := os.Stat(user); err != nil {
+		t.Errorf("expected other files to be kept: %s", err)
+	}
+}
+
+func TestNewEmbedFileSystem(t *testing.T) {
+	files := map[string][]byte{"data": []byte("embedded")}
+
+	fs := NewEmbedFileSystem(FormatVersion, []FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/a.txt", FileMode: 0644, EmbedName: "data"},
+	}, files, "assets_data")
+
+	// The embedded data is referenced rather than copied
+	if f := fs.Files["/a.txt"]; string(f.Data) != "embedded" || &f.Data[0] != &files["data"][0] {
+		t.Errorf("expected the asset to reference the embedded data, got %q", f.Data)
+	}
+}
+
+func TestSelfContained(t *testing.T) {
+	g := &Generator{SelfContained: true, Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if bytes.Contains(src, []byte("github.com/jessevdk/go-assets\"")) || !bytes.Contains(src, []byte("type assetsFileSystem struct")) {
+		t.Errorf("expected generated file with inlined runtime, got:\n%s", src)
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if data, err := fss["Assets"].ReadFile("/templates/index.html"); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+
+	g.Compression = Snappy
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected error for snappy compression")
+	}
+}
+
+func TestRuntimeImport(t *testing.T) {
+	g := &Generator{RuntimeImport: "example.com/vendor/assets", StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	if !bytes.Contains(src, []byte(`assets "example.com/vendor/assets"`)) || bytes.Contains(src, []byte(DefaultRuntimeImport)) {
+		t.Errorf("expected generated file importing the runtime import path, got:\n%s", src)
+	}
+
+	if _, err := Parse(src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	tmpl := template.Must(template.Must(DefaultTemplate.Clone()).New("file").Parse(`// Company header
+
+{{template "assets" .}}
+// Must read the asset at p, panicking on failure.
+func mustRead(p string) []byte {
+	data, err := Assets.ReadFile(p)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return data
+}
+`))
+
+	g := &Generator{Template: tmpl, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	for _, expected := range []string{"// Company header", "func mustRead(p string) []byte", "var Assets = assets.NewVersionedFileSystem("} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected generated file to contain %q, got:\n%s", expected, src)
+		}
+	}
+}
+
+func TestDevTag(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+
+	g := &Generator{DevTag: "dev", Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	release, _ := ioutil.ReadFile(filename)
+	dev, _ := ioutil.ReadFile(filepath.Join(dir, "assets_dev.go"))
+
+	if !bytes.Contains(release, []byte("\n//go:build !dev\n")) {
+		t.Errorf("expected generated file to be built without dev tag, got:\n%s", release)
+	}
+
+	if !bytes.Contains(dev, []byte("\n//go:build dev\n")) || !bytes.Contains(dev, []byte(`Source: "../`)) {
+		t.Errorf("expected disk-backed file built with dev tag, got:\n%s", dev)
+	}
+
+	src := filepath.Join(dir, "app.js")
+
+	if err := ioutil.WriteFile(src, []byte("before"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := NewDevFileSystem(FormatVersion, []FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/app.js", FileMode: 0644, Source: "app.js"},
+	}, dir)
+
+	if err := ioutil.WriteFile(src, []byte("after"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, err := fs.ReadFile("/app.js"); err != nil || string(data) != "after" {
+		t.Errorf("expected source contents %q, got %q (%v)", "after", data, err)
+	}
+}
+
+func TestBanner(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1000")
+
+	g := &Generator{Banner: "Generated by go-assets at {{.Time.Format \"2006-01-02\"}}", Header: "License header", StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+	expected := generatedMarker + "\n// Generated by go-assets at 1970-01-01\n\n// License header\n\npackage main\n"
+
+	if !bytes.HasPrefix(src, []byte(expected)) {
+		t.Errorf("expected generated file to start with %q, got:\n%s", expected, src)
+	}
+
+	g.Banner = "{{.Missing}}"
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected error for invalid banner")
+	}
+}
+
+func TestWriteFilePackageName(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		dir      string
+		files    map[string]string
+		expected string
+	}{
+		{"web-static", nil, "webstatic"},
+		{"cmd", map[string]string{"main.go": "package main\n", "main_test.go": "package main_test\n"}, "main"},
+		{"static", map[string]string{"doc.go": "// Package ui serves the user interface.\npackage ui\n"}, "ui"},
+	}
+
+	for _, test := range tests {
+		dir := filepath.Join(root, test.dir)
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		for name, data := range test.files {
+			if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		g := &Generator{StripPrefix: "/testdata"}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		// Writing twice infers the package from the other files, rather
+		// than from the previously generated file
+		for i := 0; i < 2; i++ {
+			if err := g.WriteFile(filepath.Join(dir, "assets.go")); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		src, _ := ioutil.ReadFile(filepath.Join(dir, "assets.go"))
+
+		if !bytes.Contains(src, []byte("\npackage "+test.expected+"\n")) {
+			t.Errorf("expected package %s in %s, got:\n%s", test.expected, test.dir, src)
+		}
+
+		if tmp, _ := filepath.Glob(filepath.Join(dir, ".*.tmp*")); len(tmp) != 0 {
+			t.Errorf("expected no temporary files, got %v", tmp)
+		}
+	}
+}
+
+func TestConfigData(t *testing.T) {
+	for data, byteSlices := range map[string]bool{"": false, "string": false, "bytes": true} {
+		g, err := (&Config{Output: "assets.go", Data: data}).Generator()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g.ByteSlices != byteSlices {
+			t.Errorf("expected ByteSlices %v for data %q", byteSlices, data)
+		}
+	}
+
+	if _, err := (&Config{Output: "assets.go", Data: "base32"}).Generator(); err == nil {
+		t.Errorf("expected error for unsupported data representation")
+	}
+}
+
+func TestConfigCompression(t *testing.T) {
+	snappy, none := Snappy, NoCompression
+
+	tests := []struct {
+		compression *Compression
+		compressed  bool
+		expected    Compression
+	}{
+		{nil, true, Gzip},
+		{&snappy, true, Snappy},
+		{&none, false, Gzip},
+	}
+
+	for _, test := range tests {
+		g, err := (&Config{Output: "assets.go", Compressed: true, Compression: test.compression}).Generator()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if g.Compressed != test.compressed || g.Compression != test.expected {
+			t.Errorf("%v: expected compressed %v using %v, got %v using %v", test.compression, test.compressed, test.expected, g.Compressed, g.Compression)
+		}
+	}
+
+	var c Config
+
+	if err := UnmarshalYAML([]byte("output: assets.go\ncompression: Snappy\n"), &c); err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Compression == nil || *c.Compression != Snappy {
+		t.Errorf("expected snappy compression, got %v", c.Compression)
+	}
+
+	if err := UnmarshalYAML([]byte("output: assets.go\ncompression: lzma\n"), &c); err == nil {
+		t.Errorf("expected unknown compression to fail")
+	}
+}
+
+func TestDigests(t *testing.T) {
+	g := &Generator{Digests: true, Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+	f := fs.Files["/templates/index.html"]
+
+	data, _ := ioutil.ReadFile("testdata/templates/index.html")
+	sum := sha256.Sum256(data)
+
+	if f.Digest != hex.EncodeToString(sum[:]) {
+		t.Fatalf("expected digest of the decompressed data, got %q", f.Digest)
+	}
+
+	if expected := "sha256-" + base64.StdEncoding.EncodeToString(sum[:]); f.Integrity() != expected {
+		t.Errorf("expected integrity %q, got %q", expected, f.Integrity())
+	}
+
+	r := httptest.NewRequest("GET", "/templates/index.html", nil)
+	r.Header.Set("If-None-Match", f.ETag())
+
+	w := httptest.NewRecorder()
+	fs.Handler().ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotModified || w.Header().Get("ETag") != f.ETag() {
+		t.Errorf("expected not modified response, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestChecksums(t *testing.T) {
+	g := &Generator{Checksums: true, Digests: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+	f := fs.Files["/templates/index.html"]
+
+	data, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if f.Checksum != crc32.ChecksumIEEE(data) {
+		t.Fatalf("expected checksum %08x, got %08x", crc32.ChecksumIEEE(data), f.Checksum)
+	}
+
+	if err := fs.Verify(); err != nil {
+		t.Fatal(err)
+	}
+
+	f.Data = append([]byte("x"), f.Data[1:]...)
+
+	if err := fs.Verify(); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+
+	f.Checksum = 0
+
+	if err := fs.Verify(); err == nil || !strings.Contains(err.Error(), "digest") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	g := &Generator{Fingerprint: []string{"*.js"}, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.AddKey("app", "/static/app.js")
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	data, _ := ioutil.ReadFile("testdata/static/app.js")
+	sum := sha256.Sum256(data)
+	expected := "/static/app." + hex.EncodeToString(sum[:4]) + ".js"
+
+	if url := fs.URL("/static/app.js"); url != expected {
+		t.Fatalf("expected %s, got %s", expected, url)
+	}
+
+	if url := fs.URL("/templates/index.html"); url != "/templates/index.html" {
+		t.Errorf("expected unfingerprinted path, got %s", url)
+	}
+
+	if _, ok := fs.Files["/static/app.js"]; ok {
+		t.Errorf("expected asset to be stored under its fingerprinted path only")
+	}
+
+	if fs.Keys["app"] != expected {
+		t.Errorf("expected key to refer to the fingerprinted path, got %s", fs.Keys["app"])
+	}
+
+	w := httptest.NewRecorder()
+	fs.Handler().ServeHTTP(w, httptest.NewRequest("GET", expected, nil))
+
+	if w.Code != http.StatusOK || w.Header().Get("Cache-Control") != fingerprintedCacheControl {
+		t.Errorf("expected fingerprinted asset to be cached, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	g := &Generator{
+		StripPrefix: "/testdata",
+		Compressed:  true,
+		OmitMTime:   true,
+		Tags:        map[string][]string{"partial.html": {PrivateTag}},
+	}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.WriteManifest(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest Manifest
+
+	if err := json.Unmarshal(buf.Bytes(), &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]ManifestEntry)
+
+	for _, e := range manifest.Files {
+		entries[e.Path] = e
+	}
+
+	if _, ok := entries["/templates/partial.html"]; ok {
+		t.Errorf("expected private asset to be left out of the manifest")
+	}
+
+	e, ok := entries["/templates/index.html"]
+
+	if !ok {
+		t.Fatalf("expected manifest entry for index.html, got:\n%s", buf.String())
+	}
+
+	data, _ := ioutil.ReadFile("testdata/templates/index.html")
+	sum := sha256.Sum256(data)
+
+	if e.Size != int64(len(data)) || e.SHA256 != hex.EncodeToString(sum[:]) || e.FileSystem != "Assets" || len(e.Mtime) != 0 {
+		t.Errorf("unexpected manifest entry %+v", e)
+	}
+
+	if !strings.HasPrefix(e.ContentType, "text/html") {
+		t.Errorf("expected html content type, got %q", e.ContentType)
+	}
+}
+
+func TestWriteURLs(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", Fingerprint: []string{"*.js"}, URLPrefix: "/assets"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.WriteURLs(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	var urls map[string]string
+
+	if err := json.Unmarshal(buf.Bytes(), &urls); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile("testdata/static/app.js")
+	sum := sha256.Sum256(data)
+
+	expected := map[string]string{
+		"/static/app.js":        "/assets/static/app." + hex.EncodeToString(sum[:4]) + ".js",
+		"/templates/index.html": "/assets/templates/index.html",
+	}
+
+	for p, url := range expected {
+		if urls[p] != url {
+			t.Errorf("expected %s to be served at %s, got %s", p, url, urls[p])
+		}
+	}
+
+	buf.Reset()
+
+	if err := g.WriteURLsTypeScript(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := buf.String()
+
+	if !strings.HasPrefix(ts, generatedMarker) || !strings.Contains(ts, `  "/templates/index.html": "/assets/templates/index.html",`) || !strings.Contains(ts, "export type AssetPath = keyof typeof assets;") {
+		t.Errorf("unexpected TypeScript module:\n%s", ts)
+	}
+}
+
+func TestPathConstants(t *testing.T) {
+	g := &Generator{PathConstants: true, Fingerprint: []string{"*.js"}, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Group("Static").Add("testdata/static"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	data, _ := ioutil.ReadFile("testdata/static/app.js")
+	sum := sha256.Sum256(data)
+
+	for _, expected := range []string{
+		`AssetTemplatesIndexHTML   = "/templates/index.html"`,
+		`StaticAssetStaticAppJS     = "/static/app.` + hex.EncodeToString(sum[:4]) + `.js"`,
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected generated file to contain %s, got:\n%s", expected, src)
+		}
+	}
+
+	if _, err := Parse(src); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	g := &Generator{Accessors: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Group("Static").Add("testdata/static"); err != nil {
+		t.Fatal(err)
+	}
+
+	src := generate(t, g)
+
+	for _, expected := range []string{
+		"func TemplatesIndexHTML() []byte {\n\treturn Assets.MustReadFile(\"/templates/index.html\")\n}",
+		"func StaticStaticAppJS() []byte {\n\treturn Static.MustReadFile(\"/static/app.js\")\n}",
+	} {
+		if !bytes.Contains(src, []byte(expected)) {
+			t.Errorf("expected generated file to contain %s, got:\n%s", expected, src)
+		}
+	}
+
+	fss, err := Parse(src)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/static/app.js")
+
+	if data := fss["Static"].MustReadFile("/static/app.js"); !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
+
+func TestEmbeddedTest(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	os.MkdirAll(src, 0755)
+	ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "skip.txt"), []byte("skip"), 0644)
+
+	g := &Generator{Exclude: []string{"skip.txt"}, EmbeddedTest: true}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.WriteEmbeddedTest(&buf, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	info, _ := os.Lstat("testdata/templates/index.html")
+
+	if expected := fmt.Sprintf(`{Path: "/testdata/templates/index.html", Source: "testdata/templates/index.html", FileMode: %#v}`, info.Mode()); !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected embedded test to contain %s, got:\n%s", expected, buf.String())
+	}
+
+	if expected := `{Path: "/testdata/templates", Source: "testdata/templates"}`; !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected embedded test to contain %s, got:\n%s", expected, buf.String())
+	}
+
+	// The mode of overridden files is not verified
+	g = &Generator{ModeOverrides: []ModeOverride{{Pattern: "*.html", Mode: 0600}}}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+
+	if err := g.WriteEmbeddedTest(&buf, "."); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := `{Path: "/testdata/templates/index.html", Source: "testdata/templates/index.html"}`; !strings.Contains(buf.String(), expected) {
+		t.Errorf("expected embedded test to contain %s, got:\n%s", expected, buf.String())
+	}
+
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/a.txt", FileMode: 0644, Data: "a"},
+	}, "")
+
+	sources := []SourceEntry{
+		{Path: "/", Source: "src", Ignored: []string{"skip.txt"}},
+		{Path: "/a.txt", Source: "src/a.txt", FileMode: 0644},
+	}
+
+	if err := VerifySources(fs, dir, sources); err != nil {
+		t.Fatal(err)
+	}
+
+	// Permissions depending on the umask are not verified
+	os.Chmod(src, 0775)
+	os.Chmod(filepath.Join(src, "a.txt"), 0664)
+
+	if err := VerifySources(fs, dir, sources); err != nil {
+		t.Errorf("expected changed permissions to be ignored, got %s", err)
+	}
+
+	os.Chmod(filepath.Join(src, "a.txt"), 0755)
+
+	if err := VerifySources(fs, dir, sources); err == nil || !strings.Contains(err.Error(), "mode of src/a.txt changed") {
+		t.Errorf("expected changed executable bit, got %v", err)
+	}
+
+	os.Chmod(filepath.Join(src, "a.txt"), 0644)
+
+	ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("changed"), 0644)
+
+	if err := VerifySources(fs, dir, sources); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("expected stale asset, got %v", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(src, "b.txt"), []byte("b"), 0644)
+
+	if err := VerifySources(fs, dir, sources); err == nil || !strings.Contains(err.Error(), "src/b.txt is not embedded") {
+		t.Errorf("expected new source file, got %v", err)
+	}
+}
+
+func TestSkipUnchanged(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "assets.go")
+
+	write := func(g *Generator) []byte {
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.WriteFile(filename); err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := ioutil.ReadFile(filename)
+		return data
+	}
+
+	src := write(&Generator{StripPrefix: "/testdata", SkipUnchanged: true})
+
+	if !bytes.HasPrefix(src, []byte(generatedMarker+"\n"+inputsPrefix)) {
+		t.Fatalf("expected generated file to record its inputs, got:\n%s", src)
+	}
+
+	// Mark the file to detect whether it is rewritten
+	marked := append(src, "// marked\n"...)
+	ioutil.WriteFile(filename, marked, 0644)
+
+	if data := write(&Generator{StripPrefix: "/testdata", SkipUnchanged: true}); !bytes.Equal(data, marked) {
+		t.Errorf("expected unchanged file not to be rewritten")
+	}
+
+	if data := write(&Generator{StripPrefix: "/testdata", SkipUnchanged: true, Compressed: true}); bytes.Equal(data, marked) {
+		t.Errorf("expected file to be rewritten after changing options")
+	}
+}
+
+func TestSkipUnchangedCompanionFiles(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+	pack := filepath.Join(dir, "assets.pack")
+
+	write := func() {
+		g := &Generator{StripPrefix: "/testdata", SkipUnchanged: true, PackFile: "assets.pack", EmbeddedTest: true}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.WriteFile(filename); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write()
+
+	expected, err := ioutil.ReadFile(pack)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{pack, embeddedTestFilename(filename)} {
+		if err := os.Remove(name); err != nil {
+			t.Fatal(err)
+		}
+
+		write()
+
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("expected %s to be regenerated: %s", filepath.Base(name), err)
+		}
+	}
+
+	if data, err := ioutil.ReadFile(pack); err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("expected identical pack after regenerating (%v)", err)
+	}
+}
+
+func TestEncryption(t *testing.T) {
+	key := bytes.Repeat([]byte{0x2a}, 32)
+	g := &Generator{EncryptionKey: key, Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	out := generate(t, g)
+
+	if bytes.Contains(out, []byte("DOCTYPE")) {
+		t.Fatalf("expected asset data to be encrypted")
+	}
+
+	fss, err := Parse(out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if !fs.Files["/templates/index.html"].Encrypted {
+		t.Fatalf("expected asset to be marked encrypted")
+	}
+
+	t.Setenv(KeyEnv, "")
+
+	if _, err := fs.ReadFile("/templates/index.html"); err != ErrNoDecryptionKey {
+		t.Fatalf("expected %v, got %v", ErrNoDecryptionKey, err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	t.Setenv(KeyEnv, hex.EncodeToString(key))
+
+	data, err := fs.ReadFile("/templates/index.html")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	fs.DecryptionKey = func() ([]byte, error) {
+		return bytes.Repeat([]byte{0x2b}, 32), nil
+	}
+
+	fs.decrypted = sync.Map{}
+
+	if _, err := fs.ReadFile("/templates/index.html"); err == nil {
+		t.Errorf("expected decrypting with the wrong key to fail")
+	}
+
+	if err := (&Generator{EncryptionKey: key, SelfContained: true}).Write(ioutil.Discard); err == nil {
+		t.Errorf("expected SelfContained and EncryptionKey to be rejected")
+	}
+}
+
+func TestSignature(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{SigningKey: private, Compressed: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.AddKey("index", "/templates/index.html")
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if err := fs.VerifySignature(public); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _, _ := ed25519.GenerateKey(nil)
+
+	if err := fs.VerifySignature(other); err != ErrInvalidSignature {
+		t.Errorf("expected %v for another key, got %v", ErrInvalidSignature, err)
+	}
+
+	fs.Keys["index"] = "/static/app.js"
+
+	if err := fs.VerifySignature(public); err != ErrInvalidSignature {
+		t.Errorf("expected %v after changing a key, got %v", ErrInvalidSignature, err)
+	}
+
+	fs.Keys["index"] = "/templates/index.html"
+
+	f := fs.Files["/templates/index.html"]
+	f.Data = append([]byte{f.Data[0] ^ 1}, f.Data[1:]...)
+
+	if err := fs.VerifySignature(public); err != ErrInvalidSignature {
+		t.Errorf("expected %v after changing data, got %v", ErrInvalidSignature, err)
+	}
+
+	fss, err = Parse(generate(t, &Generator{StripPrefix: "/testdata"}))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fss["Assets"].VerifySignature(public); err != ErrNotSigned {
+		t.Errorf("expected %v, got %v", ErrNotSigned, err)
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	var transformed []string
+
+	g := &Generator{
+		StripPrefix: "/testdata",
+		Transforms: []Transform{
+			{
+				Name:     "upper",
+				Patterns: []string{"index.*"},
+				Func: func(p string, data []byte) ([]byte, error) {
+					transformed = append(transformed, p)
+					return bytes.ToUpper(data), nil
+				},
+			},
+			{
+				Name: "suffix",
+				Func: func(p string, data []byte) ([]byte, error) {
+					return append(append([]byte{}, data...), "/* end */"...), nil
+				},
+			},
+		},
+	}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+	expected = append(bytes.ToUpper(expected), "/* end */"...)
+
+	if data, _ := fs.ReadFile("/templates/index.html"); !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	if len(transformed) != 1 || transformed[0] != "/templates/index.html" {
+		t.Errorf("expected only /templates/index.html to be transformed, got %v", transformed)
+	}
+
+	g.Transforms = append(g.Transforms, Transform{
+		Name: "fail",
+		Func: func(p string, data []byte) ([]byte, error) {
+			return nil, fmt.Errorf("failed")
+		},
+	})
+
+	if err := g.Write(ioutil.Discard); err == nil || !strings.Contains(err.Error(), "transform fail: failed") {
+		t.Errorf("expected transform error, got %v", err)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	dir := t.TempDir()
+
+	ioutil.WriteFile(filepath.Join(dir, "a.css"), []byte("a { color: red }"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "b.css"), []byte("b { color: blue }\n"), 0644)
+
+	g := &Generator{}
+
+	if err := g.Bundle("/bundle.css", filepath.Join(dir, "a.css"), filepath.Join(dir, "b.css")); err != nil {
+		t.Fatal(err)
+	}
+
+	g.BundleBanner = "/* bundle */\n"
+	g.BundleSeparator = "\n/* next */\n"
+
+	if err := g.Bundle("/banner.css", filepath.Join(dir, "a.css"), filepath.Join(dir, "b.css")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Bundle("/missing.css", filepath.Join(dir, "c.css")); err == nil {
+		t.Errorf("expected bundling a missing input to fail")
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"/bundle.css": "a { color: red }\nb { color: blue }\n",
+		"/banner.css": "/* bundle */\na { color: red }\n/* next */\nb { color: blue }\n",
+	}
+
+	for p, e := range expected {
+		if data, err := fs.ReadFile(p); string(data) != e {
+			t.Errorf("expected %q for %s, got %q (%v)", e, p, data, err)
+		}
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	t.Setenv("SOURCE_DATE_EPOCH", "1000")
+
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fss["Assets"].BuildInfo(); !os.IsNotExist(err) {
+		t.Errorf("expected missing build info, got %v", err)
+	}
+
+	if err := g.AddBuildInfo("1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err = Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := fss["Assets"].BuildInfo()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Version != "1.2.3" || !info.Time.Equal(time.Unix(1000, 0)) {
+		t.Errorf("unexpected build info %+v", info)
+	}
+}
+
+func TestWriteFormatted(t *testing.T) {
+	generators := []*Generator{
+		{StripPrefix: "/testdata"},
+		{StripPrefix: "/testdata", ByteSlices: true},
+		{StripPrefix: "/testdata", Base64: true, ChunkSize: 8},
+		{StripPrefix: "/testdata", Compressed: true, PathConstants: true, Accessors: true},
+	}
+
+	for i, g := range generators {
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := g.Group("Other").Add("testdata/static"); err != nil {
+			t.Fatal(err)
+		}
+
+		out := generate(t, g)
+
+		if bytes.Contains(out, []byte(dataMarker)) {
+			t.Errorf("%d: expected data markers to be replaced", i)
+		}
+
+		formatted, err := format.Source(out)
+
+		if err != nil {
+			t.Fatalf("%d: %s", i, err)
+		}
+
+		if !bytes.Equal(formatted, out) {
+			t.Errorf("%d: expected generated file to be formatted:\n%s", i, out)
+		}
+
+		if _, err := Parse(out); err != nil {
+			t.Errorf("%d: %s", i, err)
+		}
+	}
+}
+
+func TestParallel(t *testing.T) {
+	g := &Generator{Concurrency: 4}
+
+	var mu sync.Mutex
+	called := make(map[int]bool)
+
+	err := g.parallel(100, func(i int) error {
+		mu.Lock()
+		called[i] = true
+		mu.Unlock()
+
+		if i%10 == 7 {
+			return fmt.Errorf("failed %d", i)
+		}
+
+		return nil
+	})
+
+	if err == nil || err.Error() != "failed 7" {
+		t.Errorf("expected the error of the lowest index, got %v", err)
+	}
+
+	if len(called) != 100 {
+		t.Errorf("expected 100 calls, got %d", len(called))
+	}
+
+	if err := g.parallel(0, nil); err != nil {
+		t.Error(err)
+	}
+
+	// The number of concurrent calls is bounded by Concurrency
+	var running, max int
+
+	g.parallel(50, func(i int) error {
+		mu.Lock()
+		running++
+
+		if running > max {
+			max = running
+		}
+
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		return nil
+	})
+
+	if max > g.Concurrency || max < 2 {
+		t.Errorf("expected at most %d concurrent calls, got %d", g.Concurrency, max)
+	}
+
+	// The generated output does not depend on the number of workers
+	var outputs [][]byte
+
+	for _, concurrency := range []int{1, 8} {
+		g := &Generator{Concurrency: concurrency, Compressed: true, StripPrefix: "/testdata"}
+
+		if err := g.Add("testdata"); err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, generate(t, g))
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("expected identical output regardless of Concurrency")
+	}
+}
+
+func TestEachStored(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 0; i < 50; i++ {
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.txt", i)), []byte(strconv.Itoa(i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Concurrency: 3}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	paths := g.filePaths()
+
+	// The results are passed in order, regardless of when they were read
+	next := 0
+
+	err := g.eachStored(paths, func(i int, sf storedFile) error {
+		n, _ := strconv.Atoi(strings.Trim(paths[next], "/.tx"))
+
+		if i != next || string(sf.data) != strconv.Itoa(n) {
+			t.Errorf("expected the data of %s at %d, got %q at %d", paths[next], next, sf.data, i)
+		}
+
+		next++
+		return nil
+	})
+
+	if err != nil || next != len(paths) {
+		t.Errorf("expected %d results, got %d (%v)", len(paths), next, err)
+	}
+
+	// Errors stop reading without calling fn with the remaining results
+	next = 0
+
+	err = g.eachStored(paths, func(i int, sf storedFile) error {
+		next++
+		return fmt.Errorf("failed %d", i)
+	})
+
+	if err == nil || err.Error() != "failed 0" || next != 1 {
+		t.Errorf("expected the first error to stop reading, got %v after %d results", err, next)
+	}
+}
+
+func TestQuoteBytes(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+
+	inputs := []string{
+		"",
+		"plain \"text\" with \\ backslashes",
+		"\xff\xfe invalid \xc3 utf-8 \xe2\x82",
+		"\ufeffbom \u00a0 nbsp \ufffd replacement",
+		"\x00\a\b\f\n\r\t\v\x7f",
+		"héllo wörld ✓",
+	}
+
+	for i := 0; i < 100; i++ {
+		data := make([]byte, rnd.Intn(256))
+		rnd.Read(data)
+
+		inputs = append(inputs, string(data))
+	}
+
+	for _, input := range inputs {
+		literal := quoteBytes([]byte(input))
+
+		if s, err := strconv.Unquote(literal); err != nil || s != input {
+			t.Errorf("expected %q to round-trip, got %q (%v)", input, literal, err)
+		}
+
+		if strings.Contains(literal, "\ufeff") {
+			t.Errorf("expected byte order mark to be escaped in %s", literal)
+		}
+	}
+}
+
+func TestWriteBinary(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	blobs := make(map[string][]byte)
+
+	g := &Generator{ChunkSize: 100}
+
+	for i := 0; i < 20; i++ {
+		data := make([]byte, rnd.Intn(1000))
+		rnd.Read(data)
+
+		p := fmt.Sprintf("/blob%d.bin", i)
+		blobs[p] = data
+
+		g.addVirtual(p, 0644, time.Unix(1000, 0), data)
+	}
+
+	out := generate(t, g)
+
+	if _, err := format.Source(out); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, data := range blobs {
+		if actual, err := fss["Assets"].ReadFile(p); err != nil || !bytes.Equal(actual, data) {
+			t.Errorf("expected %s to round-trip (%v)", p, err)
+		}
+	}
+}
+
+func TestWriteTemplateWithoutData(t *testing.T) {
+	tmpl := template.Must(template.New("file").Parse("{{.Banner}}package {{.Package}}\n"))
+	g := &Generator{StripPrefix: "/testdata", Template: tmpl}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(ioutil.Discard); err == nil || !strings.Contains(err.Error(), "does not write the asset data of Assets") {
+		t.Errorf("expected template without data to fail, got %v", err)
+	}
+
+	// The data is not needed when it is written to a pack file
+	g.PackFile = "assets.pack"
+
+	if err := g.WriteFile(filepath.Join(t.TempDir(), "assets.go")); err != nil {
+		t.Errorf("expected template without inline data to succeed, got %s", err)
+	}
+}
+
+func TestWriteFormatError(t *testing.T) {
+	tmpl := template.Must(template.New("file").Parse("{{.Banner}}package {{.Package}}\n\nfunc init() { {\n}\n{{range .FileSystems}}{{.Data}}{{end}}"))
+	g := &Generator{StripPrefix: "/testdata", Template: tmpl}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := g.Write(ioutil.Discard)
+
+	if err == nil {
+		t.Fatalf("expected formatting to fail")
+	}
+
+	if !strings.Contains(err.Error(), "> ") || !strings.Contains(err.Error(), "func init() { {") {
+		t.Errorf("expected error to show the failing line, got %s", err)
+	}
+
+	m := regexp.MustCompile(`written to (\S+)`).FindStringSubmatch(err.Error())
+
+	if m == nil {
+		t.Fatalf("expected error to name the unformatted file, got %s", err)
+	}
+
+	defer os.Remove(m[1])
+
+	if data, err := ioutil.ReadFile(m[1]); err != nil || !bytes.Contains(data, []byte("func init() { {")) {
+		t.Errorf("expected unformatted code in %s (%v)", m[1], err)
+	}
+
+	g.SkipFormat = true
+
+	if err := g.Write(ioutil.Discard); err != nil {
+		t.Errorf("expected unformatted code to be written, got %s", err)
+	}
+}
+
+func TestContinueOnError(t *testing.T) {
+	g := &Generator{
+		StripPrefix: "/testdata",
+		Transforms: []Transform{
+			{
+				Name:     "fail",
+				Patterns: []string{"app.css"},
+				Func: func(p string, data []byte) ([]byte, error) {
+					return nil, fmt.Errorf("unreadable")
+				},
+			},
+		},
+	}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.AddKey("style", "/static/css/app.css")
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Fatalf("expected failing file to fail the generation")
+	}
+
+	g.ContinueOnError = true
+
+	var buf bytes.Buffer
+	err := g.Write(&buf)
+
+	var partial *PartialError
+
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected partial error, got %v", err)
+	}
+
+	if len(partial.Failures) != 1 || partial.Failures[0].Path != "/static/css/app.css" {
+		t.Errorf("expected app.css to be reported, got %v", partial.Failures)
+	}
+
+	if !strings.Contains(err.Error(), "1 file could not be embedded") || !strings.Contains(err.Error(), "unreadable") {
+		t.Errorf("unexpected report %s", err)
+	}
+
+	if strings.Contains(buf.String(), "app.css") {
+		t.Errorf("expected app.css to be skipped")
+	}
+
+	if !strings.Contains(buf.String(), "/static/app.js") {
+		t.Errorf("expected readable files to be written")
+	}
+}
+
+func TestContinueOnErrorRoot(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"index.html", "private/secret.txt"} {
+		p := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A dangling symlink cannot be read, regardless of permissions
+	if err := os.Symlink(filepath.Join(dir, "missing.css"), filepath.Join(dir, "app.css")); err != nil {
+		t.Skip(err)
+	}
+
+	unreadableDir := os.Geteuid() != 0
+
+	if unreadableDir {
+		if err := os.Chmod(filepath.Join(dir, "private"), 0); err != nil {
+			t.Fatal(err)
+		}
+
+		defer os.Chmod(filepath.Join(dir, "private"), 0755)
+	}
+
+	g := &Generator{}
+
+	if err := g.AddRoot(Root{Dir: dir}); err == nil && unreadableDir {
+		t.Errorf("expected unreadable directory to fail adding the root")
+	}
+
+	g = &Generator{ContinueOnError: true}
+
+	// Groups inherit ContinueOnError
+	if err := g.Group("Web").AddRoot(Root{Dir: dir, Mount: "/web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	err := g.Write(&buf)
+
+	var partial *PartialError
+
+	if !errors.As(err, &partial) {
+		t.Fatalf("expected partial error, got %v", err)
+	}
+
+	failed := make(map[string]bool)
+
+	for _, f := range partial.Failures {
+		failed[f.Path] = true
+	}
+
+	expected := []string{"/web/app.css"}
+
+	if unreadableDir {
+		expected = append(expected, "/web/private")
+	}
+
+	for _, p := range expected {
+		if !failed[p] {
+			t.Errorf("expected %s to be reported, got %v", p, partial.Failures)
+		}
+	}
+
+	if len(partial.Failures) != len(expected) {
+		t.Errorf("expected %d failures, got %v", len(expected), partial.Failures)
+	}
+
+	fss, perr := Parse(buf.Bytes())
+
+	if perr != nil {
+		t.Fatal(perr)
+	}
+
+	if _, err := fss["Web"].ReadFile("/web/index.html"); err != nil {
+		t.Errorf("expected readable files to be written: %s", err)
+	}
+
+	if _, ok := fss["Web"].Files["/web/app.css"]; ok {
+		t.Errorf("expected unreadable file to be skipped")
+	}
+}
+
+func TestAssetSizes(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.addVirtual("/testdata/static/copy.js", 0644, time.Now(), []byte("console.log(\"hello\");\n"))
+
+	sizes, err := g.AssetSizes()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 5 {
+		t.Fatalf("expected 5 sizes, got %v", sizes)
+	}
+
+	for i := 1; i < len(sizes); i++ {
+		if sizes[i].Size() > sizes[i-1].Size() {
+			t.Errorf("expected sizes in descending order, got %v", sizes)
+		}
+	}
+
+	byPath := make(map[string]AssetSize)
+
+	for _, s := range sizes {
+		byPath[s.Path] = s
+	}
+
+	if s := byPath["/static/app.js"]; s.DataSize != 22 || s.FileSystem != "Assets" {
+		t.Errorf("expected app.js to contribute its data, got %v", s)
+	}
+
+	if s := byPath["/static/copy.js"]; s.DataSize != 0 || s.EntrySize == 0 {
+		t.Errorf("expected copy.js to share the data of app.js, got %v", s)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(buf.Bytes())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed := fss["Assets"].AssetSizes()
+
+	if len(parsed) != len(sizes) || parsed[0].Path != sizes[0].Path || parsed[0].Size() != sizes[0].Size() {
+		t.Errorf("expected parsed sizes %v to match %v", parsed, sizes)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{".env", "index.html", "certs/server.pem"} {
+		p := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var warnings bytes.Buffer
+
+	g := &Generator{SecretOutput: &warnings}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(warnings.String(), ".env looks like a secret") || !strings.Contains(warnings.String(), "server.pem") || strings.Contains(warnings.String(), "index.html") {
+		t.Errorf("unexpected warnings %q", warnings.String())
+	}
+
+	g = &Generator{SecretPolicy: SecretError, AllowSecrets: []string{"*.pem"}}
+
+	if err := g.AddRoot(Root{Dir: dir}); err == nil || !strings.Contains(err.Error(), ".env") {
+		t.Errorf("expected .env to be rejected, got %v", err)
+	}
+
+	g = &Generator{SecretPolicy: SecretError, AllowSecrets: []string{"*.pem"}, Exclude: []string{".env"}}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Errorf("expected allowed secrets to be added, got %s", err)
+	}
+
+	warnings.Reset()
+	g = &Generator{SecretPolicy: SecretIgnore, SecretOutput: &warnings}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil || warnings.Len() != 0 {
+		t.Errorf("expected secrets to be added silently, got %v %q", err, warnings.String())
+	}
+
+	// Groups inherit the secret options
+	g = &Generator{SecretPolicy: SecretError, AllowSecrets: []string{"*.pem"}}
+
+	if err := g.Group("Static").AddRoot(Root{Dir: dir}); err == nil || !strings.Contains(err.Error(), ".env") {
+		t.Errorf("expected .env to be rejected in group, got %v", err)
+	}
+
+	warnings.Reset()
+	g = &Generator{SecretPatterns: []string{"*.html"}, SecretOutput: &warnings}
+
+	if err := g.Group("Static").AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(warnings.String(), "index.html looks like a secret") || strings.Contains(warnings.String(), ".env") {
+		t.Errorf("expected group to use the secret patterns of its parent, got %q", warnings.String())
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", MaxDepth: 2}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fs.Files["/static/app.js"]; !ok {
+		t.Errorf("expected files within the maximum depth to be added")
+	}
+
+	if _, ok := fs.Files["/static/css"]; !ok {
+		t.Errorf("expected truncated directory to be added")
+	}
+
+	if _, ok := fs.Files["/static/css/app.css"]; ok {
+		t.Errorf("expected files below the maximum depth to be skipped")
+	}
+
+	if truncated := g.Truncated(); len(truncated) != 1 || truncated[0] != "/static/css" {
+		t.Errorf("expected /static/css to be truncated, got %v", truncated)
+	}
+
+	var stats bytes.Buffer
+
+	g.StatsOutput = &stats
+
+	if err := g.Write(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(stats.String(), "truncated: /static/css") {
+		t.Errorf("expected truncated directory in stats, got %s", stats.String())
+	}
+
+	g = &Generator{MaxDepth: 1}
+
+	if err := g.AddRoot(Root{Dir: "testdata", Mount: "/web"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if truncated := g.Truncated(); len(truncated) != 2 || truncated[0] != "/web/static" || truncated[1] != "/web/templates" {
+		t.Errorf("expected top-level directories to be truncated, got %v", truncated)
+	}
+
+	if _, ok := g.fsFilesMap["/web/static/app.js"]; ok {
+		t.Errorf("expected files below the maximum depth to be skipped")
+	}
+
+	// Groups inherit MaxDepth, their truncated directories are reported
+	// with those of their parent
+	g = &Generator{StripPrefix: "/testdata", MaxDepth: 1}
+
+	if err := g.Group("Static").Add("testdata/static"); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs, err := g.Group("Static").FileSystem(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := fs.Files["/static/app.js"]; !ok {
+		t.Errorf("expected files within the maximum depth to be added to group")
+	} else if _, ok := fs.Files["/static/css/app.css"]; ok {
+		t.Errorf("expected files below the maximum depth to be skipped in group")
+	}
+
+	if truncated := g.Truncated(); len(truncated) != 1 || truncated[0] != "/static/css" {
+		t.Errorf("expected /static/css of the group to be truncated, got %v", truncated)
+	}
+}
+
+func TestAddURL(t *testing.T) {
+	data := []byte("body { color: red; }\n")
+	sum := sha256.Sum256(data)
+	modified := time.Unix(1500000000, 0).UTC()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/modified.css":
+			w.Header().Set("Last-Modified", modified.Format(http.TimeFormat))
+		case "/slow.css":
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		case "/missing.css":
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write(data)
+	}))
+
+	defer srv.Close()
+
+	g := &Generator{
+		FetchTimeout: 50 * time.Millisecond,
+		URLChecksums: map[string]string{
+			srv.URL + "/modified.css": strings.ToUpper(hex.EncodeToString(sum[:])),
+			srv.URL + "/bad.css":      hex.EncodeToString(make([]byte, sha256.Size)),
+		},
+	}
+
+	if err := g.AddURL(srv.URL+"/modified.css", "/css/modified.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddURL(srv.URL+"/plain.css", "/css/plain.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.AddURL(srv.URL+"/bad.css", "/css/bad.css"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected checksum mismatch, got %v", err)
+	}
+
+	if err := g.AddURL(srv.URL+"/missing.css", "/css/missing.css"); err == nil {
+		t.Errorf("expected error fetching a missing asset")
+	}
+
+	start := time.Now()
+
+	if err := g.AddURL(srv.URL+"/slow.css", "/css/slow.css"); err == nil {
+		t.Errorf("expected error fetching beyond FetchTimeout")
+	} else if time.Since(start) > 5*time.Second {
+		t.Errorf("expected fetching to stop after FetchTimeout, took %s", time.Since(start))
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"/css/modified.css", "/css/plain.css"} {
+		if got, err := fs.ReadFile(p); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: expected %q, got %q (%v)", p, data, got, err)
+		}
+	}
+
+	for _, p := range []string{"/css/bad.css", "/css/missing.css", "/css/slow.css"} {
+		if _, ok := fs.Files[p]; ok {
+			t.Errorf("%s: expected failed fetch to not be added", p)
+		}
+	}
+
+	if mt := fs.Files["/css/modified.css"].Mtime; !mt.Equal(modified) {
+		t.Errorf("expected modification time from Last-Modified %s, got %s", modified, mt)
+	}
+
+	// Without Last-Modified, the modification time does not depend on the
+	// time of generation
+	if mt := fs.Files["/css/plain.css"].Mtime; !mt.IsZero() {
+		t.Errorf("expected zero modification time without Last-Modified, got %s", mt)
+	}
+
+	epoch := &Generator{MaxModTime: time.Unix(1000, 0)}
+
+	if err := epoch.AddURL(srv.URL+"/plain.css", "/plain.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if efs, err := epoch.FileSystem(); err != nil {
+		t.Fatal(err)
+	} else if mt := efs.Files["/plain.css"].Mtime; !mt.Equal(epoch.MaxModTime) {
+		t.Errorf("expected MaxModTime without Last-Modified, got %s", mt)
+	}
+
+	// StripPrefix is not stripped from mount paths
+	stripped := &Generator{StripPrefix: "/vendor"}
+
+	if err := stripped.AddURL(srv.URL+"/plain.css", "/vendor/plain.css"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sfs, err := stripped.FileSystem(); err != nil {
+		t.Fatal(err)
+	} else if _, ok := sfs.Files["/vendor/plain.css"]; !ok {
+		t.Errorf("expected asset at the mount path, got %v", sfs.Dirs)
+	}
+}
+
+func TestKeys(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.AddKey("page", "templates/index.html")
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fss["Assets"].Lookup("page")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if data, _ := ioutil.ReadAll(f); !bytes.Equal(data, expected) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+
+	if _, err := fss["Assets"].Lookup("unknown"); !os.IsNotExist(err) {
+		t.Errorf("expected unknown key to not exist, got %v", err)
+	}
+
+	g.AddKey("missing", "/templates/missing.html")
+
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err == nil || !strings.Contains(err.Error(), `"missing"`) {
+		t.Errorf("expected error for a key of a non-existing asset, got %v", err)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	g := &Generator{
+		StripPrefix:     "/testdata",
+		Exclude:         []string{"*.css"},
+		MaxDepth:        2,
+		SharedData:      true,
+		SecretPolicy:    SecretError,
+		AllowSecrets:    []string{"*.pem"},
+		ContinueOnError: true,
+		Compressed:      true,
+		DedupHash:       sha256.New,
+	}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	static := g.Group("Static")
+
+	if g.Group("Static") != static {
+		t.Errorf("expected existing group to be returned")
+	}
+
+	// All options are shared, except for the variable name
+	gv, sv := reflect.ValueOf(g).Elem(), reflect.ValueOf(static).Elem()
+
+	for i := 0; i < gv.NumField(); i++ {
+		field := gv.Type().Field(i)
+
+		if !field.IsExported() || field.Name == "VariableName" {
+			continue
+		}
+
+		a, b := gv.Field(i), sv.Field(i)
+
+		if field.Type.Kind() == reflect.Func {
+			if a.Pointer() != b.Pointer() {
+				t.Errorf("expected group to share %s", field.Name)
+			}
+		} else if !reflect.DeepEqual(a.Interface(), b.Interface()) {
+			t.Errorf("expected group to share %s, got %v", field.Name, b.Interface())
+		}
+	}
+
+	if err := static.Add("testdata/static"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fss["Assets"].Files["/templates/index.html"]; !ok {
+		t.Errorf("expected templates in Assets")
+	}
+
+	if _, ok := fss["Assets"].Files["/static/app.js"]; ok {
+		t.Errorf("expected group assets to not be part of Assets")
+	}
+
+	if _, ok := fss["Static"].Files["/static/app.js"]; !ok {
+		t.Errorf("expected group assets in Static")
+	}
+
+	if _, ok := fss["Static"].Files["/static/css/app.css"]; ok {
+		t.Errorf("expected group to use the excludes of its parent")
+	}
+}
+
+func TestWriteIdenticalData(t *testing.T) {
+	dir := t.TempDir()
+
+	for name, data := range map[string]string{"a.txt": "same", "sub/b.txt": "same", "c.txt": "other"} {
+		p := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, dedup := range []func() hash.Hash{nil, sha256.New} {
+		g := &Generator{DedupHash: dedup}
+
+		if err := g.AddRoot(Root{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+
+		src := generate(t, g)
+
+		// One variable for the identical files and one for the other
+		if n := bytes.Count(src, []byte("\nconst _")); n != 2 {
+			t.Errorf("expected 2 data declarations, got %d:\n%s", n, src)
+		}
+
+		fss, err := Parse(src)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for p, expected := range map[string]string{"/a.txt": "same", "/sub/b.txt": "same", "/c.txt": "other"} {
+			if data, err := fss["Assets"].ReadFile(p); err != nil || string(data) != expected {
+				t.Errorf("%s: expected %q, got %q (%v)", p, expected, data, err)
+			}
+		}
+	}
+}
+
+func TestWritePaths(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", PathsPackageName: "webpaths"}
+
+	if err := g.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := g.WritePaths(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	src := buf.String()
+
+	for _, expected := range []string{
+		"package webpaths\n",
+		"type Path string\n",
+		`TemplatesIndexHTML   Path = "/templates/index.html"`,
+		`TemplatesPartialHTML Path = "/templates/partial.html"`,
+	} {
+		if !strings.Contains(src, expected) {
+			t.Errorf("expected paths file to contain %s, got:\n%s", expected, src)
+		}
+	}
+
+	// Constants of groups are prefixed with the group name
+	if err := g.Group("Static").Add("testdata/static"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf.Reset()
+
+	if err := g.WritePaths(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{`AssetsTemplatesIndexHTML`, `StaticStaticAppJS`, `StaticStaticCSSAppCSS`} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected paths file to contain %s, got:\n%s", expected, buf.String())
+		}
+	}
+
+	for p, expected := range map[string]string{
+		"/templates/index.html": "TemplatesIndexHTML",
+		"/img/2x/logo-dark.svg": "Img2xLogoDarkSVG",
+		"/404.html":             "Asset404HTML",
+		"/":                     "Asset",
+	} {
+		if id := pathIdentifier(p); id != expected {
+			t.Errorf("%s: expected identifier %s, got %s", p, expected, id)
+		}
+	}
+
+	if ids := pathIdentifiers("", []string{"/a-b.txt", "/a_b.txt"}, make(map[string]bool)); ids["/a-b.txt"] != "ABTXT" || ids["/a_b.txt"] != "ABTXT2" {
+		t.Errorf("expected clashing identifiers to be numbered, got %v", ids)
+	}
+
+	type assetPath string
+
+	fs := testFileSystem()
+
+	if data, err := ReadPath(fs, assetPath("/css/app.css")); err != nil || string(data) != "body { margin: 0; }\n" {
+		t.Errorf("expected ReadPath to read typed paths, got %q (%v)", data, err)
+	}
+}
+
+func TestDirectorySizes(t *testing.T) {
+	dir := t.TempDir()
+	previous := filepath.Join(dir, "previous.go")
+
+	// The previously generated file only contains the templates
+	old := &Generator{StripPrefix: "/testdata"}
+
+	if err := old.Add("testdata/templates"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := old.WriteFile(previous); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	sizes, err := g.DirectorySizes(previous)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bydir := make(map[string]DirectorySize)
+
+	for i, d := range sizes {
+		bydir[d.Dir] = d
+
+		if i > 0 && sizes[i-1].StoredSize < d.StoredSize {
+			t.Errorf("expected directories sorted by descending stored size, got %v", sizes)
+		}
+	}
+
+	templates, _ := ioutil.ReadDir("testdata/templates")
+	var templatesSize int64
+
+	for _, info := range templates {
+		templatesSize += info.Size()
+	}
+
+	if d := bydir["/testdata/templates"]; d.Files != len(templates) || d.Size != templatesSize || d.StoredSize != templatesSize || d.Delta != 0 {
+		t.Errorf("expected unchanged templates of %d bytes, got %+v", templatesSize, d)
+	}
+
+	for _, p := range []string{"/testdata/static", "/testdata/static/css"} {
+		if d := bydir[p]; d.Files != 1 || d.Delta != d.StoredSize || d.StoredSize == 0 {
+			t.Errorf("%s: expected a single new file, got %+v", p, d)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteDirectorySizes(&buf, sizes); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "DIRECTORY") || !strings.Contains(buf.String(), "/testdata/templates") || !strings.Contains(buf.String(), "+0") {
+		t.Errorf("unexpected directory sizes table:\n%s", buf.String())
+	}
+}
+
+func TestAddRoot(t *testing.T) {
+	g := &Generator{}
+
+	roots := []Root{
+		// The contents of testdata/templates mounted at /
+		{Dir: "testdata/templates"},
+
+		// testdata/static mounted as static at /assets/v1
+		{Dir: "testdata/static", Strip: "testdata", Mount: "/assets/v1"},
+
+		// A single file
+		{Dir: "testdata/static/app.js", Mount: "/js/main.js"},
+	}
+
+	for _, root := range roots {
+		if err := g.AddRoot(root); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{"/index.html", "/partial.html", "/assets/v1/static/app.js", "/assets/v1/static/css/app.css", "/js/main.js"} {
+		if _, ok := fs.Files[p]; !ok {
+			t.Errorf("expected %s to exist", p)
+		}
+	}
+
+	// Mount points are listed in their parents
+	for dir, name := range map[string]string{"/": "assets", "/assets": "v1", "/assets/v1": "static"} {
+		found := false
+
+		for _, n := range fs.Dirs[dir] {
+			found = found || n == name
+		}
+
+		if !found {
+			t.Errorf("expected %s to be listed in %s, got %v", name, dir, fs.Dirs[dir])
+		}
+	}
+
+	if _, ok := fs.Files["/templates/index.html"]; ok {
+		t.Errorf("expected root directory to be stripped")
+	}
+
+	// StripPrefix is not stripped from mount points
+	g = &Generator{StripPrefix: "/web"}
+
+	if err := g.AddRoot(Root{Dir: "testdata/templates", Mount: "/web/pages"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if fs, err = g.FileSystem(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := fs.Files["/web/pages/index.html"]; !ok {
+		t.Errorf("expected files at the mount point, got %v", fs.Dirs)
+	}
+
+	if err := (&Generator{}).AddRoot(Root{Dir: "testdata/missing"}); !os.IsNotExist(err) {
+		t.Errorf("expected missing root to not exist, got %v", err)
+	}
+
+	for _, strip := range []string{"static", "testdata/stat", "testdata/static/css"} {
+		if err := (&Generator{}).AddRoot(Root{Dir: "testdata/static", Strip: strip}); err == nil {
+			t.Errorf("expected strip prefix %s not prefixing the root directory to fail", strip)
+		}
+	}
+}
+
+func TestLicenses(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"app.js":                         "console.log('first party')",
+		"vendor/jquery/LICENSE.txt":      "Permission is hereby granted, free of charge, to any person",
+		"vendor/jquery/jquery.js":        "/*! jquery */",
+		"vendor/jquery/plugin.js":        "/* @license GPL-3.0 */",
+		"vendor/fonts/COPYING":           "SIL OPEN FONT LICENSE Version 1.1",
+		"vendor/fonts/sub/font.woff":     "font",
+		"vendor/dual.js":                 "// SPDX-License-Identifier: (MIT OR GPL-2.0)\n",
+		"vendor/unknown/LICENSE":         "All rights reserved",
+		"vendor/unknown/proprietary.css": "body {}",
+	}
+
+	for name, data := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	licenses, err := g.Licenses()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"/vendor/jquery/LICENSE.txt":      "MIT",
+		"/vendor/jquery/jquery.js":        "MIT",
+		"/vendor/jquery/plugin.js":        "GPL-3.0",
+		"/vendor/fonts/COPYING":           "OFL-1.1",
+		"/vendor/fonts/sub/font.woff":     "OFL-1.1",
+		"/vendor/dual.js":                 "(MIT OR GPL-2.0)",
+		"/vendor/unknown/LICENSE":         UnknownLicense,
+		"/vendor/unknown/proprietary.css": UnknownLicense,
+	}
+
+	if !reflect.DeepEqual(licenses, expected) {
+		t.Errorf("expected licenses %v, got %v", expected, licenses)
+	}
+
+	g.AllowedLicenses = []string{"mit", "OFL-1.1"}
+
+	err = g.Write(ioutil.Discard)
+
+	if err == nil {
+		t.Fatalf("expected assets with other licenses to be rejected")
+	}
+
+	for _, denied := range []string{"plugin.js (GPL-3.0)", "proprietary.css (UNKNOWN)"} {
+		if !strings.Contains(err.Error(), denied) {
+			t.Errorf("expected %s to be rejected, got %s", denied, err)
+		}
+	}
+
+	if strings.Contains(err.Error(), "dual.js") || strings.Contains(err.Error(), "font.woff") {
+		t.Errorf("expected allowed licenses to be accepted, got %s", err)
+	}
+
+	g.AllowedLicenses = append(g.AllowedLicenses, "GPL-3.0", UnknownLicense)
+
+	if err := g.Write(ioutil.Discard); err != nil {
+		t.Errorf("expected all licenses to be allowed, got %s", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", Exclude: []string{"*.css"}}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	planned, err := g.Plan()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+
+	for _, f := range planned {
+		paths = append(paths, f.Path)
+
+		data, err := ioutil.ReadFile(filepath.FromSlash(f.SourcePath))
+
+		if err != nil {
+			t.Errorf("%s: expected source path on disk, got %q", f.Path, f.SourcePath)
+			continue
+		}
+
+		compressed, _ := compress(data, Gzip, 0)
+
+		if f.Size != int64(len(data)) || f.CompressedSize != int64(len(compressed)) {
+			t.Errorf("%s: expected sizes %d/%d, got %d/%d", f.Path, len(data), len(compressed), f.Size, f.CompressedSize)
+		}
+	}
+
+	if expected := "/static/app.js /templates/index.html /templates/partial.html"; strings.Join(paths, " ") != expected {
+		t.Errorf("expected planned files %s, got %v", expected, paths)
+	}
+
+	// Planning does not write or otherwise change the generator
+	if g.Stats() != nil {
+		t.Errorf("expected Plan to not generate")
+	}
+}
+
+func TestStats(t *testing.T) {
+	dir := t.TempDir()
+
+	for i := 0; i < StatsLargestFiles+2; i++ {
+		data := strings.Repeat("x", (i+1)*100)
+
+		if err := ioutil.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.txt", i)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var report bytes.Buffer
+
+	g := &Generator{Compressed: true, StatsOutput: &report}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if g.Stats() != nil {
+		t.Errorf("expected no statistics before writing")
+	}
+
+	if err := g.Write(ioutil.Discard); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := g.Stats()
+
+	if stats == nil {
+		t.Fatal("expected statistics after writing")
+	}
+
+	n := StatsLargestFiles + 2
+
+	if stats.Files != n || stats.Size != int64(n*(n+1)/2*100) {
+		t.Errorf("expected %d files of %d bytes, got %d files of %d bytes", n, n*(n+1)/2*100, stats.Files, stats.Size)
+	}
+
+	if stats.StoredSize <= 0 || stats.StoredSize >= stats.Size {
+		t.Errorf("expected compressed stored size, got %d of %d", stats.StoredSize, stats.Size)
+	}
+
+	if len(stats.Largest) != StatsLargestFiles || stats.Largest[0].Path != fmt.Sprintf("/%02d.txt", n-1) || stats.Largest[0].Size != int64(n*100) {
+		t.Errorf("expected the %d largest files in descending order, got %+v", StatsLargestFiles, stats.Largest)
+	}
+
+	for i := 1; i < len(stats.Largest); i++ {
+		if stats.Largest[i].Size > stats.Largest[i-1].Size {
+			t.Errorf("expected largest files in descending order, got %+v", stats.Largest)
+		}
+	}
+
+	for _, expected := range []string{fmt.Sprintf("files:   %d\n", n), "stored:  ", "LARGEST", fmt.Sprintf("/%02d.txt", n-1)} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("expected report to contain %q, got:\n%s", expected, report.String())
+		}
+	}
+}
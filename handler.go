@@ -0,0 +1,253 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// The tag marking assets as private. Private assets are never exposed in
+// directory listings, are left out of manifests and are only served by
+// Handler after successful authorization. Note that FileSystem.Open does
+// not know about requests and therefore does not restrict access; serve
+// file systems with private assets using Handler instead of
+// http.FileServer.
+const PrivateTag = "private"
+
+// The Cache-Control header value of fingerprinted assets (see
+// Generator.Fingerprint), unless overridden by a cache rule.
+const fingerprintedCacheControl = "public, max-age=31536000, immutable"
+
+// An HTTP handler serving the assets of a file system, as configured by its
+// ServerConfig. Directory listings are never served, a request for a
+// directory serves its index file instead. Compressed assets are served as
+// is to clients accepting their encoding, and decompressed otherwise.
+// Uncompressed assets can be compressed on the fly, see
+// ServerConfig.CompressResponses.
+type Handler struct {
+	// The file system to serve assets from.
+	FS *FileSystem
+
+	ServerConfig
+}
+
+// Create a new handler serving the assets of the file system using the
+// default configuration.
+func (f *FileSystem) Handler() *Handler {
+	return &Handler{FS: f}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p := r.URL.Path
+
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+
+	p = path.Clean(p)
+
+	if h.FS.packErr != nil {
+		http.Error(w, h.FS.packErr.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	f, ok := h.FS.Files[p]
+
+	if ok && f.IsDir() {
+		index := h.Index
+
+		if len(index) == 0 {
+			index = DefaultIndex
+		}
+
+		f, ok = h.FS.Files[path.Join(p, index)]
+	}
+
+	if !ok && len(h.SPAFallback) != 0 {
+		f, ok = h.FS.Files[h.SPAFallback]
+	}
+
+	if !ok || (f.IsPrivate() && (h.Authorize == nil || !h.Authorize(r, f))) {
+		http.NotFound(w, r)
+		return
+	}
+
+	for name, value := range h.Headers {
+		w.Header().Set(name, value)
+	}
+
+	if f.IsPrivate() {
+		w.Header().Set("Cache-Control", "private, no-store")
+	} else if cc := h.cacheControl(f.Path); len(cc) != 0 {
+		w.Header().Set("Cache-Control", cc)
+	} else if f.Fingerprinted {
+		w.Header().Set("Cache-Control", fingerprintedCacheControl)
+	}
+
+	// Conditional requests (If-None-Match) are handled by ServeContent
+	if etag := f.ETag(); len(etag) != 0 {
+		w.Header().Set("ETag", etag)
+	}
+
+	if f.compressed() {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		codec, err := f.compression().codec()
+
+		if err == nil && len(codec.Encoding) != 0 && h.allowsEncoding(codec.Encoding) && acceptsEncoding(r.Header.Get("Accept-Encoding"), codec.Encoding) {
+			// Serve the compressed data as is, unless the content type
+			// needs to be sniffed from the decompressed data
+			if ctype := mime.TypeByExtension(path.Ext(f.Path)); len(ctype) != 0 {
+				w.Header().Set("Content-Type", ctype)
+				w.Header().Set("Content-Encoding", codec.Encoding)
+				setEncodedETag(w, f, codec.Encoding)
+
+				rd, err := f.dataReader()
+
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+
+				http.ServeContent(w, r, f.Name(), f.ModTime(), rd)
+				return
+			}
+		}
+
+		data, err := h.FS.ReadFile(f.Path)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		http.ServeContent(w, r, f.Name(), f.ModTime(), bytes.NewReader(data))
+		return
+	}
+
+	if h.CompressResponses && h.compressible(f) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if h.allowsEncoding("gzip") && acceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip") {
+			data, err := h.gzipped(f)
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(f.Path)))
+			w.Header().Set("Content-Encoding", "gzip")
+			setEncodedETag(w, f, "gzip")
+
+			http.ServeContent(w, r, f.Name(), f.ModTime(), bytes.NewReader(data))
+			return
+		}
+	}
+
+	// Use a pooled handle to avoid allocating a reader per request
+	fd, err := h.FS.Open(f.Path)
+
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	http.ServeContent(w, r, f.Name(), f.ModTime(), fd)
+	fd.Close()
+}
+
+// Check whether the uncompressed asset f is compressed when served, see
+// ServerConfig.CompressResponses.
+func (h *Handler) compressible(f *File) bool {
+	if len(mime.TypeByExtension(path.Ext(f.Path))) == 0 || h.FS.sources != nil {
+		return false
+	}
+
+	patterns := h.FS.Precompressed
+
+	if patterns == nil {
+		patterns = DefaultPrecompressed
+	}
+
+	for _, pattern := range patterns {
+		if matchPattern(pattern, f.Path) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the gzip compressed data of the uncompressed asset f. The compressed
+// data is kept by the file system, shared between handlers, as long as the
+// total size of the kept data does not exceed ServerConfig.MaxCompressedCache.
+func (h *Handler) gzipped(f *File) ([]byte, error) {
+	h.FS.gzippedMu.Lock()
+	data, ok := h.FS.gzipped[f]
+	h.FS.gzippedMu.Unlock()
+
+	if ok {
+		return data, nil
+	}
+
+	data, err := f.data()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = gzipCompress(data, 0); err != nil {
+		return nil, err
+	}
+
+	h.FS.gzippedMu.Lock()
+	defer h.FS.gzippedMu.Unlock()
+
+	if cached, ok := h.FS.gzipped[f]; ok {
+		return cached, nil
+	}
+
+	if h.MaxCompressedCache <= 0 || h.FS.gzippedSize+int64(len(data)) <= h.MaxCompressedCache {
+		if h.FS.gzipped == nil {
+			h.FS.gzipped = make(map[*File][]byte)
+		}
+
+		h.FS.gzipped[f] = data
+		h.FS.gzippedSize += int64(len(data))
+	}
+
+	return data, nil
+}
+
+// Set the entity tag of the asset f served in the given content coding, which
+// differs from the entity tag of the asset itself since the representations
+// differ.
+func setEncodedETag(w http.ResponseWriter, f *File, encoding string) {
+	if len(f.Digest) != 0 {
+		w.Header().Set("ETag", `"`+f.Digest+"-"+encoding+`"`)
+	}
+}
+
+// Create an authorization function which accepts requests carrying the given
+// bearer token in their Authorization header. The digests of the tokens are
+// compared in constant time, such that neither the token nor its length leaks
+// through the time taken.
+func BearerTokenAuthorizer(token string) func(r *http.Request, f *File) bool {
+	expected := sha256.Sum256([]byte(token))
+
+	return func(r *http.Request, f *File) bool {
+		auth := r.Header.Get("Authorization")
+
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return false
+		}
+
+		actual := sha256.Sum256([]byte(auth[len("Bearer "):]))
+		return subtle.ConstantTimeCompare(actual[:], expected[:]) == 1
+	}
+}
@@ -0,0 +1,109 @@
+package assets
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A file mode override, see Generator.ModeOverrides.
+type ModeOverride struct {
+	// A glob pattern (see Generator.Exclude) of the files the override
+	// applies to, matched against the paths of the generated file system
+	Pattern string
+
+	// The permissions of matching files
+	Mode os.FileMode
+}
+
+// Get the file mode of the asset at path k as written to the generated file
+// system.
+func (x *Generator) fileMode(k string, info os.FileInfo) os.FileMode {
+	mode := info.Mode()
+
+	if !mode.IsDir() {
+		if perm, ok := x.modeOverride(k); ok {
+			return mode&^os.ModePerm | perm
+		}
+	}
+
+	if !x.Hermetic {
+		return mode
+	}
+
+	// The permissions of files depend on the umask of the machine the
+	// files were checked out on
+	if mode.IsDir() {
+		return os.ModeDir | 0755
+	} else if isExecutable(mode) {
+		return 0755
+	}
+
+	return 0644
+}
+
+// Get the permissions of the file at path k set by ModeOverrides, if any.
+func (x *Generator) modeOverride(k string) (os.FileMode, bool) {
+	if len(x.ModeOverrides) == 0 {
+		return 0, false
+	}
+
+	vp, _ := x.virtualPath(k)
+
+	// Later overrides take precedence
+	for i := len(x.ModeOverrides) - 1; i >= 0; i-- {
+		if o := x.ModeOverrides[i]; matchPattern(o.Pattern, vp) {
+			return o.Mode & os.ModePerm, true
+		}
+	}
+
+	return 0, false
+}
+
+// Get the host specific directories which should not appear in the paths of
+// hermetically generated assets.
+func hostDirs() []string {
+	var ret []string
+
+	if wd, err := os.Getwd(); err == nil {
+		ret = append(ret, wd)
+	}
+
+	if home, err := os.UserHomeDir(); err == nil {
+		ret = append(ret, home)
+	}
+
+	if tmp := os.TempDir(); len(tmp) != 0 {
+		ret = append(ret, tmp)
+	}
+
+	for i, d := range ret {
+		ret[i] = filepath.ToSlash(filepath.Clean(d))
+	}
+
+	return ret
+}
+
+// Check that none of the asset paths contains a host specific directory,
+// which happens when adding files by absolute path without stripping the
+// path prefix.
+func (x *Generator) checkHermetic() error {
+	dirs := hostDirs()
+
+	for _, k := range x.sortedPaths() {
+		vp, ok := x.virtualPath(k)
+
+		if !ok {
+			continue
+		}
+
+		for _, d := range dirs {
+			if d != "/" && (vp == d || strings.HasPrefix(vp, d+"/")) {
+				return fmt.Errorf("asset path %s contains host specific directory %s", vp, d)
+			}
+		}
+	}
+
+	return nil
+}
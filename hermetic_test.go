@@ -0,0 +1,111 @@
+package assets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriteHermetic(t *testing.T) {
+	var outputs [][]byte
+
+	for i, mode := range []os.FileMode{0600, 0664} {
+		dir := t.TempDir()
+		p := filepath.Join(dir, "web", "app.js")
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte("console.log(1);\n"), mode); err != nil {
+			t.Fatal(err)
+		}
+
+		mtime := time.Unix(int64(1000000+i), 0)
+
+		if err := os.Chtimes(p, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		g := &Generator{Hermetic: true}
+
+		if err := g.AddRoot(Root{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, generate(t, g))
+	}
+
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Errorf("expected hermetic generation to produce identical output, got:\n%s\n%s", outputs[0], outputs[1])
+	}
+
+	wd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Hermetic: true}
+
+	if err := g.AddRoot(Root{Dir: "testdata", Mount: filepath.ToSlash(wd)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Write(ioutil.Discard); err == nil {
+		t.Errorf("expected host specific asset paths to fail hermetic generation")
+	}
+}
+
+func TestModeOverrides(t *testing.T) {
+	dir := t.TempDir()
+
+	for p, mode := range map[string]os.FileMode{"bin/run": 0600, "index.html": 0600, "data.txt": 0777} {
+		p = filepath.Join(dir, p)
+
+		if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, nil, mode); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chmod(p, mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{
+		ModeOverrides: []ModeOverride{
+			{Pattern: "*", Mode: 0644},
+			{Pattern: "bin/*", Mode: 0755},
+		},
+	}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]os.FileMode{
+		"/bin/run":    0755,
+		"/index.html": 0644,
+		"/data.txt":   0644,
+		"/bin":        os.ModeDir | 0700,
+	}
+
+	for p, mode := range expected {
+		if f, ok := fss["Assets"].Files[p]; !ok || f.Mode() != mode {
+			t.Errorf("expected mode %s for %s, got %v", mode, p, f)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package assets
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A function unmarshaling locale file data.
+type UnmarshalFunc func(data []byte, v interface{}) error
+
+// A map of file extensions to the unmarshal functions used by LoadCatalog.
+// JSON and YAML (see UnmarshalYAML) are supported out of the box, other
+// formats can be supported by registering an unmarshal function for their
+// extension(s).
+var CatalogFormats = map[string]UnmarshalFunc{
+	".json": json.Unmarshal,
+	".yaml": UnmarshalYAML,
+	".yml":  UnmarshalYAML,
+}
+
+// A catalog of translated messages.
+type Catalog struct {
+	// A map of locales to message keys to messages.
+	Messages map[string]map[string]string
+
+	// Explicit fallback locales for a locale, consulted in order after the
+	// locale itself.
+	Fallbacks map[string][]string
+
+	// The locale consulted last when resolving a message.
+	DefaultLocale string
+}
+
+// Load a message catalog from the locale files found in the directory dir
+// of the file system. Each file (e.g. locales/de-CH.json) contains the
+// messages of the locale named after the file. Nested objects are flattened
+// into dot separated message keys. Files with an extension not present in
+// CatalogFormats are ignored.
+func LoadCatalog(fs *FileSystem, dir string) (*Catalog, error) {
+	d, err := fs.Open(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := d.Readdir(-1)
+	d.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalog{
+		Messages:  make(map[string]map[string]string),
+		Fallbacks: make(map[string][]string),
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		ext := path.Ext(e.Name())
+		unmarshal, ok := CatalogFormats[ext]
+
+		if !ok {
+			continue
+		}
+
+		p := path.Join(dir, e.Name())
+		data, err := fs.ReadFile(p)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var v map[string]interface{}
+
+		if err := unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("%s: %s", p, err)
+		}
+
+		messages := make(map[string]string)
+		flattenMessages(messages, "", v)
+
+		c.Messages[strings.TrimSuffix(e.Name(), ext)] = messages
+	}
+
+	return c, nil
+}
+
+func flattenMessages(messages map[string]string, prefix string, v interface{}) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, item := range vv {
+			if len(prefix) != 0 {
+				k = prefix + "." + k
+			}
+
+			flattenMessages(messages, k, item)
+		}
+	case map[interface{}]interface{}:
+		for k, item := range vv {
+			flattenMessages(messages, prefix, map[string]interface{}{fmt.Sprint(k): item})
+		}
+	case string:
+		messages[prefix] = vv
+	case nil:
+	default:
+		messages[prefix] = fmt.Sprint(vv)
+	}
+}
+
+// Get the chain of locales consulted when resolving a message for the given
+// locale. The chain consists of the locale itself, its explicit fallbacks,
+// its less specific parents (de-CH falls back to de) and finally the
+// default locale.
+func (c *Catalog) Chain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+
+	add := func(l string, parents bool) {
+		for len(l) != 0 {
+			if !seen[l] {
+				seen[l] = true
+				chain = append(chain, l)
+			}
+
+			i := strings.LastIndexAny(l, "-_")
+
+			if !parents || i < 0 {
+				break
+			}
+
+			l = l[:i]
+		}
+	}
+
+	add(locale, false)
+
+	for _, l := range c.Fallbacks[locale] {
+		add(l, true)
+	}
+
+	add(locale, true)
+	add(c.DefaultLocale, true)
+	return chain
+}
+
+// Get the message for key in the given locale, resolving missing messages
+// using the locale fallback chain.
+func (c *Catalog) Get(locale string, key string) (string, bool) {
+	for _, l := range c.Chain(locale) {
+		if m, ok := c.Messages[l][key]; ok {
+			return m, true
+		}
+	}
+
+	return "", false
+}
+
+// Get the message for key in the given locale, or the key itself when no
+// message could be found.
+func (c *Catalog) T(locale string, key string) string {
+	if m, ok := c.Get(locale, key); ok {
+		return m
+	}
+
+	return key
+}
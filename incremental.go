@@ -0,0 +1,207 @@
+package assets
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+// The prefix of the comment line recording the digest of the inputs of a
+// generated file, see Generator.SkipUnchanged.
+const inputsPrefix = "// go-assets:inputs "
+
+// Get the hex encoded SHA-256 digest of the inputs of the generated file: the
+// version of go-assets, the generator options, and the paths, metadata and
+// (transformed) contents of the assets of all file systems (see
+// SkipUnchanged). With ContinueOnError, the digest is empty when files cannot
+// be read, such that their failures are reported on every generation.
+func (x *Generator) inputsDigest() (string, error) {
+	h := sha256.New()
+
+	fmt.Fprintf(h, "format %d\n", FormatVersion)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == DefaultRuntimeImport {
+				fmt.Fprintf(h, "version %s %s\n", dep.Version, dep.Sum)
+			}
+		}
+	}
+
+	maxModTime, err := x.maxModTime()
+
+	if err != nil {
+		return "", err
+	}
+
+	gens := append([]*Generator{x}, x.groups...)
+
+	for _, g := range gens {
+		if len(g.failures) != 0 {
+			return "", nil
+		}
+
+		writeOptions(h, g)
+
+		for _, key := range sortedKeys(g.keys) {
+			fmt.Fprintf(h, "key %q %q\n", key, g.keys[key])
+		}
+
+		for _, k := range g.sortedPaths() {
+			vp, ok := g.virtualPath(k)
+
+			if !ok {
+				continue
+			}
+
+			f := g.fsFilesMap[k]
+			m := g.meta(k)
+
+			fmt.Fprintf(h, "file %q %q %s %d %q %q %d", k, vp, g.fileMode(k, f.info), g.modTime(f.info, maxModTime).UnixNano(), g.tags(k), m.title, m.order)
+
+			if !f.info.IsDir() {
+				data, err := g.read(k)
+
+				if err != nil && x.ContinueOnError {
+					return "", nil
+				} else if err != nil {
+					return "", err
+				}
+
+				sum := sha256.Sum256(data)
+				fmt.Fprintf(h, " %x", sum)
+			}
+
+			fmt.Fprintln(h)
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Write the exported options of the generator to h. Options which cannot be
+// compared by value, such as functions and writers, only contribute whether
+// they are set, such that changing them is not detected (see SkipUnchanged).
+// Transforms contribute their names and patterns, their effect is covered by
+// the transformed contents.
+func writeOptions(h hash.Hash, x *Generator) {
+	v := reflect.ValueOf(x).Elem()
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		if !field.IsExported() {
+			continue
+		}
+
+		value := v.Field(i)
+
+		if transforms, ok := value.Interface().([]Transform); ok {
+			for _, t := range transforms {
+				fmt.Fprintf(h, "%s %q %#v\n", field.Name, t.Name, t.Patterns)
+			}
+
+			continue
+		}
+
+		if tmpl, ok := value.Interface().(*template.Template); ok && tmpl != nil {
+			for _, tt := range tmpl.Templates() {
+				if tt.Tree != nil {
+					fmt.Fprintf(h, "%s %q %q\n", field.Name, tt.Name(), tt.Tree.Root.String())
+				}
+			}
+
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Func, reflect.Interface, reflect.Ptr, reflect.Chan:
+			fmt.Fprintf(h, "%s %v\n", field.Name, !value.IsNil())
+		default:
+			fmt.Fprintf(h, "%s %#v\n", field.Name, value.Interface())
+		}
+	}
+}
+
+// Check whether the files written by WriteFile along with the generated file
+// at filename (see WriteFile) exist, such that skipping an unchanged
+// generation does not leave them missing.
+func (x *Generator) companionFilesExist(filename string) bool {
+	dir := filepath.Dir(filename)
+
+	var files []string
+
+	if len(x.PackFile) != 0 {
+		files = append(files, filepath.Join(dir, x.PackFile))
+	} else if len(x.EmbedDir) != 0 {
+		files = append(files, filepath.Join(dir, x.EmbedDir))
+	} else if x.DataShardSize > 0 && len(x.filePaths()) != 0 {
+		// At least the first data file is written when there is data
+		files = append(files, dataFilename(filename, 1))
+	}
+
+	if len(x.DevTag) != 0 {
+		files = append(files, devFilename(filename, x.DevTag))
+	}
+
+	if x.EmbeddedTest {
+		files = append(files, embeddedTestFilename(filename))
+	}
+
+	if len(x.testGenerators()) != 0 {
+		files = append(files, testFilename(filename))
+	}
+
+	for _, f := range files {
+		if _, err := os.Stat(f); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Get the digest of the inputs recorded in the generated file at filename, or
+// an empty string if there is none.
+func recordedInputs(filename string) string {
+	fd, err := os.Open(filename)
+
+	if err != nil {
+		return ""
+	}
+
+	defer fd.Close()
+
+	scanner := bufio.NewScanner(fd)
+
+	// The digest is recorded in the comment block at the top of the file
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, inputsPrefix) {
+			return strings.TrimPrefix(line, inputsPrefix)
+		}
+
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+	}
+
+	return ""
+}
+
+// Record the digest of the inputs in the banner of a generated file, on the
+// line following the generated code marker.
+func recordInputs(banner string, digest string) string {
+	marker := generatedMarker + "\n"
+	return marker + inputsPrefix + digest + "\n" + strings.TrimPrefix(banner, marker)
+}
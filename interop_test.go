@@ -0,0 +1,167 @@
+package assets
+
+import (
+	"bytes"
+	"html/template"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// The file systems tested against the standard library consumers, built from
+// testdata with and without compression
+func interopFileSystems(t *testing.T) map[string]*FileSystem {
+	ret := make(map[string]*FileSystem)
+
+	for _, compressed := range []bool{false, true} {
+		fs, err := Snapshot("testdata", func(g *Generator) {
+			g.Compressed = compressed
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := "uncompressed"
+
+		if compressed {
+			name = "compressed"
+		}
+
+		ret[name] = fs
+	}
+
+	return ret
+}
+
+func TestInteropFSTest(t *testing.T) {
+	for name, afs := range interopFileSystems(t) {
+		t.Run(name, func(t *testing.T) {
+			err := fstest.TestFS(afs.FS(),
+				"static/app.js",
+				"static/css/app.css",
+				"templates/index.html",
+				"templates/partial.html")
+
+			if err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestInteropWalkDir(t *testing.T) {
+	for name, afs := range interopFileSystems(t) {
+		t.Run(name, func(t *testing.T) {
+			var paths []string
+
+			err := fs.WalkDir(afs.FS(), ".", func(p string, d fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+
+				paths = append(paths, p)
+				return nil
+			})
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := ". static static/app.js static/css static/css/app.css templates templates/index.html templates/partial.html"
+
+			if actual := strings.Join(paths, " "); actual != expected {
+				t.Errorf("expected walk %q, got %q", expected, actual)
+			}
+		})
+	}
+}
+
+func TestInteropParseFS(t *testing.T) {
+	for name, afs := range interopFileSystems(t) {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := template.ParseFS(afs.FS(), "templates/*.html")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+
+			if err := tmpl.ExecuteTemplate(&buf, "index.html", map[string]string{"Title": "title", "Body": "body"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if !strings.Contains(buf.String(), "<title>title</title>") {
+				t.Errorf("unexpected template output %q", buf.String())
+			}
+
+			if tmpl.Lookup("partial.html") == nil {
+				t.Errorf("expected partial.html to be parsed")
+			}
+		})
+	}
+}
+
+func interopGet(t *testing.T, h http.Handler, p string, header http.Header) *http.Response {
+	r := httptest.NewRequest("GET", p, nil)
+
+	for k, v := range header {
+		r.Header[k] = v
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	return w.Result()
+}
+
+func TestInteropFileServer(t *testing.T) {
+	afss := interopFileSystems(t)
+
+	// Open serves the stored data, so only the uncompressed file system is
+	// served directly. Compressed file systems are served through FS.
+	handlers := map[string]http.Handler{
+		"http.FileSystem":    http.FileServer(afss["uncompressed"]),
+		"fs.FS":              http.FileServer(http.FS(afss["uncompressed"].FS())),
+		"compressed fs.FS":   http.FileServer(http.FS(afss["compressed"].FS())),
+		"compressed handler": afss["compressed"].Handler(),
+	}
+
+	for name, h := range handlers {
+		t.Run(name, func(t *testing.T) {
+			resp := interopGet(t, h, "/static/app.js", http.Header{"Range": {"bytes=0-6"}})
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != http.StatusPartialContent || string(body) != "console" {
+				t.Errorf("expected partial content %q, got %d %q", "console", resp.StatusCode, body)
+			}
+
+			resp = interopGet(t, h, "/static/app.js", http.Header{"Range": {"bytes=100-200"}})
+
+			if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+				t.Errorf("expected unsatisfiable range, got %d", resp.StatusCode)
+			}
+
+			resp = interopGet(t, h, "/static/missing.js", nil)
+
+			if resp.StatusCode != http.StatusNotFound {
+				t.Errorf("expected not found, got %d", resp.StatusCode)
+			}
+		})
+	}
+
+	// Directory listings are served by http.FileServer, but not by Handler
+	for _, name := range []string{"http.FileSystem", "fs.FS", "compressed fs.FS"} {
+		resp := interopGet(t, handlers[name], "/static/", nil)
+		body, _ := ioutil.ReadAll(resp.Body)
+
+		if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `href="app.js"`) || !strings.Contains(string(body), `href="css/"`) {
+			t.Errorf("%s: expected directory listing, got %d %q", name, resp.StatusCode, body)
+		}
+	}
+}
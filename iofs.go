@@ -0,0 +1,225 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+)
+
+// Get an io/fs view of the file system, for use with consumers of fs.FS such
+// as fs.WalkDir, template.ParseFS and http.FS. Unlike files opened with Open,
+// files opened through the view read their decompressed contents and report
+// their decompressed size. Like in directory listings served over http,
+// private assets are not listed in directories but can be opened by name.
+func (f *FileSystem) FS() fs.FS {
+	if len(f.LocalPath) != 0 {
+		return os.DirFS(f.LocalPath)
+	}
+
+	return ioFS{fs: f}
+}
+
+type ioFS struct {
+	fs *FileSystem
+}
+
+func (x ioFS) lookup(op string, name string) (*File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	if x.fs.packErr != nil {
+		return nil, &fs.PathError{Op: op, Path: name, Err: x.fs.packErr}
+	}
+
+	f, ok := x.fs.Files[path.Join("/", name)]
+
+	if !ok {
+		return nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+
+	return f, nil
+}
+
+// Implementation of fs.FS
+func (x ioFS) Open(name string) (fs.File, error) {
+	f, err := x.lookup("open", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.IsDir() {
+		return &ioDir{fs: x.fs, file: f}, nil
+	}
+
+	if src, ok := x.fs.sources[f.Path]; ok {
+		return os.Open(src)
+	}
+
+	data, err := f.data()
+
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+
+	if f.compressed() {
+		if data, err = x.fs.ReadFile(f.Path); err != nil {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+
+	return &ioFile{
+		info:   ioFileInfo{File: f, size: int64(len(data))},
+		Reader: bytes.NewReader(data),
+	}, nil
+}
+
+// Implementation of fs.ReadFileFS
+func (x ioFS) ReadFile(name string) ([]byte, error) {
+	f, err := x.lookup("read", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if f.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrInvalid}
+	}
+
+	// Always returns a copy, which the caller is allowed to modify
+	return x.fs.ReadFile(f.Path)
+}
+
+// Implementation of fs.ReadDirFS
+func (x ioFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	f, err := x.lookup("readdir", name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	d := &ioDir{fs: x.fs, file: f}
+	ret, err := d.ReadDir(-1)
+
+	// Directory listings are normally sorted already
+	if !sort.SliceIsSorted(ret, func(i, j int) bool { return ret[i].Name() < ret[j].Name() }) {
+		sort.Slice(ret, func(i, j int) bool {
+			return ret[i].Name() < ret[j].Name()
+		})
+	}
+
+	return ret, err
+}
+
+// Implementation of fs.StatFS
+func (x ioFS) Stat(name string) (fs.FileInfo, error) {
+	fd, err := x.Open(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer fd.Close()
+	return fd.Stat()
+}
+
+// The file info of a file opened through the io/fs view, reporting the size
+// of the decompressed contents.
+type ioFileInfo struct {
+	*File
+	size int64
+}
+
+func (f ioFileInfo) Size() int64 {
+	return f.size
+}
+
+type ioFile struct {
+	*bytes.Reader
+	info ioFileInfo
+}
+
+func (f *ioFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+func (f *ioFile) Close() error {
+	return nil
+}
+
+type ioDir struct {
+	fs    *FileSystem
+	file  *File
+	index int
+}
+
+func (d *ioDir) Stat() (fs.FileInfo, error) {
+	return d.file, nil
+}
+
+func (d *ioDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.file.Path, Err: fs.ErrInvalid}
+}
+
+func (d *ioDir) Close() error {
+	return nil
+}
+
+// Implementation of fs.ReadDirFile
+func (d *ioDir) ReadDir(count int) ([]fs.DirEntry, error) {
+	var ret []fs.DirEntry
+
+	next, err := d.fs.visitDir(d.file.Path, d.index, count, func(n int) {
+		ret = make([]fs.DirEntry, 0, n)
+	}, func(fi *File) {
+		ret = append(ret, ioDirEntry{file: fi})
+	})
+
+	d.index = next
+
+	if err == io.EOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: d.file.Path, Err: err}
+	}
+
+	return ret, nil
+}
+
+// A directory entry of the io/fs view. The file info of an entry is only
+// determined when requested, since determining the decompressed size of
+// compressed assets requires decompressing them. Entries consist of a single
+// pointer, such that storing them in an fs.DirEntry does not allocate.
+type ioDirEntry struct {
+	file *File
+}
+
+func (e ioDirEntry) Name() string {
+	return e.file.Name()
+}
+
+func (e ioDirEntry) IsDir() bool {
+	return e.file.IsDir()
+}
+
+func (e ioDirEntry) Type() fs.FileMode {
+	return e.file.FileMode.Type()
+}
+
+func (e ioDirEntry) Info() (fs.FileInfo, error) {
+	if e.file.IsDir() || !e.file.compressed() {
+		return e.file, nil
+	}
+
+	data, err := e.file.fs.ReadFile(e.file.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ioFileInfo{File: e.file, size: int64(len(data))}, nil
+}
@@ -0,0 +1,229 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The identifier used for license files whose license is not recognized.
+const UnknownLicense = "UNKNOWN"
+
+var (
+	spdxRegexp          = regexp.MustCompile(`SPDX-License-Identifier:\s*([^\n\r*]+)`)
+	licenseAnnotationRe = regexp.MustCompile(`@license\s+([A-Za-z0-9.+-]+)`)
+)
+
+// Phrases identifying the text of common licenses, in order of precedence.
+var licensePhrases = []struct {
+	id      string
+	phrases []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"OFL-1.1", []string{"SIL OPEN FONT LICENSE"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"CC0-1.0", []string{"CC0 1.0"}},
+}
+
+// Detect a license declared in the header of a file, either as an SPDX
+// identifier or as an @license annotation (common in minified javascript).
+func headerLicense(data []byte) string {
+	if len(data) > 1024 {
+		data = data[:1024]
+	}
+
+	if m := spdxRegexp.FindSubmatch(data); m != nil {
+		return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(string(m[1])), "-->"))
+	}
+
+	if m := licenseAnnotationRe.FindSubmatch(data); m != nil {
+		return string(m[1])
+	}
+
+	return ""
+}
+
+// Classify the full text of a license file.
+func classifyLicense(data []byte) string {
+	if l := headerLicense(data); len(l) != 0 {
+		return l
+	}
+
+	text := strings.Join(strings.Fields(string(data)), " ")
+
+	for _, l := range licensePhrases {
+		matches := true
+
+		for _, p := range l.phrases {
+			if !strings.Contains(text, p) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return l.id
+		}
+	}
+
+	return UnknownLicense
+}
+
+func isLicenseFile(name string) bool {
+	name = strings.ToUpper(name)
+	return strings.HasPrefix(name, "LICENSE") || strings.HasPrefix(name, "LICENCE") || strings.HasPrefix(name, "COPYING")
+}
+
+// Check whether a license expression (e.g. MIT OR Apache-2.0) is permitted
+// by the list of allowed license identifiers.
+func licenseAllowed(expr string, allowed []string) bool {
+	isAllowed := func(id string) bool {
+		id = strings.Trim(id, "()")
+
+		for _, a := range allowed {
+			if strings.EqualFold(a, id) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, alt := range strings.Split(expr, " OR ") {
+		ok := true
+
+		for _, id := range strings.Split(alt, " AND ") {
+			if !isAllowed(strings.TrimSpace(id)) {
+				ok = false
+				break
+			}
+		}
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Detect the licenses of the assets in the generator. The license of a file is
+// taken from its header (an SPDX-License-Identifier or @license annotation),
+// or otherwise from the nearest LICENSE (or COPYING) file in its directory or
+// any of its parent directories. Files without any detected license are
+// considered first party and are not included. Returns a map of asset
+// paths (as in the generated file system) to license expressions.
+func (x *Generator) Licenses() (map[string]string, error) {
+	ret := make(map[string]string)
+	dirLicenses := make(map[string]string)
+
+	var dirLicense func(d string) (string, error)
+
+	dirLicense = func(d string) (string, error) {
+		if l, ok := dirLicenses[d]; ok {
+			return l, nil
+		}
+
+		l := ""
+
+		for _, name := range x.fsDirsMap[d] {
+			f, ok := x.fsFilesMap[path.Join(d, name)]
+
+			if !ok || f.info.IsDir() || !isLicenseFile(name) {
+				continue
+			}
+
+			data, err := f.read()
+
+			if err != nil {
+				return "", err
+			}
+
+			l = classifyLicense(data)
+			break
+		}
+
+		if len(l) == 0 && d != "/" {
+			var err error
+
+			if l, err = dirLicense(path.Dir(d)); err != nil {
+				return "", err
+			}
+		}
+
+		dirLicenses[d] = l
+		return l, nil
+	}
+
+	for _, k := range x.sortedPaths() {
+		f := x.fsFilesMap[k]
+
+		if f.info.IsDir() {
+			continue
+		}
+
+		vp, ok := x.virtualPath(k)
+
+		if !ok {
+			continue
+		}
+
+		data, err := f.read()
+
+		if err != nil {
+			return nil, err
+		}
+
+		l := headerLicense(bytes.TrimSpace(data))
+
+		if len(l) == 0 {
+			if l, err = dirLicense(path.Dir(k)); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(l) != 0 {
+			ret[vp] = l
+		}
+	}
+
+	return ret, nil
+}
+
+func (x *Generator) checkLicenses() error {
+	if len(x.AllowedLicenses) == 0 {
+		return nil
+	}
+
+	licenses, err := x.Licenses()
+
+	if err != nil {
+		return err
+	}
+
+	var denied []string
+
+	for _, p := range sortedKeys(licenses) {
+		if !licenseAllowed(licenses[p], x.AllowedLicenses) {
+			denied = append(denied, fmt.Sprintf("%s (%s)", p, licenses[p]))
+		}
+	}
+
+	if len(denied) != 0 {
+		return fmt.Errorf("assets with licenses which are not allowed: %s", strings.Join(denied, ", "))
+	}
+
+	return nil
+}
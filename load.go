@@ -0,0 +1,81 @@
+package assets
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+)
+
+// A map of file extensions to the unmarshal functions used by Load. JSON and
+// YAML (see UnmarshalYAML) are supported out of the box, other formats can be
+// supported by registering an unmarshal function for their extension(s).
+var DataFormats = map[string]UnmarshalFunc{
+	".json": json.Unmarshal,
+	".yaml": UnmarshalYAML,
+	".yml":  UnmarshalYAML,
+}
+
+// The key of a decoded asset in the cache of a file system.
+type loadKey struct {
+	path string
+	typ  reflect.Type
+}
+
+// Load and decode the asset at path p into a value of type T, using the
+// unmarshal function registered in DataFormats for the extension of p.
+// Decoded assets are cached by path and type (unless the file system has a
+// LocalPath), such that repeated loads of configuration or locale assets do
+// not decompress and decode them again. Cached values are shared between
+// callers and must not be modified.
+func Load[T any](fs *FileSystem, p string) (T, error) {
+	unmarshal, ok := DataFormats[path.Ext(p)]
+
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("%s: unsupported data format %s", p, path.Ext(p))
+	}
+
+	return load[T](fs, p, unmarshal)
+}
+
+// Load and decode the JSON asset at path p into a value of type T, see Load.
+func LoadJSON[T any](fs *FileSystem, p string) (T, error) {
+	return load[T](fs, p, json.Unmarshal)
+}
+
+// Load and decode the YAML asset at path p into a value of type T, see Load
+// and UnmarshalYAML.
+func LoadYAML[T any](fs *FileSystem, p string) (T, error) {
+	return load[T](fs, p, UnmarshalYAML)
+}
+
+func load[T any](fs *FileSystem, p string, unmarshal UnmarshalFunc) (T, error) {
+	var ret T
+
+	key := loadKey{path: path.Clean(p), typ: reflect.TypeOf(&ret).Elem()}
+	cache := len(fs.LocalPath) == 0 && fs.sources == nil
+
+	if cache {
+		if v, ok := fs.loaded.Load(key); ok {
+			return v.(T), nil
+		}
+	}
+
+	data, err := fs.ReadFile(p)
+
+	if err != nil {
+		return ret, err
+	}
+
+	if err := unmarshal(data, &ret); err != nil {
+		return ret, fmt.Errorf("%s: %s", p, err)
+	}
+
+	if cache {
+		v, _ := fs.loaded.LoadOrStore(key, ret)
+		return v.(T), nil
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,131 @@
+package assets
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	type config struct {
+		Name  string `json:"name"`
+		Count int    `json:"count"`
+	}
+
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/config.json", FileMode: 0644, Data: `{"name": "app", "count": 3}`},
+		{Path: "/config.toml", FileMode: 0644, Data: `name = "app"`},
+		{Path: "/config.yaml", FileMode: 0644, Data: "name: app\ncount: 4\n"},
+	}, "")
+
+	c, err := Load[config](fs, "/config.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Name != "app" || c.Count != 3 {
+		t.Errorf("unexpected config %+v", c)
+	}
+
+	// Cached values are returned without decoding the asset again
+	fs.Files["/config.json"].Data = []byte(`{"name": "changed"}`)
+
+	if c, err := LoadJSON[config](fs, "/config.json"); err != nil || c.Name != "app" {
+		t.Errorf("expected cached config, got %+v (%v)", c, err)
+	}
+
+	// Values are cached per type
+	if m, err := LoadJSON[map[string]interface{}](fs, "/config.json"); err != nil || m["name"] != "changed" {
+		t.Errorf("expected config to be decoded for another type, got %v (%v)", m, err)
+	}
+
+	for _, load := range []func(*FileSystem, string) (config, error){Load[config], LoadYAML[config]} {
+		if c, err := load(fs, "/config.yaml"); err != nil || c.Name != "app" || c.Count != 4 {
+			t.Errorf("expected yaml config, got %+v (%v)", c, err)
+		}
+	}
+
+	if _, err := Load[config](fs, "/config.toml"); err == nil {
+		t.Errorf("expected unsupported format to fail")
+	}
+
+	// Other formats are decoded with their registered unmarshal function
+	DataFormats[".toml"] = func(data []byte, v interface{}) error {
+		v.(*config).Name = "toml"
+		return nil
+	}
+
+	defer delete(DataFormats, ".toml")
+
+	if c, err := Load[config](fs, "/config.toml"); err != nil || c.Name != "toml" {
+		t.Errorf("expected registered format to be decoded, got %+v (%v)", c, err)
+	}
+}
+
+func TestLoadCatalog(t *testing.T) {
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/locales", FileMode: os.ModeDir | 0755},
+		{Path: "/locales/en.json", FileMode: 0644, Data: `{"hello": "Hello", "bye": "Bye", "menu": {"file": "File", "count": 3}}`},
+		{Path: "/locales/de.json", FileMode: 0644, Data: `{"hello": "Hallo", "menu": {"file": "Datei"}}`},
+		{Path: "/locales/de-CH.json", FileMode: 0644, Data: `{"hello": "Grüezi"}`},
+		{Path: "/locales/fr.yaml", FileMode: 0644, Data: "hello: Bonjour\nmenu:\n  file: Fichier\n"},
+		{Path: "/locales/README.md", FileMode: 0644, Data: "not a locale"},
+	}, "")
+
+	c, err := LoadCatalog(fs, "/locales")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.Messages) != 4 {
+		t.Errorf("expected only locale files to be loaded, got %v", c.Messages)
+	}
+
+	c.DefaultLocale = "en"
+
+	if chain := strings.Join(c.Chain("de-CH"), ","); chain != "de-CH,de,en" {
+		t.Errorf("expected fallback chain de-CH,de,en, got %s", chain)
+	}
+
+	tests := []struct {
+		locale, key, expected string
+	}{
+		{"de-CH", "hello", "Grüezi"},
+		{"de-CH", "menu.file", "Datei"},
+		{"de-CH", "bye", "Bye"},
+		{"de", "hello", "Hallo"},
+		{"fr", "hello", "Bonjour"},
+		{"fr", "menu.file", "Fichier"},
+		{"fr", "bye", "Bye"},
+		{"it", "hello", "Hello"},
+		{"en", "menu.count", "3"},
+		{"en", "unknown", "unknown"},
+	}
+
+	for _, test := range tests {
+		if m := c.T(test.locale, test.key); m != test.expected {
+			t.Errorf("%s %s: expected %q, got %q", test.locale, test.key, test.expected, m)
+		}
+	}
+
+	// Explicit fallbacks are consulted before parents
+	c.Fallbacks["de-CH"] = []string{"en"}
+
+	if m := c.T("de-CH", "menu.file"); m != "File" {
+		t.Errorf("expected explicit fallback to be consulted first, got %q", m)
+	}
+
+	if _, err := LoadCatalog(fs, "/missing"); !os.IsNotExist(err) {
+		t.Errorf("expected missing directory to not exist, got %v", err)
+	}
+
+	fs.Files["/locales/de.json"].Data = []byte("{")
+
+	if _, err := LoadCatalog(fs, "/locales"); err == nil || !strings.Contains(err.Error(), "/locales/de.json") {
+		t.Errorf("expected invalid locale file to be reported, got %v", err)
+	}
+}
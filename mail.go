@@ -0,0 +1,198 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// The way images referenced from a mail template are inlined.
+type InlineMode int
+
+const (
+	// Inline images as related MIME parts referenced by content id.
+	InlineCID InlineMode = iota
+
+	// Inline images as data URIs.
+	InlineDataURI
+)
+
+// An inline attachment of a rendered mail.
+type MailAttachment struct {
+	// The content id by which the attachment is referenced in the HTML.
+	ContentID string
+
+	// The MIME content type of the attachment.
+	ContentType string
+
+	// The path of the asset the attachment originates from.
+	Path string
+
+	// The attachment data.
+	Data []byte
+}
+
+// A rendered HTML mail.
+type Mail struct {
+	// The rendered HTML body.
+	HTML string
+
+	// The inline attachments referenced from the HTML body (InlineCID only).
+	Attachments []MailAttachment
+}
+
+var mailReferenceRegexp = regexp.MustCompile(`(?i)(\b(?:src|background)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// Render the HTML mail template at path p with the given data and inline
+// the embedded images it references. References (src and background
+// attributes) are resolved relative to the template directory, or to the
+// root of the file system when absolute. References which do not resolve to
+// an asset (e.g. remote URLs) are left untouched.
+func RenderMail(fs *FileSystem, p string, data interface{}, mode InlineMode) (*Mail, error) {
+	text, err := fs.ReadFile(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New(path.Base(p)).Parse(string(text))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+
+	mail := &Mail{}
+	cids := make(map[string]string)
+	dir := path.Dir(path.Join("/", p))
+
+	html := mailReferenceRegexp.ReplaceAllStringFunc(buf.String(), func(m string) string {
+		parts := mailReferenceRegexp.FindStringSubmatch(m)
+		quote := parts[2][:1]
+		ref := parts[2][1 : len(parts[2])-1]
+
+		if len(ref) == 0 || strings.Contains(ref, ":") || strings.HasPrefix(ref, "//") {
+			return m
+		}
+
+		ap := ref
+
+		if !path.IsAbs(ap) {
+			ap = path.Join(dir, ap)
+		}
+
+		ap = path.Clean(ap)
+
+		if _, ok := cids[ap]; !ok {
+			data, err := fs.ReadFile(ap)
+
+			if err != nil {
+				return m
+			}
+
+			ctype := mime.TypeByExtension(path.Ext(ap))
+
+			if len(ctype) == 0 {
+				ctype = "application/octet-stream"
+			}
+
+			if mode == InlineDataURI {
+				cids[ap] = "data:" + ctype + ";base64," + base64.StdEncoding.EncodeToString(data)
+			} else {
+				cid := fmt.Sprintf("asset%d@go-assets", len(mail.Attachments)+1)
+
+				mail.Attachments = append(mail.Attachments, MailAttachment{
+					ContentID:   cid,
+					ContentType: ctype,
+					Path:        ap,
+					Data:        data,
+				})
+
+				cids[ap] = "cid:" + cid
+			}
+		}
+
+		return parts[1] + quote + cids[ap] + quote
+	})
+
+	mail.HTML = html
+	return mail, nil
+}
+
+// Write the mail as a multipart/related MIME entity, starting with its
+// Content-Type header. The HTML body is the first part and is followed by
+// the inline attachments. The written entity can be used as the body of a
+// message, after the message headers (From, To, Subject, etc.).
+func (m *Mail) WriteMIME(w io.Writer) error {
+	var buf bytes.Buffer
+
+	mw := multipart.NewWriter(&buf)
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "base64")
+
+	pw, err := mw.CreatePart(h)
+
+	if err != nil {
+		return err
+	}
+
+	writeBase64Lines(pw, []byte(m.HTML))
+
+	for _, a := range m.Attachments {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Type", a.ContentType)
+		h.Set("Content-Transfer-Encoding", "base64")
+		h.Set("Content-ID", "<"+a.ContentID+">")
+		h.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": path.Base(a.Path)}))
+
+		pw, err := mw.CreatePart(h)
+
+		if err != nil {
+			return err
+		}
+
+		writeBase64Lines(pw, a.Data)
+	}
+
+	if err := mw.Close(); err != nil {
+		return err
+	}
+
+	ctype := mime.FormatMediaType("multipart/related", map[string]string{
+		"boundary": mw.Boundary(),
+		"type":     "text/html",
+	})
+
+	if _, err := fmt.Fprintf(w, "Content-Type: %s\r\n\r\n", ctype); err != nil {
+		return err
+	}
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeBase64Lines(w io.Writer, data []byte) {
+	s := base64.StdEncoding.EncodeToString(data)
+
+	for len(s) > 76 {
+		io.WriteString(w, s[:76]+"\r\n")
+		s = s[76:]
+	}
+
+	io.WriteString(w, s+"\r\n")
+}
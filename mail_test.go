@@ -0,0 +1,111 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRenderMail(t *testing.T) {
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/img", FileMode: os.ModeDir | 0755},
+		{Path: "/img/bg.gif", FileMode: 0644, Data: "GIF89a"},
+		{Path: "/mail", FileMode: os.ModeDir | 0755},
+		{Path: "/mail/logo.png", FileMode: 0644, Data: "\x89PNG"},
+		{Path: "/mail/welcome.html", FileMode: 0644, Data: `<p>Hello {{.}}</p>` +
+			`<img src="logo.png"><img src='/img/bg.gif'>` +
+			`<img src="https://example.com/remote.png"><img src="missing.png">` +
+			`<table background="logo.png"></table>`},
+	}, "")
+
+	m, err := RenderMail(fs, "/mail/welcome.html", "<Bob>", InlineCID)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Attachments) != 2 || m.Attachments[0].Path != "/mail/logo.png" || m.Attachments[1].Path != "/img/bg.gif" {
+		t.Fatalf("expected logo.png and bg.gif to be attached once, got %+v", m.Attachments)
+	}
+
+	if m.Attachments[0].ContentType != "image/png" || string(m.Attachments[0].Data) != "\x89PNG" {
+		t.Errorf("unexpected attachment %+v", m.Attachments[0])
+	}
+
+	for _, expected := range []string{
+		"Hello &lt;Bob&gt;",
+		`src="cid:asset1@go-assets"`,
+		`src='cid:asset2@go-assets'`,
+		`background="cid:asset1@go-assets"`,
+		`src="https://example.com/remote.png"`,
+		`src="missing.png"`,
+	} {
+		if !strings.Contains(m.HTML, expected) {
+			t.Errorf("expected HTML to contain %s, got %s", expected, m.HTML)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := m.WriteMIME(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	header, body, _ := strings.Cut(buf.String(), "\r\n\r\n")
+	mediaType, params, err := mime.ParseMediaType(strings.TrimPrefix(header, "Content-Type: "))
+
+	if err != nil || mediaType != "multipart/related" {
+		t.Fatalf("expected multipart/related, got %q (%v)", header, err)
+	}
+
+	rd := multipart.NewReader(strings.NewReader(body), params["boundary"])
+
+	var cids []string
+
+	for i := 0; ; i++ {
+		part, err := rd.NextPart()
+
+		if err != nil {
+			break
+		}
+
+		data, _ := ioutil.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+
+		if i == 0 {
+			if string(data) != m.HTML {
+				t.Errorf("expected HTML as first part, got %q", data)
+			}
+		} else {
+			cids = append(cids, part.Header.Get("Content-ID"))
+
+			if !bytes.Equal(data, m.Attachments[i-1].Data) {
+				t.Errorf("expected attachment data %q, got %q", m.Attachments[i-1].Data, data)
+			}
+		}
+	}
+
+	if strings.Join(cids, " ") != "<asset1@go-assets> <asset2@go-assets>" {
+		t.Errorf("expected attachments with content ids, got %v", cids)
+	}
+
+	// Data URIs do not need attachments
+	m, err = RenderMail(fs, "/mail/welcome.html", "Bob", InlineDataURI)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.Attachments) != 0 || !strings.Contains(m.HTML, `src="data:image/png;base64,`+base64.StdEncoding.EncodeToString([]byte("\x89PNG"))+`"`) {
+		t.Errorf("expected images as data URIs, got %s", m.HTML)
+	}
+
+	if _, err := RenderMail(fs, "/mail/missing.html", nil, InlineCID); !os.IsNotExist(err) {
+		t.Errorf("expected missing template to not exist, got %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"time"
+)
+
+// The manifest of the assets of a generator, see Generator.WriteManifest.
+type Manifest struct {
+	// The embedded files, sorted by file system and path.
+	Files []ManifestEntry `json:"files"`
+}
+
+// A file listed in a Manifest.
+type ManifestEntry struct {
+	// The name of the variable holding the file system of the file.
+	FileSystem string `json:"file_system"`
+
+	// The path of the file in the generated file system, which is its
+	// fingerprinted path for fingerprinted files (see Generator.Fingerprint).
+	Path string `json:"path"`
+
+	// The path of the file before fingerprinting, for fingerprinted files.
+	OriginalPath string `json:"original_path,omitempty"`
+
+	// The raw size of the file in bytes.
+	Size int64 `json:"size"`
+
+	// The size of the file in bytes as stored in the generated file, if
+	// stored compressed.
+	CompressedSize int64 `json:"compressed_size,omitempty"`
+
+	// The hex encoded SHA-256 digest of the raw file data.
+	SHA256 string `json:"sha256"`
+
+	// The modification time of the file (in RFC 3339 format), unless
+	// omitted (see Generator.OmitMTime).
+	Mtime string `json:"mtime,omitempty"`
+
+	// The content type of the file as derived from its extension, if known.
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Write a JSON manifest listing the files embedded by Write, for deployment
+// tooling and CI pipelines inspecting the contents of the generated file.
+// Private assets (see PrivateTag) and test-only assets (see TestOnly) are
+// left out.
+func (x *Generator) WriteManifest(wr io.Writer) error {
+	manifest, err := x.Manifest()
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(append(data, '\n'))
+	return err
+}
+
+// Get the manifest of the files embedded by Write, see WriteManifest.
+func (x *Generator) Manifest() (*Manifest, error) {
+	maxModTime, err := x.maxModTime()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Manifest{Files: []ManifestEntry{}}
+
+	for _, g := range x.fileSystems() {
+		variableName := g.VariableName
+
+		if len(variableName) == 0 {
+			variableName = "Assets"
+		}
+
+		var paths []string
+
+		for _, k := range g.filePaths() {
+			if !containsString(g.tags(k), PrivateTag) {
+				paths = append(paths, k)
+			}
+		}
+
+		entries := make([]ManifestEntry, len(paths))
+
+		err := g.parallel(len(paths), func(i int) error {
+			k := paths[i]
+			f := g.fsFilesMap[k]
+			vp, _ := g.virtualPath(k)
+
+			data, err := g.read(k)
+
+			if err != nil {
+				return err
+			}
+
+			stored, err := g.storedData(k)
+
+			if err != nil {
+				return err
+			}
+
+			sum := sha256.Sum256(data)
+
+			entry := ManifestEntry{
+				FileSystem:  variableName,
+				Path:        vp,
+				Size:        int64(len(data)),
+				SHA256:      hex.EncodeToString(sum[:]),
+				ContentType: mime.TypeByExtension(path.Ext(vp)),
+			}
+
+			if len(stored.fingerprint) != 0 {
+				entry.Path = fingerprintPath(vp, stored.fingerprint)
+				entry.OriginalPath = vp
+			}
+
+			if stored.compressed {
+				entry.CompressedSize = int64(len(stored.data))
+			}
+
+			if mt := g.modTime(f.info, maxModTime); !mt.IsZero() {
+				entry.Mtime = mt.UTC().Format(time.RFC3339)
+			}
+
+			entries[i] = entry
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret.Files = append(ret.Files, entries...)
+	}
+
+	return ret, nil
+}
+
+// Get the map of asset paths to the URLs the assets are served under, see
+// WriteURLs.
+func (x *Generator) urls() (map[string]string, error) {
+	manifest, err := x.Manifest()
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]string, len(manifest.Files))
+
+	for _, e := range manifest.Files {
+		p := e.Path
+
+		if len(e.OriginalPath) != 0 {
+			p = e.OriginalPath
+		}
+
+		ret[p] = path.Join("/", x.URLPrefix, e.Path)
+	}
+
+	return ret, nil
+}
+
+// Write a JSON object mapping the paths of the assets embedded by Write to
+// the URLs they are served under, which include the fingerprints of
+// fingerprinted assets (see Fingerprint) and URLPrefix. This allows frontend
+// builds to reference embedded assets without hardcoding their URLs. Assets
+// of groups are included, private assets are left out.
+func (x *Generator) WriteURLs(wr io.Writer) error {
+	urls, err := x.urls()
+
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(urls, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(append(data, '\n'))
+	return err
+}
+
+// Write a TypeScript module exporting the URL map written by WriteURLs as
+// assets, and the type of its keys as AssetPath.
+func (x *Generator) WriteURLsTypeScript(wr io.Writer) error {
+	urls, err := x.urls()
+
+	if err != nil {
+		return err
+	}
+
+	banner, err := x.banner()
+
+	if err != nil {
+		return err
+	}
+
+	writer := &bytes.Buffer{}
+
+	fmt.Fprint(writer, banner)
+	fmt.Fprintln(writer, "export const assets = {")
+
+	for _, p := range sortedKeys(urls) {
+		// JSON strings are valid TypeScript string literals
+		key, _ := json.Marshal(p)
+		value, _ := json.Marshal(urls[p])
+
+		fmt.Fprintf(writer, "  %s: %s,\n", key, value)
+	}
+
+	fmt.Fprintln(writer, "} as const;")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "export type AssetPath = keyof typeof assets;")
+
+	_, err = wr.Write(writer.Bytes())
+	return err
+}
@@ -0,0 +1,273 @@
+package assets
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A strategy for resolving conflicts when merging file systems, see MergeFS.
+type ConflictStrategy int
+
+const (
+	// Fail the merge when both file systems contain a file at the same path
+	ConflictError ConflictStrategy = iota
+
+	// Keep the file of the first file system
+	ConflictPreferA
+
+	// Keep the file of the second file system
+	ConflictPreferB
+
+	// Keep both files, renaming the file of the second file system by
+	// inserting a number before its extension (e.g. /app.js becomes
+	// /app.1.js)
+	ConflictRename
+)
+
+func (s ConflictStrategy) String() string {
+	switch s {
+	case ConflictError:
+		return "error"
+	case ConflictPreferA:
+		return "prefer-a"
+	case ConflictPreferB:
+		return "prefer-b"
+	case ConflictRename:
+		return "rename"
+	}
+
+	return fmt.Sprintf("ConflictStrategy(%d)", int(s))
+}
+
+// Merge two file systems into a new file system containing the assets of
+// both, for example to present independently generated plugin bundles as a
+// single asset tree. Directories present in both file systems are merged.
+// Any other path present in both file systems (including a file in one and a
+// directory in the other) is a conflict, which is resolved using the given
+// strategy. Asset keys are merged the same way. The data of the assets is
+// shared with a and b, assets keep their compression.
+// File systems with a LocalPath cannot be merged.
+func MergeFS(a *FileSystem, b *FileSystem, strategy ConflictStrategy) (*FileSystem, error) {
+	if len(a.LocalPath) != 0 || len(b.LocalPath) != 0 {
+		return nil, fmt.Errorf("cannot merge file systems with a local path")
+	}
+
+	m := &merger{
+		files:    make(map[string]*File),
+		renamed:  make(map[string]string),
+		strategy: strategy,
+	}
+
+	for _, p := range sortedKeys(a.Files) {
+		if err := m.add(a, p, false); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, p := range sortedKeys(b.Files) {
+		if err := m.add(b, p, true); err != nil {
+			return nil, err
+		}
+	}
+
+	dirs := make(map[string][]string)
+
+	for p, f := range m.files {
+		if f.IsDir() {
+			if _, ok := dirs[p]; !ok {
+				dirs[p] = []string{}
+			}
+		}
+
+		if p != "/" {
+			dir := path.Dir(p)
+			dirs[dir] = append(dirs[dir], path.Base(p))
+		}
+	}
+
+	for _, names := range dirs {
+		sort.Strings(names)
+	}
+
+	ret := NewFileSystem(dirs, m.files, "")
+
+	for _, f := range m.files {
+		f.fs = ret
+	}
+
+	keys, err := m.mergeKeys(a.Keys, b.Keys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ret.Keys = keys
+	return ret, nil
+}
+
+type merger struct {
+	files    map[string]*File
+	strategy ConflictStrategy
+
+	// The assets of the second file system which are not part of the merged
+	// file system, or which were renamed
+	dropped []string
+	renamed map[string]string
+}
+
+// Get the path of an asset of the second file system in the merged file
+// system, taking into account dropped and renamed parent directories.
+func (m *merger) path(p string) (string, bool) {
+	for _, d := range m.dropped {
+		if p == d || strings.HasPrefix(p, d+"/") {
+			return "", false
+		}
+	}
+
+	for dir := p; dir != "/"; dir = path.Dir(dir) {
+		if r, ok := m.renamed[dir]; ok {
+			return r + p[len(dir):], true
+		}
+	}
+
+	return p, true
+}
+
+func (m *merger) add(fs *FileSystem, p string, second bool) error {
+	f := fs.Files[p]
+
+	if second {
+		var ok bool
+
+		if p, ok = m.path(p); !ok {
+			return nil
+		}
+	}
+
+	existing, ok := m.files[p]
+
+	if ok && existing.IsDir() && f.IsDir() {
+		return nil
+	}
+
+	if ok {
+		switch m.strategy {
+		case ConflictPreferA:
+			m.dropped = append(m.dropped, f.Path)
+			return nil
+		case ConflictPreferB:
+			m.remove(p)
+		case ConflictRename:
+			p = m.rename(p)
+			m.renamed[f.Path] = p
+		default:
+			return fmt.Errorf("conflicting assets at %s", p)
+		}
+	}
+
+	// Merged file systems do not know the key, encrypted data is stored
+	// decrypted
+	data, err := f.data()
+
+	if err != nil {
+		return err
+	}
+
+	m.files[p] = &File{
+		Path:     p,
+		FileMode: f.FileMode,
+		Mtime:    f.Mtime,
+		Data:     data,
+		Tags:     f.Tags,
+		Title:    f.Title,
+		Order:    f.Order,
+		Digest:   f.Digest,
+		Checksum: f.Checksum,
+
+		Fingerprinted: f.Fingerprinted,
+	}
+
+	if f.compressed() {
+		m.files[p].Compressed = true
+		m.files[p].Compression = f.compression()
+	}
+
+	return nil
+}
+
+// Remove the asset at p, including all its descendants.
+func (m *merger) remove(p string) {
+	for k := range m.files {
+		if k == p || strings.HasPrefix(k, p+"/") {
+			delete(m.files, k)
+		}
+	}
+}
+
+// Get a path for p which does not exist in the merged file system yet.
+func (m *merger) rename(p string) string {
+	dir, name := path.Split(p)
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	for i := 1; ; i++ {
+		r := path.Join(dir, base+"."+strconv.Itoa(i)+ext)
+
+		if _, ok := m.files[r]; !ok {
+			return r
+		}
+	}
+}
+
+func (m *merger) mergeKeys(a map[string]string, b map[string]string) (map[string]string, error) {
+	if len(a) == 0 && len(b) == 0 {
+		return nil, nil
+	}
+
+	ret := make(map[string]string)
+
+	for key, p := range a {
+		ret[key] = p
+	}
+
+	for _, key := range sortedKeys(b) {
+		p, ok := m.path(b[key])
+
+		if !ok {
+			continue
+		}
+
+		if _, exists := ret[key]; exists {
+			switch m.strategy {
+			case ConflictPreferA:
+				continue
+			case ConflictPreferB:
+			case ConflictRename:
+				for i := 1; exists; i++ {
+					r := key + "." + strconv.Itoa(i)
+					_, exists = ret[r]
+
+					if !exists {
+						key = r
+					}
+				}
+			default:
+				return nil, fmt.Errorf("conflicting asset key %s", key)
+			}
+		}
+
+		ret[key] = p
+	}
+
+	// Drop keys of assets replaced by a directory
+	for key, p := range ret {
+		if _, ok := m.files[p]; !ok {
+			delete(ret, key)
+		}
+	}
+
+	return ret, nil
+}
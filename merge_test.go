@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"testing"
+)
+
+func mergeTestFileSystems() (*FileSystem, *FileSystem) {
+	a := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: 0x800001ed},
+		{Path: "/app.js", FileMode: 0x1a4, Data: "a"},
+		{Path: "/a.css", FileMode: 0x1a4, Data: "a"},
+	}, "")
+
+	b := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: 0x800001ed},
+		{Path: "/app.js", FileMode: 0x1a4, Data: "b"},
+		{Path: "/b.css", FileMode: 0x1a4, Data: "b"},
+	}, "")
+
+	return a, b
+}
+
+func TestMergeFS(t *testing.T) {
+	tests := []struct {
+		strategy ConflictStrategy
+		expected map[string]string
+	}{
+		{ConflictPreferA, map[string]string{"/app.js": "a", "/a.css": "a", "/b.css": "b"}},
+		{ConflictPreferB, map[string]string{"/app.js": "b", "/a.css": "a", "/b.css": "b"}},
+		{ConflictRename, map[string]string{"/app.js": "a", "/app.1.js": "b", "/a.css": "a", "/b.css": "b"}},
+	}
+
+	for _, test := range tests {
+		a, b := mergeTestFileSystems()
+		fs, err := MergeFS(a, b, test.strategy)
+
+		if err != nil {
+			t.Errorf("%s: %s", test.strategy, err)
+			continue
+		}
+
+		if len(fs.Files) != len(test.expected)+1 {
+			t.Errorf("%s: expected %d files, got %d", test.strategy, len(test.expected)+1, len(fs.Files))
+		}
+
+		if len(fs.Dirs["/"]) != len(test.expected) {
+			t.Errorf("%s: unexpected root directory listing %v", test.strategy, fs.Dirs["/"])
+		}
+
+		for p, data := range test.expected {
+			if actual, err := fs.ReadFile(p); err != nil || string(actual) != data {
+				t.Errorf("%s: expected %s to contain %q, got %q (%v)", test.strategy, p, data, actual, err)
+			}
+		}
+	}
+
+	a, b := mergeTestFileSystems()
+
+	if _, err := MergeFS(a, b, ConflictError); err == nil {
+		t.Errorf("expected conflict error")
+	}
+}
+
+func TestMergeFSCompressed(t *testing.T) {
+	a, _ := mergeTestFileSystems()
+	b, err := Snapshot("testdata", func(g *Generator) {
+		g.Compressed = true
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err := MergeFS(a, b, ConflictError)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for p, f := range b.Files {
+		if fs.Files[p].Compressed != f.Compressed {
+			t.Errorf("expected merged %s to keep its compression", p)
+		}
+	}
+
+	if data, err := fs.ReadFile("/static/app.js"); err != nil || string(data) != "console.log(\"hello\");\n" {
+		t.Errorf("unexpected data %q (%v)", data, err)
+	}
+}
@@ -0,0 +1,152 @@
+package assets
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+// The metadata of the assets in a directory, read from the metadata file of
+// the directory (see Generator.MetaFilename).
+type DirMeta struct {
+	// The title of the directory.
+	Title string `json:"title" yaml:"title"`
+
+	// The names of the files in the directory, in navigation order. Files
+	// which are not listed follow the listed files (see
+	// FileSystem.Navigation).
+	Order []string `json:"order" yaml:"order"`
+
+	// Tags assigned to all files in the directory.
+	Tags []string `json:"tags" yaml:"tags"`
+
+	// The metadata of the files in the directory by name.
+	Files map[string]FileMeta `json:"files" yaml:"files"`
+}
+
+// The metadata of a single asset, see DirMeta.
+type FileMeta struct {
+	// The title of the asset.
+	Title string `json:"title" yaml:"title"`
+
+	// Tags assigned to the asset.
+	Tags []string `json:"tags" yaml:"tags"`
+}
+
+// The metadata of an asset collected from the metadata files.
+type assetMeta struct {
+	title string
+	order int
+	tags  []string
+}
+
+// Check whether the file with the given name is a metadata file.
+func (x *Generator) isMetaFile(name string) bool {
+	return len(x.MetaFilename) != 0 && name == x.MetaFilename
+}
+
+// Read the metadata file at filename on disk for the directory at generator
+// path dir.
+func (x *Generator) loadMeta(dir string, filename string) error {
+	unmarshal, ok := ConfigFormats[filepath.Ext(filename)]
+
+	if !ok {
+		return fmt.Errorf("%s: unsupported metadata format %s", filename, filepath.Ext(filename))
+	}
+
+	data, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return err
+	}
+
+	m := &DirMeta{}
+
+	if err := unmarshal(data, m); err != nil {
+		return fmt.Errorf("%s: %s", filename, err)
+	}
+
+	if x.dirMeta == nil {
+		x.dirMeta = make(map[string]*DirMeta)
+	}
+
+	x.dirMeta[dir] = m
+	return nil
+}
+
+// Get the metadata of the asset at generator path k from the metadata files
+// of the asset (for directories) and of its parent directory.
+func (x *Generator) meta(k string) assetMeta {
+	var ret assetMeta
+
+	if m, ok := x.dirMeta[k]; ok {
+		ret.title = m.Title
+	}
+
+	if k == "/" {
+		return ret
+	}
+
+	m, ok := x.dirMeta[path.Dir(k)]
+
+	if !ok {
+		return ret
+	}
+
+	name := path.Base(k)
+	ret.tags = append(ret.tags, m.Tags...)
+
+	if f, ok := m.Files[name]; ok {
+		if len(f.Title) != 0 {
+			ret.title = f.Title
+		}
+
+		ret.tags = append(ret.tags, f.Tags...)
+	}
+
+	for i, n := range m.Order {
+		if n == name {
+			ret.order = i + 1
+			break
+		}
+	}
+
+	return ret
+}
+
+// Get the files in the directory at path dir in navigation order: files with
+// an Order (see DirMeta) first, followed by the other files sorted by name.
+func (f *FileSystem) Navigation(dir string) ([]*File, error) {
+	dir = path.Clean(dir)
+	names, ok := f.Dirs[dir]
+
+	if !ok {
+		return nil, fmt.Errorf("%s: no such directory", dir)
+	}
+
+	ret := make([]*File, 0, len(names))
+
+	for _, name := range names {
+		if fi, ok := f.Files[path.Join(dir, name)]; ok {
+			ret = append(ret, fi)
+		}
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool {
+		a, b := ret[i].Order, ret[j].Order
+
+		if a == 0 || b == 0 {
+			if a != b {
+				return b == 0
+			}
+
+			return ret[i].Path < ret[j].Path
+		}
+
+		return a < b
+	})
+
+	return ret, nil
+}
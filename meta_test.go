@@ -0,0 +1,158 @@
+package assets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMeta(t *testing.T) {
+	dir := t.TempDir()
+	docs := filepath.Join(dir, "docs")
+
+	if err := os.MkdirAll(docs, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"docs/_meta.json": `{
+			"title": "Documentation",
+			"order": ["intro.md", "setup.md"],
+			"tags": ["docs"],
+			"files": {"intro.md": {"title": "Introduction", "tags": ["beginner"]}}
+		}`,
+		"docs/advanced.md": "advanced",
+		"docs/appendix.md": "appendix",
+		"docs/intro.md":    "intro",
+		"docs/setup.md":    "setup",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{MetaFilename: "_meta.json"}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if _, ok := fs.Files["/docs/_meta.json"]; ok {
+		t.Errorf("expected metadata file to not be an asset")
+	}
+
+	if title := fs.Files["/docs"].Title; title != "Documentation" {
+		t.Errorf("expected directory title %q, got %q", "Documentation", title)
+	}
+
+	intro := fs.Files["/docs/intro.md"]
+
+	if intro.Title != "Introduction" || !reflect.DeepEqual(intro.Tags, []string{"beginner", "docs"}) {
+		t.Errorf("unexpected metadata of /docs/intro.md: %q %v", intro.Title, intro.Tags)
+	}
+
+	nav, err := fs.Navigation("/docs")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+
+	for _, f := range nav {
+		paths = append(paths, f.Path)
+	}
+
+	expected := []string{"/docs/intro.md", "/docs/setup.md", "/docs/advanced.md", "/docs/appendix.md"}
+
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected navigation %v, got %v", expected, paths)
+	}
+}
+
+func TestMetaYAML(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"_meta.yaml": "title: Documentation\norder:\n- setup.md\nfiles:\n  intro.md:\n    title: Introduction\n    tags: [beginner]\n",
+		"intro.md":   "intro",
+		"setup.md":   "setup",
+	}
+
+	for name, data := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{MetaFilename: "_meta.yaml"}
+
+	if err := g.AddRoot(Root{Dir: dir, Mount: "/docs"}); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if _, ok := fs.Files["/docs/_meta.yaml"]; ok {
+		t.Errorf("expected metadata file to not be an asset")
+	}
+
+	if title := fs.Files["/docs"].Title; title != "Documentation" {
+		t.Errorf("expected directory title %q, got %q", "Documentation", title)
+	}
+
+	intro := fs.Files["/docs/intro.md"]
+
+	if intro.Title != "Introduction" || !reflect.DeepEqual(intro.Tags, []string{"beginner"}) {
+		t.Errorf("unexpected metadata of /docs/intro.md: %q %v", intro.Title, intro.Tags)
+	}
+
+	nav, err := fs.Navigation("/docs")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(nav) != 2 || nav[0].Path != "/docs/setup.md" {
+		t.Errorf("expected /docs/setup.md to come first, got %v", nav)
+	}
+}
+
+func TestMetaUnsupportedFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "_meta.toml"), []byte("title = \"Documentation\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{MetaFilename: "_meta.toml"}
+
+	// TOML requires registering an unmarshal function in ConfigFormats
+	err := g.AddRoot(Root{Dir: dir})
+
+	if err == nil {
+		_, err = g.FileSystem()
+	}
+
+	if err == nil {
+		t.Errorf("expected an unregistered metadata format to fail")
+	}
+}
@@ -0,0 +1,500 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// A minifier of the contents of text assets, see Minifiers.
+type Minifier func(data []byte) ([]byte, error)
+
+// The minifiers applied by Generator.Minify, by file extension. The built-in
+// minifiers are conservative: they remove comments and collapse whitespace,
+// but do not rewrite code. JavaScript is only minified by the built-in
+// minifier for the files matching Generator.MinifyJS. Register a minifier to
+// replace or extend them, for example using github.com/tdewolff/minify:
+//
+//	m := minify.New()
+//	m.AddFunc("text/javascript", js.Minify)
+//
+//	assets.Minifiers[".js"] = func(data []byte) ([]byte, error) {
+//		return m.Bytes("text/javascript", data)
+//	}
+var Minifiers = map[string]Minifier{
+	".css":  minifyCSS,
+	".htm":  minifyHTML,
+	".html": minifyHTML,
+	".json": minifyJSON,
+}
+
+// Write a pending whitespace separator, collapsing runs of whitespace to a
+// single newline when they contain a newline, and to a single space
+// otherwise.
+func writeSeparator(buf *bytes.Buffer, space bool, newline bool) {
+	if buf.Len() == 0 {
+		return
+	}
+
+	if newline {
+		buf.WriteByte('\n')
+	} else if space {
+		buf.WriteByte(' ')
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// Get the index following the quoted string starting at data[i], and whether
+// the string is terminated. Unterminated strings extend to the end of the
+// line.
+func quotedEnd(data []byte, i int) (int, bool) {
+	quote := data[i]
+
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1, true
+		case '\n':
+			return i, false
+		}
+	}
+
+	return len(data), false
+}
+
+// Minify JSON by removing insignificant whitespace.
+func minifyJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := json.Compact(&buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Minify CSS by removing comments (except for /*! comments, which usually
+// hold licenses) and collapsing whitespace, removing it around braces,
+// semicolons, commas and child combinators.
+func minifyCSS(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+
+	space := false
+
+	// Whitespace is insignificant next to these characters
+	tight := func(c byte) bool {
+		return c == '{' || c == '}' || c == ';' || c == ',' || c == '>'
+	}
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if c == '/' && i+1 < len(data) && data[i+1] == '*' {
+			end := bytes.Index(data[i+2:], []byte("*/"))
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+
+			comment := data[i : i+2+end+2]
+			i += len(comment) - 1
+
+			if !bytes.HasPrefix(comment, []byte("/*!")) {
+				space = true
+				continue
+			}
+
+			writeSeparator(&buf, space, false)
+			buf.Write(comment)
+
+			space = false
+			continue
+		}
+
+		if isSpace(c) {
+			space = true
+			continue
+		}
+
+		if space && buf.Len() != 0 && !tight(buf.Bytes()[buf.Len()-1]) && !tight(c) {
+			buf.WriteByte(' ')
+		}
+
+		space = false
+
+		// The last declaration of a block needs no semicolon
+		if c == '}' && buf.Len() != 0 && buf.Bytes()[buf.Len()-1] == ';' {
+			buf.Truncate(buf.Len() - 1)
+		}
+
+		if c == '"' || c == '\'' {
+			end, _ := quotedEnd(data, i)
+			buf.Write(data[i:end])
+
+			i = end - 1
+			continue
+		}
+
+		buf.WriteByte(c)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// The elements whose contents are copied as is by minifyHTML.
+var htmlVerbatimElements = []string{"script", "style", "pre", "textarea"}
+
+// Minify HTML by removing comments (except for conditional comments) and
+// collapsing whitespace in text. Tags, template actions ({{ ... }}) and the
+// contents of script, style, pre and textarea elements are copied as is.
+func minifyHTML(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+
+	space, newline := false, false
+
+	for i := 0; i < len(data); {
+		c := data[i]
+
+		if isSpace(c) {
+			space = true
+			newline = newline || c == '\n'
+
+			i++
+			continue
+		}
+
+		if bytes.HasPrefix(data[i:], []byte("<!--")) && !bytes.HasPrefix(data[i:], []byte("<!--[")) {
+			end := bytes.Index(data[i+4:], []byte("-->"))
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+
+			i += 4 + end + 3
+			continue
+		}
+
+		writeSeparator(&buf, space, newline)
+		space, newline = false, false
+
+		var end int
+
+		if bytes.HasPrefix(data[i:], []byte("{{")) {
+			end = bytes.Index(data[i:], []byte("}}"))
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated template action")
+			}
+
+			end += i + 2
+		} else if c == '<' && i+1 < len(data) && isTagStart(data[i+1]) {
+			end = htmlTagEnd(data, i)
+
+			if name := htmlTagName(data[i:end]); containsString(htmlVerbatimElements, name) {
+				// Copy the contents up to the closing tag
+				close := bytes.Index(bytes.ToLower(data[end:]), []byte("</"+name))
+
+				if close < 0 {
+					end = len(data)
+				} else {
+					end += close
+				}
+			}
+		} else {
+			end = i + 1
+
+			for end < len(data) && !isSpace(data[end]) && data[end] != '<' && data[end] != '{' {
+				end++
+			}
+		}
+
+		buf.Write(data[i:end])
+		i = end
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isTagStart(c byte) bool {
+	return c == '/' || c == '!' || c == '?' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Get the index following the tag starting at data[i].
+func htmlTagEnd(data []byte, i int) int {
+	for i++; i < len(data); i++ {
+		switch data[i] {
+		case '"', '\'':
+			for q := data[i]; i+1 < len(data) && data[i+1] != q; {
+				i++
+			}
+
+			i++
+		case '>':
+			return i + 1
+		}
+	}
+
+	return len(data)
+}
+
+// Get the lower case name of the opening tag tag, or an empty string for
+// closing tags, comments and declarations.
+func htmlTagName(tag []byte) string {
+	end := 1
+
+	for end < len(tag) && !isSpace(tag[end]) && tag[end] != '>' && tag[end] != '/' {
+		end++
+	}
+
+	return string(bytes.ToLower(tag[1:end]))
+}
+
+// The keywords after which a slash starts a regular expression.
+var jsRegexpKeywords = []string{"return", "typeof", "case", "do", "else", "in", "instanceof", "new", "delete", "void", "throw", "yield", "await", "of"}
+
+func isJSWordByte(c byte) bool {
+	return c == '_' || c == '$' || c == '\\' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// The keywords whose parenthesized expression is followed by a statement,
+// which may start with a regular expression.
+var jsStatementKeywords = []string{"if", "while", "for", "with"}
+
+// Minify JavaScript by removing comments (except for /*! comments, which
+// usually hold licenses) and collapsing whitespace. Line breaks are kept,
+// such that automatic semicolon insertion is not affected. Like jsmin, a
+// slash is taken to start a regular expression unless it follows a value (an
+// identifier, a property name, a literal, a postfix increment or decrement,
+// or a closing parenthesis or bracket). The parentheses of if, while, for and
+// with are followed by a statement rather than a value. Without parsing, this
+// cannot tell all regular expressions from divisions, which is why the
+// JavaScript minifier is opt-in, see Generator.MinifyJS.
+func minifyJS(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Grow(len(data))
+
+	i := 0
+
+	// Keep the interpreter directive of scripts
+	if bytes.HasPrefix(data, []byte("#!")) {
+		if i = bytes.IndexByte(data, '\n'); i < 0 {
+			i = len(data)
+		}
+
+		buf.Write(data[:i])
+	}
+
+	space, newline := false, false
+	regexp := true
+
+	// Whether each open brace starts a template literal substitution
+	var braces []bool
+
+	// Whether each open parenthesis follows a statement keyword, the last
+	// word and whether the last token was a property access
+	var parens []bool
+	word, property := "", false
+
+	for i < len(data) {
+		c := data[i]
+
+		if c == '/' && i+1 < len(data) && data[i+1] == '/' {
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+
+			continue
+		}
+
+		if c == '/' && i+1 < len(data) && data[i+1] == '*' {
+			end := bytes.Index(data[i+2:], []byte("*/"))
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+
+			comment := data[i : i+2+end+2]
+			i += len(comment)
+
+			if !bytes.HasPrefix(comment, []byte("/*!")) {
+				space = true
+				newline = newline || bytes.IndexByte(comment, '\n') >= 0
+				continue
+			}
+
+			writeSeparator(&buf, space, newline)
+			buf.Write(comment)
+
+			space, newline = false, false
+			continue
+		}
+
+		if isSpace(c) {
+			space = true
+			newline = newline || c == '\n'
+
+			i++
+			continue
+		}
+
+		writeSeparator(&buf, space, newline)
+		space, newline = false, false
+
+		start := i
+		lastWord, lastProperty := word, property
+		word, property = "", false
+
+		switch {
+		case c == '"' || c == '\'':
+			var ok bool
+
+			if i, ok = quotedEnd(data, i); !ok {
+				return nil, fmt.Errorf("unterminated string")
+			}
+
+			regexp = false
+		case c == '`' || (c == '}' && len(braces) != 0 && braces[len(braces)-1]):
+			if c == '}' {
+				braces = braces[:len(braces)-1]
+			}
+
+			end, substitution := jsTemplateEnd(data, i+1)
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated template literal")
+			}
+
+			if substitution {
+				braces = append(braces, true)
+			}
+
+			i = end
+			regexp = substitution
+		case c == '{':
+			braces = append(braces, false)
+			i++
+			regexp = true
+		case c == '}':
+			if len(braces) != 0 {
+				braces = braces[:len(braces)-1]
+			}
+
+			i++
+			regexp = true
+		case c == '/' && regexp:
+			end := jsRegexpEnd(data, i+1)
+
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated regular expression")
+			}
+
+			i = end
+			regexp = false
+		case isJSWordByte(c):
+			for i < len(data) && isJSWordByte(data[i]) {
+				if data[i] == '\\' {
+					i++
+				}
+
+				i++
+			}
+
+			if i > len(data) {
+				i = len(data)
+			}
+
+			word = string(data[start:i])
+			regexp = !lastProperty && containsString(jsRegexpKeywords, word)
+		case c == '(':
+			parens = append(parens, containsString(jsStatementKeywords, lastWord))
+			i++
+			regexp = true
+		case c == ')':
+			regexp = false
+
+			if len(parens) != 0 {
+				regexp = parens[len(parens)-1]
+				parens = parens[:len(parens)-1]
+			}
+
+			i++
+		case c == '.' && bytes.HasPrefix(data[i:], []byte("...")):
+			i += 3
+			regexp = true
+		case c == '.':
+			i++
+			property = true
+		case (c == '+' || c == '-') && i+1 < len(data) && data[i+1] == c:
+			// Increments and decrements are followed by a division when
+			// postfix, and by an operand when prefix
+			i += 2
+			regexp = false
+		default:
+			i++
+			regexp = c != ']'
+		}
+
+		buf.Write(data[start:i])
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Get the index following the part of a template literal starting at
+// data[i], which ends with either a backquote or the start of a substitution,
+// and whether it ends with a substitution. Returns -1 for unterminated
+// template literals.
+func jsTemplateEnd(data []byte, i int) (int, bool) {
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '`':
+			return i + 1, false
+		case '$':
+			if i+1 < len(data) && data[i+1] == '{' {
+				return i + 2, true
+			}
+		}
+	}
+
+	return -1, false
+}
+
+// Get the index following the regular expression literal (including its
+// flags) whose body starts at data[i]. Returns -1 for unterminated regular
+// expressions.
+func jsRegexpEnd(data []byte, i int) int {
+	class := false
+
+	for ; i < len(data); i++ {
+		switch data[i] {
+		case '\\':
+			i++
+		case '\n':
+			return -1
+		case '[':
+			class = true
+		case ']':
+			class = false
+		case '/':
+			if class {
+				continue
+			}
+
+			for i++; i < len(data) && isJSWordByte(data[i]); i++ {
+			}
+
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"body {\n\tmargin: 0;\n}\n", "body{margin: 0}"},
+		{"/* comment */\na > b, c  d { content: \"a  /* b */\"; }", "a>b,c d{content: \"a  /* b */\"}"},
+		{"/*! license */\np { width: calc(1px + 2px) }", "/*! license */ p{width: calc(1px + 2px)}"},
+	}
+
+	for _, test := range tests {
+		data, err := minifyCSS([]byte(test.input))
+
+		if err != nil || string(data) != test.expected {
+			t.Errorf("expected %q, got %q (%v)", test.expected, data, err)
+		}
+	}
+
+	if _, err := minifyCSS([]byte("a { /* b }")); err == nil {
+		t.Errorf("expected unterminated comment to fail")
+	}
+}
+
+func TestMinifyHTML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"<p>\n  Hello   <b>world</b>\n</p>\n", "<p>\nHello <b>world</b>\n</p>"},
+		{"<!-- comment --><a title=\"a  b\">x</a>", "<a title=\"a  b\">x</a>"},
+		{"<pre>\n  a\n    b\n</pre>  <textarea> x  </textarea>", "<pre>\n  a\n    b\n</pre> <textarea> x  </textarea>"},
+		{"<script>\n  // <b>  x </b>\n</script>", "<script>\n  // <b>  x </b>\n</script>"},
+		{"<!--[if IE]><p>IE</p><![endif]-->", "<!--[if IE]><p>IE</p><![endif]-->"},
+		{"<p>{{printf \"a  b\"}}  c</p>", "<p>{{printf \"a  b\"}} c</p>"},
+	}
+
+	for _, test := range tests {
+		data, err := minifyHTML([]byte(test.input))
+
+		if err != nil || string(data) != test.expected {
+			t.Errorf("expected %q, got %q (%v)", test.expected, data, err)
+		}
+	}
+}
+
+func TestMinifyJS(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"console.log(\"hello\");\n", "console.log(\"hello\");"},
+		{"// comment\nvar a = 1 // one\n  var b = a / 2 /* half */\n", "var a = 1\nvar b = a / 2"},
+		{"var s = 'a // b', r = /\\/* [/]/g;\nreturn /x/.test(s)", "var s = 'a // b', r = /\\/* [/]/g;\nreturn /x/.test(s)"},
+		{"var t = `a  ${ {b: 1}.b /* c */ } // d`;", "var t = `a  ${ {b: 1}.b } // d`;"},
+		{"#!/usr/bin/env node\n\n  run()", "#!/usr/bin/env node\nrun()"},
+		{"/*! license */\nf()", "/*! license */\nf()"},
+		{"x = i++ / 2; s = \"/*\"", "x = i++ / 2; s = \"/*\""},
+		{"x = a.return / 2; s = '/*'", "x = a.return / 2; s = '/*'"},
+		{"if (x) /\\/\\*/.test(s) /* c */", "if (x) /\\/\\*/.test(s)"},
+		{"f(...[1]) / 2 /* c */", "f(...[1]) / 2"},
+	}
+
+	for _, test := range tests {
+		data, err := minifyJS([]byte(test.input))
+
+		if err != nil || string(data) != test.expected {
+			t.Errorf("expected %q, got %q (%v)", test.expected, data, err)
+		}
+	}
+
+	for _, invalid := range []string{"var s = 'a", "var t = `a ${b}", "x = /a", "/* a"} {
+		if _, err := minifyJS([]byte(invalid)); err == nil {
+			t.Errorf("expected %q to fail", invalid)
+		}
+	}
+}
+
+func TestMinify(t *testing.T) {
+	g := &Generator{Minify: true, StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	g.addVirtual("/testdata/static/lib.min.js", 0644, time.Now(), []byte("a  =  1\n"))
+	g.addVirtual("/testdata/static/a.js", 0644, time.Now(), []byte("a  =  1\n"))
+	g.addVirtual("/testdata/static/b.js", 0644, time.Now(), []byte("b  =  1\n"))
+	g.addVirtual("/testdata/static/invalid.js", 0644, time.Now(), []byte("b  =  '\n"))
+	g.MinifyJS = []string{"a.js", "invalid.js"}
+
+	fs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := fs.ReadFile("/static/css/app.css"); string(data) != "body{margin: 0}" {
+		t.Errorf("expected minified css, got %q", data)
+	}
+
+	if data, _ := fs.ReadFile("/static/lib.min.js"); string(data) != "a  =  1\n" {
+		t.Errorf("expected minified file to be left alone, got %q", data)
+	}
+
+	// Scripts are only minified when they match MinifyJS, and scripts which
+	// cannot be minified are embedded as is
+	for p, expected := range map[string]string{"/static/a.js": "a = 1", "/static/b.js": "b  =  1\n", "/static/invalid.js": "b  =  '\n"} {
+		if data, err := fs.ReadFile(p); err != nil || string(data) != expected {
+			t.Errorf("expected %s to be %q, got %q (%v)", p, expected, data, err)
+		}
+	}
+
+	expected, _ := ioutil.ReadFile("testdata/templates/index.html")
+
+	if data, _ := fs.ReadFile("/templates/index.html"); !bytes.Equal(bytes.TrimSpace(data), bytes.TrimSpace(expected)) {
+		t.Errorf("expected %q, got %q", expected, data)
+	}
+}
@@ -0,0 +1,96 @@
+package assets
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	// Test vectors from the unicode normalization tests
+	// (NormalizationTest.txt), restricted to the supported scripts. Each
+	// vector holds the NFD (c3) and NFC (c2) columns.
+	tests := []struct {
+		nfd string
+		nfc string
+	}{
+		{"\u0044\u0307", "\u1E0A"},
+		{"\u0044\u0323", "\u1E0C"},
+		{"\u0044\u0323\u0307", "\u1E0C\u0307"},
+		{"\u0044\u031B\u0307", "\u1E0A\u031B"},
+		{"\u0044\u031B\u0323", "\u1E0C\u031B"},
+		{"\u0044\u031B\u0323\u0307", "\u1E0C\u031B\u0307"},
+		{"\u0045\u0300", "\u00C8"},
+		{"\u0045\u0304", "\u0112"},
+		{"\u0045\u0304\u0300", "\u1E14"},
+		{"\u0045\u0304\u0300\u0304", "\u1E14\u0304"},
+		{"\u0045\u0300\u0304", "\u00C8\u0304"},
+		{"\u0061\u0323\u0302", "\u1EAD"},
+		{"\u03B1\u0313\u0300\u0345", "\u1F82"},
+		{"\u03C9\u0342\u0345", "\u1FF7"},
+		{"\u0438\u0306", "\u0439"},
+		{"\u0415\u0308", "\u0401"},
+		{"\u1100\u1161", "\uAC00"},
+		{"\u1100\u1161\u11A8", "\uAC01"},
+		{"\u1111\u1171\u11B6", "\uD4DB"},
+		{"\u1100\u1100\u1161\u11A8", "\u1100\uAC01"},
+		{"\u1100\u1100\u1161\u11A8\u11A8", "\u1100\uAC01\u11A8"},
+	}
+
+	for _, test := range tests {
+		if ret := compose(test.nfd); ret != test.nfc {
+			t.Errorf("expected %+q to compose to %+q, but got %+q", test.nfd, test.nfc, ret)
+		}
+
+		if ret := compose(test.nfc); ret != test.nfc {
+			t.Errorf("expected %+q to be left as is, but got %+q", test.nfc, ret)
+		}
+	}
+
+	// Marks which are not in canonical order are reordered first
+	if ret := compose("\u0044\u0307\u0323"); ret != "\u1E0C\u0307" {
+		t.Errorf("expected reordered marks to compose to %+q, but got %+q", "\u1E0C\u0307", ret)
+	}
+
+	// Other scripts are left as is
+	for _, s := range []string{"app.js", "\u0915\u093C", "\u05D1\u05BC"} {
+		if ret := compose(s); ret != s {
+			t.Errorf("expected %+q to be left as is, but got %+q", s, ret)
+		}
+	}
+}
+
+func TestNormalization(t *testing.T) {
+	dir := t.TempDir()
+	nfd := "cafe\u0301.txt"
+
+	if err := ioutil.WriteFile(filepath.Join(dir, nfd), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		normalization Normalization
+		expected      string
+	}{
+		{NormalizeComposed, "/caf\u00E9.txt"},
+		{NormalizeNone, "/" + nfd},
+	}
+
+	for _, test := range tests {
+		g := &Generator{Normalization: test.normalization}
+
+		if err := g.AddRoot(Root{Dir: dir}); err != nil {
+			t.Fatal(err)
+		}
+
+		fs, err := g.FileSystem()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := fs.Files[test.expected]; !ok {
+			t.Errorf("expected %+q to exist", test.expected)
+		}
+	}
+}
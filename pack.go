@@ -0,0 +1,512 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// The magic bytes at the start of pack files, see Generator.PackFile.
+const packMagic = "GOASPACK"
+
+// The size of the trailer following a pack appended to an executable, which
+// consists of the little endian size of the pack and packMagic.
+const packTrailerSize = 8 + len(packMagic)
+
+// The size of the pack header, consisting of packMagic and the digest.
+const packHeaderSize = len(packMagic) + sha256.Size
+
+// The asset data written to a pack file, see Generator.PackFile. A pack file
+// consists of packMagic, the SHA-256 digest of the data and the data of all
+// assets, which are referenced by offset and length from the generated code.
+type packWriter struct {
+	// The data of the pack, spooled to a temporary file such that it is not
+	// held in memory, its size and its digest
+	data *os.File
+	size int64
+	hash hash.Hash
+
+	// The pack file path written to the generated code, and the name of the
+	// constant holding the digest of the data
+	path       string
+	digestName string
+
+	// Whether the pack is loaded from the end of the executable, see
+	// Generator.AppendPack
+	appended bool
+}
+
+// Add data to the pack, returning its offset.
+func (p *packWriter) add(data []byte) (int64, error) {
+	if p.data == nil {
+		fd, err := ioutil.TempFile("", "go-assets-pack")
+
+		if err != nil {
+			return 0, err
+		}
+
+		p.data = fd
+	}
+
+	if _, err := p.data.Write(data); err != nil {
+		return 0, err
+	}
+
+	p.sum().Write(data)
+
+	offset := p.size
+	p.size += int64(len(data))
+
+	return offset, nil
+}
+
+// Get the running SHA-256 digest of the data in the pack.
+func (p *packWriter) sum() hash.Hash {
+	if p.hash == nil {
+		p.hash = sha256.New()
+	}
+
+	return p.hash
+}
+
+// Get the hex encoded SHA-256 digest of the data in the pack.
+func (p *packWriter) digest() string {
+	return hex.EncodeToString(p.sum().Sum(nil))
+}
+
+// Write the pack to the file at path.
+func (p *packWriter) writeFile(path string) error {
+	return writeFileAtomicFunc(path, func(w io.Writer) error {
+		if _, err := io.WriteString(w, packMagic); err != nil {
+			return err
+		}
+
+		if _, err := w.Write(p.sum().Sum(nil)); err != nil {
+			return err
+		}
+
+		if p.data == nil {
+			return nil
+		}
+
+		if _, err := p.data.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		_, err := io.Copy(w, p.data)
+		return err
+	})
+}
+
+// Remove the temporary file holding the data of the pack.
+func (p *packWriter) remove() {
+	if p.data != nil {
+		p.data.Close()
+		os.Remove(p.data.Name())
+		p.data = nil
+	}
+}
+
+// The verified data of the packs loaded by file systems, by their digest.
+// All file systems of a generated file (see Generator.Group) refer to the same
+// pack, which is opened and verified once and shared by them for the lifetime
+// of the program. The asset data is read from the open pack file on demand,
+// such that packs are not held in memory.
+var loadedPacks = struct {
+	sync.Mutex
+	data map[string]*io.SectionReader
+}{data: make(map[string]*io.SectionReader)}
+
+// Get the shared data of the pack with the given digest, storing data as the
+// shared data if the pack has not been loaded before.
+func sharePack(digest string, data *io.SectionReader) *io.SectionReader {
+	loadedPacks.Lock()
+	defer loadedPacks.Unlock()
+
+	if shared, ok := loadedPacks.data[digest]; ok {
+		return shared
+	}
+
+	loadedPacks.data[digest] = data
+	return data
+}
+
+// Use the data of the pack of the file system if another file system has
+// already loaded it.
+func (f *FileSystem) loadSharedPack(path string) bool {
+	loadedPacks.Lock()
+	data, ok := loadedPacks.data[f.packDigest]
+	loadedPacks.Unlock()
+
+	if ok {
+		f.packErr = f.usePack(path, data)
+	}
+
+	return ok
+}
+
+// Create a new file system from a table of file entries whose data is stored
+// in a pack file (see Generator.PackFile), referenced by the Offset and
+// Length of each entry. A relative pack path is resolved against the
+// directory of the executable, falling back to the working directory. The
+// pack must have the given (hex encoded SHA-256) digest. Failing to load the
+// pack does not panic, instead reading assets fails with the load error until
+// the pack is loaded successfully using LoadPack.
+func NewPackFileSystem(version int, entries []FileEntry, packPath string, digest string) *FileSystem {
+	fs := NewVersionedFileSystem(version, entries, "")
+	fs.packDigest = digest
+
+	if !fs.loadSharedPack(packPath) {
+		fs.packErr = fs.LoadPack(resolvePackPath(packPath))
+	}
+
+	return fs
+}
+
+// Create a new file system like NewPackFileSystem, loading the pack from the
+// end of the executable, where it has been appended using AppendPack (see
+// Generator.AppendPack). When the executable has no appended pack with the
+// given digest (e.g. during development), the pack is loaded from packPath
+// instead.
+func NewAppendedPackFileSystem(version int, entries []FileEntry, packPath string, digest string) *FileSystem {
+	fs := NewVersionedFileSystem(version, entries, "")
+	fs.packDigest = digest
+
+	if fs.loadSharedPack(packPath) {
+		return fs
+	}
+
+	exe, err := os.Executable()
+
+	if err == nil {
+		err = fs.loadAppendedPack(exe)
+	}
+
+	if err != nil {
+		if perr := fs.LoadPack(resolvePackPath(packPath)); perr != nil {
+			fs.packErr = fmt.Errorf("%s (%s)", err, perr)
+		}
+	}
+
+	return fs
+}
+
+// Get the path of the pack file at p, relative to the executable or the
+// working directory.
+func resolvePackPath(p string) string {
+	if filepath.IsAbs(p) {
+		return p
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		candidate := filepath.Join(filepath.Dir(exe), p)
+
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return p
+}
+
+// Load the asset data of a file system created by NewPackFileSystem from the
+// pack file at path, for example when the pack is installed at a different
+// location than the executable. The pack is verified once and kept open, the
+// data of the assets is read from it when they are read; the pack must
+// therefore not be modified in place while loaded. The pack is shared
+// by all file systems loading it. LoadPack replaces the data of the assets
+// and must therefore be called before the file system is used, e.g. at the
+// start of main; it is not safe to call it while assets are being read.
+func (f *FileSystem) LoadPack(path string) error {
+	fd, data, err := f.readPack(path)
+
+	if err != nil {
+		return err
+	}
+
+	shared := sharePack(f.packDigest, data)
+
+	if shared != data {
+		fd.Close()
+	}
+
+	return f.usePack(path, shared)
+}
+
+// Open and verify the pack file at path, returning the open file and the
+// asset data it holds. The file is closed when the pack cannot be used.
+func (f *FileSystem) readPack(path string) (*os.File, *io.SectionReader, error) {
+	fd, err := os.Open(path)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := fd.Stat()
+
+	if err == nil {
+		var data *io.SectionReader
+
+		if data, err = f.verifyPack(path, io.NewSectionReader(fd, 0, info.Size())); err == nil {
+			return fd, data, nil
+		}
+	}
+
+	fd.Close()
+	return nil, nil, err
+}
+
+// Verify the pack read from the file at path, returning the asset data it
+// holds.
+func (f *FileSystem) verifyPack(path string, pack *io.SectionReader) (*io.SectionReader, error) {
+	digest, data, err := checkPack(path, pack)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if digest != f.packDigest {
+		return nil, fmt.Errorf("%s: asset pack file does not match the generated code", path)
+	}
+
+	return data, nil
+}
+
+// Check the integrity of the pack read from the file at path, returning the
+// hex encoded digest and the asset data it holds. The data is streamed
+// through the hash, rather than read into memory.
+func checkPack(path string, pack *io.SectionReader) (string, *io.SectionReader, error) {
+	var header [packHeaderSize]byte
+
+	if pack.Size() < int64(packHeaderSize) {
+		return "", nil, fmt.Errorf("%s: not an asset pack file", path)
+	}
+
+	if _, err := pack.ReadAt(header[:], 0); err != nil {
+		return "", nil, err
+	}
+
+	if string(header[:len(packMagic)]) != packMagic {
+		return "", nil, fmt.Errorf("%s: not an asset pack file", path)
+	}
+
+	data := io.NewSectionReader(pack, int64(packHeaderSize), pack.Size()-int64(packHeaderSize))
+	h := sha256.New()
+
+	if _, err := io.Copy(h, data); err != nil {
+		return "", nil, err
+	}
+
+	sum := header[len(packMagic):]
+
+	if !bytes.Equal(sum, h.Sum(nil)) {
+		return "", nil, fmt.Errorf("%s: corrupt asset pack file", path)
+	}
+
+	return hex.EncodeToString(sum), data, nil
+}
+
+// Read the pack file at path (see Generator.PackFile) and verify its
+// integrity, returning the hex encoded SHA-256 digest and the size of the
+// asset data it holds. The digest identifies the generated code the pack
+// belongs to. The assets themselves are described by the generated code,
+// load them using ParseFile and LoadPack.
+func ReadPackDigest(path string) (string, int64, error) {
+	fd, err := os.Open(path)
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	defer fd.Close()
+
+	info, err := fd.Stat()
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	digest, data, err := checkPack(path, io.NewSectionReader(fd, 0, info.Size()))
+
+	if err != nil {
+		return "", 0, err
+	}
+
+	return digest, data.Size(), nil
+}
+
+// Use the verified asset data of the pack read from the file at path.
+func (f *FileSystem) usePack(path string, data *io.SectionReader) error {
+	for _, fi := range f.Files {
+		if fi.IsDir() {
+			continue
+		}
+
+		if fi.packOffset < 0 || fi.packLength < 0 || fi.packOffset+fi.packLength > data.Size() {
+			return fmt.Errorf("%s: asset %s is out of range", path, fi.Path)
+		}
+	}
+
+	for _, fi := range f.Files {
+		if !fi.IsDir() {
+			fi.Data = nil
+			fi.pack = data
+		}
+	}
+
+	f.packErr = nil
+	return nil
+}
+
+// Load the asset data from the pack appended to the executable at path. The
+// executable is kept open to read the asset data from.
+func (f *FileSystem) loadAppendedPack(path string) error {
+	fd, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+
+	start, size, err := findAppendedPack(fd, f.packDigest)
+
+	if err == nil && size < 0 {
+		err = fmt.Errorf("no asset pack appended to the executable")
+	}
+
+	if err != nil {
+		fd.Close()
+		return fmt.Errorf("%s: %s", path, err)
+	}
+
+	data, err := f.verifyPack(path, io.NewSectionReader(fd, start, size))
+
+	if err != nil {
+		fd.Close()
+		return err
+	}
+
+	shared := sharePack(f.packDigest, data)
+
+	if shared != data {
+		fd.Close()
+	}
+
+	return f.usePack(path, shared)
+}
+
+// Find the pack with the given digest among the packs appended to the
+// executable fd, returning its offset and size (or -1 if not found).
+// Executables can carry several packs (of several generated files), which
+// are chained by their trailers.
+func findAppendedPack(fd *os.File, digest string) (int64, int64, error) {
+	info, err := fd.Stat()
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end := info.Size()
+
+	var trailer [packTrailerSize]byte
+	var header [packHeaderSize]byte
+
+	for end >= int64(packTrailerSize+packHeaderSize) {
+		if _, err := fd.ReadAt(trailer[:], end-int64(packTrailerSize)); err != nil {
+			return 0, 0, err
+		}
+
+		if string(trailer[8:]) != packMagic {
+			break
+		}
+
+		size := int64(binary.LittleEndian.Uint64(trailer[:8]))
+		start := end - int64(packTrailerSize) - size
+
+		if size < int64(packHeaderSize) || start < 0 {
+			return 0, 0, fmt.Errorf("corrupt appended asset pack")
+		}
+
+		if _, err := fd.ReadAt(header[:], start); err != nil {
+			return 0, 0, err
+		}
+
+		if hex.EncodeToString(header[len(packMagic):]) == digest {
+			return start, size, nil
+		}
+
+		end = start
+	}
+
+	return 0, -1, nil
+}
+
+// Append the pack file at pack (see Generator.PackFile) to the executable at
+// executable, for distributing a single file without compiling the asset
+// data into the executable. File systems generated with Generator.AppendPack
+// load the pack from the end of their executable. The pack is verified before
+// it is appended, appending a pack which has already been appended does
+// nothing. Note that appending data invalidates
+// code signatures, executables must therefore be signed afterwards.
+func AppendPack(executable string, pack string) error {
+	src, err := os.Open(pack)
+
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	info, err := src.Stat()
+
+	if err != nil {
+		return err
+	}
+
+	digest, _, err := checkPack(pack, io.NewSectionReader(src, 0, info.Size()))
+
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.OpenFile(executable, os.O_RDWR|os.O_APPEND, 0)
+
+	if err != nil {
+		return err
+	}
+
+	_, size, err := findAppendedPack(fd, digest)
+
+	if err != nil {
+		fd.Close()
+		return fmt.Errorf("%s: %s", executable, err)
+	}
+
+	if size >= 0 {
+		return fd.Close()
+	}
+
+	var trailer [packTrailerSize]byte
+
+	binary.LittleEndian.PutUint64(trailer[:8], uint64(info.Size()))
+	copy(trailer[8:], packMagic)
+
+	if _, err := io.Copy(fd, io.NewSectionReader(src, 0, info.Size())); err != nil {
+		fd.Close()
+		return err
+	}
+
+	if _, err := fd.Write(trailer[:]); err != nil {
+		fd.Close()
+		return err
+	}
+
+	return fd.Close()
+}
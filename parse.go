@@ -0,0 +1,873 @@
+package assets
+
+import (
+	"encoding/base64"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// Parse a previously generated asset go file and reconstruct the file systems
+// it defines. The returned map contains the file systems by their variable
+// name. See Parse for more information.
+func ParseFile(filename string) (map[string]*FileSystem, error) {
+	src, err := ioutil.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parse(filename, src)
+}
+
+// Parse the source of a previously generated asset go file and reconstruct
+// the file systems it defines. The returned map contains the file systems by
+// their variable name. The source is only parsed, not compiled, and therefore
+// only code as emitted by the Generator is supported.
+func Parse(src []byte) (map[string]*FileSystem, error) {
+	return parse("", src)
+}
+
+type sourceParser struct {
+	fset *token.FileSet
+	vars map[string]ast.Expr
+
+	// The directory pack files are resolved against
+	dir string
+}
+
+func parse(filename string, src []byte) (map[string]*FileSystem, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, src, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p := &sourceParser{
+		fset: fset,
+		vars: make(map[string]ast.Expr),
+		dir:  filepath.Dir(filename),
+	}
+
+	ret := make(map[string]*FileSystem)
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+
+		if !ok || (gen.Tok != token.VAR && gen.Tok != token.CONST) {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			vspec := spec.(*ast.ValueSpec)
+
+			for i, name := range vspec.Names {
+				if i < len(vspec.Values) {
+					p.vars[name.Name] = vspec.Values[i]
+				}
+			}
+		}
+	}
+
+	for name, expr := range p.vars {
+		call, ok := expr.(*ast.CallExpr)
+
+		if !ok {
+			continue
+		}
+
+		var fs *FileSystem
+		var err error
+
+		switch callName(call) {
+		case "NewFileSystem":
+			fs, err = p.fileSystem(call)
+		case "NewFileSystemFromEntries":
+			fs, err = p.fileSystemFromEntries(call.Args)
+		case "NewVersionedFileSystem":
+			fs, err = p.versionedFileSystem(call)
+		case "NewPackFileSystem", "NewAppendedPackFileSystem":
+			fs, err = p.packFileSystem(call)
+		case "newAssetsFileSystem":
+			fs, err = p.selfContainedFileSystem(call)
+		case "NewEmbedFileSystem":
+			fs, err = p.embedFileSystem(call)
+		default:
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret[name] = fs
+	}
+
+	// Apply file system fields assigned in init functions
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+
+		if !ok || fn.Name.Name != "init" || fn.Recv != nil || fn.Body == nil {
+			continue
+		}
+
+		for _, stmt := range fn.Body.List {
+			if err := p.assignment(ret, stmt); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func callName(call *ast.CallExpr) string {
+	switch fn := call.Fun.(type) {
+	case *ast.Ident:
+		return fn.Name
+	case *ast.SelectorExpr:
+		return fn.Sel.Name
+	}
+
+	return ""
+}
+
+func (p *sourceParser) errorf(node ast.Node, format string, args ...interface{}) error {
+	return fmt.Errorf("%s: %s", p.fset.Position(node.Pos()), fmt.Sprintf(format, args...))
+}
+
+func (p *sourceParser) assignment(fss map[string]*FileSystem, stmt ast.Stmt) error {
+	assign, ok := stmt.(*ast.AssignStmt)
+
+	if !ok || len(assign.Lhs) != 1 || len(assign.Rhs) != 1 {
+		return nil
+	}
+
+	sel, ok := assign.Lhs[0].(*ast.SelectorExpr)
+
+	if !ok {
+		return nil
+	}
+
+	ident, ok := sel.X.(*ast.Ident)
+
+	if !ok {
+		return nil
+	}
+
+	fs, ok := fss[ident.Name]
+
+	if !ok {
+		return nil
+	}
+
+	v, err := p.eval(assign.Rhs[0])
+
+	if err != nil {
+		return err
+	}
+
+	switch sel.Sel.Name {
+	case "Compressed":
+		fs.Compressed, ok = v.(bool)
+	case "Compression":
+		fs.Compression, ok = v.(Compression)
+	case "CacheDecompressed":
+		fs.CacheDecompressed, ok = v.(bool)
+	case "Precompressed":
+		fs.Precompressed, ok = v.([]string)
+	case "Keys":
+		fs.Keys, ok = v.(map[string]string)
+	case "Fingerprints":
+		fs.Fingerprints, ok = v.(map[string]string)
+	case "Signature":
+		fs.Signature, ok = v.([]byte)
+	}
+
+	if !ok {
+		return p.errorf(assign, "unexpected value for %s", sel.Sel.Name)
+	}
+
+	return nil
+}
+
+func (p *sourceParser) fileSystem(call *ast.CallExpr) (*FileSystem, error) {
+	if len(call.Args) != 3 {
+		return nil, p.errorf(call, "unexpected number of arguments to NewFileSystem")
+	}
+
+	dirs, err := p.eval(call.Args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	dirsMap, ok := dirs.(map[string][]string)
+
+	if !ok {
+		return nil, p.errorf(call.Args[0], "expected directory map")
+	}
+
+	files, ok := call.Args[1].(*ast.CompositeLit)
+
+	if !ok {
+		return nil, p.errorf(call.Args[1], "expected file map")
+	}
+
+	filesMap := make(map[string]*File)
+
+	for _, elt := range files.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+
+		if !ok {
+			return nil, p.errorf(elt, "expected key value pair")
+		}
+
+		k, err := p.evalString(kv.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f, err := p.file(kv.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		filesMap[k] = f
+	}
+
+	localPath, err := p.evalString(call.Args[2])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileSystem(dirsMap, filesMap, localPath), nil
+}
+
+func (p *sourceParser) versionedFileSystem(call *ast.CallExpr) (*FileSystem, error) {
+	if len(call.Args) != 3 {
+		return nil, p.errorf(call, "unexpected number of arguments to NewVersionedFileSystem")
+	}
+
+	if err := p.version(call.Args[0]); err != nil {
+		return nil, err
+	}
+
+	return p.fileSystemFromEntries(call.Args[1:])
+}
+
+// Parse a file system loading its data from a pack file. The pack file is
+// resolved against the directory of the parsed file. Like at runtime, a
+// missing pack does not fail parsing, reading assets fails instead until the
+// pack is loaded using LoadPack (e.g. from a different location).
+func (p *sourceParser) packFileSystem(call *ast.CallExpr) (*FileSystem, error) {
+	if len(call.Args) != 4 {
+		return nil, p.errorf(call, "unexpected number of arguments to %s", callName(call))
+	}
+
+	if err := p.version(call.Args[0]); err != nil {
+		return nil, err
+	}
+
+	entries, err := p.entries(call.Args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	packPath, err := p.evalString(call.Args[2])
+
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := p.evalString(call.Args[3])
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !filepath.IsAbs(packPath) {
+		packPath = filepath.Join(p.dir, packPath)
+	}
+
+	fs := NewFileSystemFromEntries(entries, "")
+	fs.packDigest = digest
+
+	// Parsed file systems do not share their pack (see LoadPack), such that
+	// the open pack file is released with the file system
+	_, data, err := fs.readPack(packPath)
+
+	if os.IsNotExist(err) {
+		fs.packErr = err
+	} else if err != nil {
+		return nil, err
+	} else if err := fs.usePack(packPath, data); err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// Parse a file system loading its data from an embedded directory. The
+// directory is resolved against the directory of the parsed file.
+func (p *sourceParser) embedFileSystem(call *ast.CallExpr) (*FileSystem, error) {
+	if len(call.Args) != 4 {
+		return nil, p.errorf(call, "unexpected number of arguments to NewEmbedFileSystem")
+	}
+
+	if err := p.version(call.Args[0]); err != nil {
+		return nil, err
+	}
+
+	entries, err := p.entries(call.Args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := p.evalString(call.Args[3])
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		e := &entries[i]
+
+		if len(e.EmbedName) == 0 {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(p.dir, filepath.FromSlash(dir), e.EmbedName))
+
+		if err != nil {
+			return nil, err
+		}
+
+		e.Bytes = data
+	}
+
+	return NewFileSystemFromEntries(entries, ""), nil
+}
+
+// Parse a file system of a self-contained generated file, see
+// Generator.SelfContained.
+func (p *sourceParser) selfContainedFileSystem(call *ast.CallExpr) (*FileSystem, error) {
+	if len(call.Args) != 1 {
+		return nil, p.errorf(call, "unexpected number of arguments to newAssetsFileSystem")
+	}
+
+	entries, err := p.entries(call.Args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileSystemFromEntries(entries, ""), nil
+}
+
+func (p *sourceParser) version(expr ast.Expr) error {
+	v, err := p.eval(expr)
+
+	if err != nil {
+		return err
+	}
+
+	version, ok := v.(int64)
+
+	if !ok {
+		return p.errorf(expr, "expected format version")
+	}
+
+	if version < 2 || version > FormatVersion {
+		return p.errorf(expr, "unsupported format version %d (supported up to %d)", version, FormatVersion)
+	}
+
+	return nil
+}
+
+func (p *sourceParser) fileSystemFromEntries(args []ast.Expr) (*FileSystem, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("unexpected number of arguments to NewFileSystemFromEntries")
+	}
+
+	entries, err := p.entries(args[0])
+
+	if err != nil {
+		return nil, err
+	}
+
+	localPath, err := p.evalString(args[1])
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFileSystemFromEntries(entries, localPath), nil
+}
+
+func (p *sourceParser) entries(expr ast.Expr) ([]FileEntry, error) {
+	if slice, ok := expr.(*ast.SliceExpr); ok {
+		expr = slice.X
+	}
+
+	if ident, ok := expr.(*ast.Ident); ok {
+		if v, ok := p.vars[ident.Name]; ok {
+			expr = v
+		}
+	}
+
+	lit, ok := expr.(*ast.CompositeLit)
+
+	if !ok {
+		return nil, p.errorf(expr, "expected file entries")
+	}
+
+	entries := make([]FileEntry, 0, len(lit.Elts))
+
+	for _, elt := range lit.Elts {
+		e, err := p.entry(elt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+func (p *sourceParser) entry(expr ast.Expr) (FileEntry, error) {
+	var e FileEntry
+
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+
+	lit, ok := expr.(*ast.CompositeLit)
+
+	if !ok {
+		return e, p.errorf(expr, "expected file entry literal")
+	}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+
+		if !ok {
+			return e, p.errorf(elt, "expected file entry field")
+		}
+
+		key, ok := kv.Key.(*ast.Ident)
+
+		if !ok {
+			return e, p.errorf(kv.Key, "expected file entry field name")
+		}
+
+		v, err := p.eval(kv.Value)
+
+		if err != nil {
+			return e, err
+		}
+
+		switch key.Name {
+		case "Path":
+			e.Path, ok = v.(string)
+		case "FileMode":
+			var mode int64
+
+			mode, ok = v.(int64)
+			e.FileMode = os.FileMode(mode)
+		case "Mtime":
+			e.Mtime, ok = v.(int64)
+		case "Data":
+			e.Data, err = p.evalString(kv.Value)
+			ok = err == nil
+		case "Base64":
+			e.Base64, ok = v.(bool)
+		case "Source":
+			e.Source, ok = v.(string)
+		case "EmbedName":
+			e.EmbedName, ok = v.(string)
+		case "Digest":
+			e.Digest, ok = v.(string)
+		case "Fingerprinted":
+			e.Fingerprinted, ok = v.(bool)
+		case "Encrypted":
+			e.Encrypted, ok = v.(bool)
+		case "Checksum":
+			var checksum int64
+
+			checksum, ok = v.(int64)
+			e.Checksum = uint32(checksum)
+		case "Offset":
+			e.Offset, ok = v.(int64)
+		case "Length":
+			e.Length, ok = v.(int64)
+		case "Bytes":
+			e.Bytes, ok = v.([]byte)
+		case "Tags":
+			e.Tags, ok = v.([]string)
+		case "Title":
+			e.Title, ok = v.(string)
+		case "Order":
+			var order int64
+
+			order, ok = v.(int64)
+			e.Order = int(order)
+		case "Compressed":
+			e.Compressed, ok = v.(bool)
+		case "Compression":
+			e.Compression, ok = v.(Compression)
+		}
+
+		if !ok {
+			return e, p.errorf(kv.Value, "unexpected value for %s", key.Name)
+		}
+	}
+
+	if len(e.Path) == 0 {
+		return e, p.errorf(lit, "file entry without path")
+	}
+
+	if e.Base64 {
+		data, err := base64.StdEncoding.DecodeString(e.Data)
+
+		if err != nil {
+			return e, p.errorf(lit, "invalid base64 data: %s", err)
+		}
+
+		e.Data = string(data)
+		e.Base64 = false
+	}
+
+	e.Path = path.Clean(e.Path)
+	return e, nil
+}
+
+func (p *sourceParser) file(expr ast.Expr) (*File, error) {
+	if u, ok := expr.(*ast.UnaryExpr); ok && u.Op == token.AND {
+		expr = u.X
+	}
+
+	lit, ok := expr.(*ast.CompositeLit)
+
+	if !ok {
+		return nil, p.errorf(expr, "expected file literal")
+	}
+
+	f := &File{}
+
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+
+		if !ok {
+			return nil, p.errorf(elt, "expected file field")
+		}
+
+		key, ok := kv.Key.(*ast.Ident)
+
+		if !ok {
+			return nil, p.errorf(kv.Key, "expected file field name")
+		}
+
+		v, err := p.eval(kv.Value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch key.Name {
+		case "Path":
+			f.Path, ok = v.(string)
+		case "FileMode":
+			var mode int64
+
+			mode, ok = v.(int64)
+			f.FileMode = os.FileMode(mode)
+		case "Mtime":
+			f.Mtime, ok = v.(time.Time)
+		case "Data":
+			if v != nil {
+				f.Data, ok = v.([]byte)
+			}
+		case "Tags":
+			f.Tags, ok = v.([]string)
+		}
+
+		if !ok {
+			return nil, p.errorf(kv.Value, "unexpected value for %s", key.Name)
+		}
+	}
+
+	if len(f.Path) == 0 {
+		return nil, p.errorf(lit, "file without path")
+	}
+
+	f.Path = path.Clean(f.Path)
+	return f, nil
+}
+
+func (p *sourceParser) evalString(expr ast.Expr) (string, error) {
+	v, err := p.eval(expr)
+
+	if err != nil {
+		return "", err
+	}
+
+	switch vv := v.(type) {
+	case string:
+		return vv, nil
+	case []byte:
+		return string(vv), nil
+	}
+
+	return "", p.errorf(expr, "expected string")
+}
+
+// Evaluate the constant expressions emitted by the generator.
+func (p *sourceParser) eval(expr ast.Expr) (interface{}, error) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return p.eval(e.X)
+	case *ast.BasicLit:
+		c := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+
+		switch c.Kind() {
+		case constant.String:
+			return constant.StringVal(c), nil
+		case constant.Int:
+			if v, ok := constant.Int64Val(c); ok {
+				return v, nil
+			}
+		}
+
+		return nil, p.errorf(e, "unsupported literal %s", e.Value)
+	case *ast.Ident:
+		switch e.Name {
+		case "nil":
+			return nil, nil
+		case "true", "false":
+			return e.Name == "true", nil
+		}
+
+		if v, ok := p.vars[e.Name]; ok {
+			return p.eval(v)
+		}
+
+		return nil, p.errorf(e, "undefined identifier %s", e.Name)
+	case *ast.SelectorExpr:
+		for c, name := range compressionNames {
+			if e.Sel.Name == name {
+				return c, nil
+			}
+		}
+
+		return nil, p.errorf(e, "undefined identifier %s", e.Sel.Name)
+	case *ast.BinaryExpr:
+		x, err := p.eval(e.X)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := p.eval(e.Y)
+
+		if err != nil {
+			return nil, err
+		}
+
+		xs, xok := x.(string)
+		ys, yok := y.(string)
+
+		if e.Op == token.ADD && xok && yok {
+			return xs + ys, nil
+		}
+
+		return nil, p.errorf(e, "unsupported expression")
+	case *ast.CallExpr:
+		return p.evalCall(e)
+	case *ast.CompositeLit:
+		return p.evalComposite(e)
+	}
+
+	return nil, p.errorf(expr, "unsupported expression")
+}
+
+func (p *sourceParser) evalCall(e *ast.CallExpr) (interface{}, error) {
+	args := make([]interface{}, len(e.Args))
+
+	for i, arg := range e.Args {
+		v, err := p.eval(arg)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args[i] = v
+	}
+
+	// Type conversions
+	if arr, ok := e.Fun.(*ast.ArrayType); ok && len(args) == 1 {
+		if ident, ok := arr.Elt.(*ast.Ident); ok && arr.Len == nil && ident.Name == "byte" {
+			if s, ok := args[0].(string); ok {
+				return []byte(s), nil
+			}
+		}
+	}
+
+	switch callName(e) {
+	case "Unix":
+		if len(args) == 2 {
+			sec, ok1 := args[0].(int64)
+			nsec, ok2 := args[1].(int64)
+
+			if ok1 && ok2 {
+				// Older generators emitted the full unix nano time as
+				// the nanoseconds argument
+				if nsec >= 1e9 && nsec/1e9 == sec {
+					return time.Unix(0, nsec), nil
+				}
+
+				return time.Unix(sec, nsec), nil
+			}
+		}
+	case "FileMode":
+		if len(args) == 1 {
+			if v, ok := args[0].(int64); ok {
+				return v, nil
+			}
+		}
+	case "string":
+		if len(args) == 1 {
+			if v, ok := args[0].([]byte); ok {
+				return string(v), nil
+			}
+		}
+	}
+
+	return nil, p.errorf(e, "unsupported call")
+}
+
+func (p *sourceParser) evalComposite(e *ast.CompositeLit) (interface{}, error) {
+	switch t := e.Type.(type) {
+	case *ast.MapType:
+		val, _ := t.Value.(*ast.ArrayType)
+
+		if val != nil {
+			ret := make(map[string][]string)
+
+			for _, elt := range e.Elts {
+				kv, ok := elt.(*ast.KeyValueExpr)
+
+				if !ok {
+					return nil, p.errorf(elt, "expected key value pair")
+				}
+
+				k, err := p.evalString(kv.Key)
+
+				if err != nil {
+					return nil, err
+				}
+
+				lit, ok := kv.Value.(*ast.CompositeLit)
+
+				if !ok {
+					return nil, p.errorf(kv.Value, "expected string slice")
+				}
+
+				items := make([]string, 0, len(lit.Elts))
+
+				for _, item := range lit.Elts {
+					s, err := p.evalString(item)
+
+					if err != nil {
+						return nil, err
+					}
+
+					items = append(items, s)
+				}
+
+				ret[k] = items
+			}
+
+			return ret, nil
+		}
+
+		ret := make(map[string]string)
+
+		for _, elt := range e.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+
+			if !ok {
+				return nil, p.errorf(elt, "expected key value pair")
+			}
+
+			k, err := p.evalString(kv.Key)
+
+			if err != nil {
+				return nil, err
+			}
+
+			v, err := p.evalString(kv.Value)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ret[k] = v
+		}
+
+		return ret, nil
+	case *ast.ArrayType:
+		if elt, ok := t.Elt.(*ast.Ident); ok && elt.Name == "string" {
+			ret := make([]string, 0, len(e.Elts))
+
+			for _, elt := range e.Elts {
+				s, err := p.evalString(elt)
+
+				if err != nil {
+					return nil, err
+				}
+
+				ret = append(ret, s)
+			}
+
+			return ret, nil
+		}
+
+		ret := make([]byte, 0, len(e.Elts))
+
+		for _, elt := range e.Elts {
+			v, err := p.eval(elt)
+
+			if err != nil {
+				return nil, err
+			}
+
+			b, ok := v.(int64)
+
+			if !ok || b < 0 || b > 255 {
+				return nil, p.errorf(elt, "expected byte")
+			}
+
+			ret = append(ret, byte(b))
+		}
+
+		return ret, nil
+	}
+
+	return nil, p.errorf(e, "unsupported literal")
+}
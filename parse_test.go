@@ -0,0 +1,120 @@
+package assets
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// A file system generated in format version 1
+const parseVersion1Source = `package main
+
+import (
+	"time"
+
+	"github.com/jessevdk/go-assets"
+)
+
+var _Assets3737a75b5254ed1f6d588b40a3449721f9ea86c2 = "hello"
+
+// Assets returns go-assets FileSystem
+var Assets = assets.NewFileSystem(map[string][]string{"/": []string{"a.txt"}}, map[string]*assets.File{
+	"/": &assets.File{
+		Path:     "/",
+		FileMode: 0x800001ed,
+		Mtime:    time.Unix(1000, 0),
+		Data:     nil,
+	}, "/a.txt": &assets.File{
+		Path:     "/a.txt",
+		FileMode: 0x1a4,
+		Mtime:    time.Unix(1000, 0),
+		Data:     []byte(_Assets3737a75b5254ed1f6d588b40a3449721f9ea86c2),
+	}}, "")
+`
+
+func TestParseFormatVersions(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	current := generate(t, g)
+
+	for name, src := range map[string]string{"version 1": parseVersion1Source, "current version": string(current)} {
+		fss, err := Parse([]byte(src))
+
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+
+		if _, ok := fss["Assets"]; !ok {
+			t.Errorf("%s: expected Assets file system", name)
+		}
+	}
+
+	newer := strings.Replace(string(current), "NewVersionedFileSystem(2,", "NewVersionedFileSystem(3,", 1)
+
+	if _, err := Parse([]byte(newer)); err == nil {
+		t.Errorf("expected newer format version to be rejected")
+	}
+}
+
+func TestParseMissingPack(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "assets.go")
+	pack := filepath.Join(dir, "assets.pack")
+	moved := filepath.Join(dir, "moved.pack")
+
+	g := &Generator{PackFile: "assets.pack", StripPrefix: "/testdata"}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.WriteFile(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(pack, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entries can be inspected without the pack, reading fails until
+	// the pack is loaded from its new location
+	fss, err := ParseFile(filename)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+
+	if _, ok := fs.Files["/templates/index.html"]; !ok {
+		t.Errorf("expected /templates/index.html in the parsed file system")
+	}
+
+	if _, err := fs.ReadFile("/templates/index.html"); !os.IsNotExist(err) {
+		t.Errorf("expected reading without the pack to fail, got %v", err)
+	}
+
+	if err := fs.LoadPack(moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fs.ReadFile("/templates/index.html"); err != nil {
+		t.Errorf("expected reading after LoadPack to succeed, got %v", err)
+	}
+
+	// Other errors still fail parsing
+	if err := ioutil.WriteFile(pack, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ParseFile(filename); err == nil {
+		t.Errorf("expected an invalid pack to fail parsing")
+	}
+}
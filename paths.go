@@ -0,0 +1,214 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// Common initialisms which are written in upper case in generated
+// identifiers.
+var initialisms = map[string]bool{
+	"API": true, "CSS": true, "CSV": true, "GIF": true, "HTML": true,
+	"HTTP": true, "ID": true, "JPG": true, "JPEG": true, "JS": true,
+	"JSON": true, "MD": true, "PDF": true, "PNG": true, "SQL": true,
+	"SVG": true, "TOML": true, "TXT": true, "UI": true, "URL": true,
+	"WASM": true, "XML": true, "YAML": true, "YML": true,
+}
+
+// Convert an asset path to an exported go identifier (e.g.
+// /templates/index.html becomes TemplatesIndexHTML).
+func pathIdentifier(p string) string {
+	words := strings.FieldsFunc(p, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var ret strings.Builder
+
+	for _, w := range words {
+		if initialisms[strings.ToUpper(w)] {
+			ret.WriteString(strings.ToUpper(w))
+		} else {
+			r := []rune(w)
+			ret.WriteString(string(unicode.ToUpper(r[0])) + string(r[1:]))
+		}
+	}
+
+	if ret.Len() == 0 || !unicode.IsLetter([]rune(ret.String())[0]) {
+		return "Asset" + ret.String()
+	}
+
+	return ret.String()
+}
+
+// Assign unique identifiers to the given sorted paths, using prefix as the
+// identifier prefix.
+func pathIdentifiers(prefix string, paths []string, used map[string]bool) map[string]string {
+	ret := make(map[string]string)
+
+	for _, p := range paths {
+		id := prefix + pathIdentifier(p)
+		base := id
+
+		for i := 2; used[id]; i++ {
+			id = fmt.Sprintf("%s%d", base, i)
+		}
+
+		used[id] = true
+		ret[p] = id
+	}
+
+	return ret
+}
+
+// Get the declarations of the path constants of a generated file system (see
+// Generator.PathConstants), given the logical paths of its files and the
+// paths they are served under.
+func pathConstants(variableName string, paths []string, served map[string]string) string {
+	prefix := "Asset"
+
+	if variableName != "Assets" {
+		prefix = variableName + prefix
+	}
+
+	ids := pathIdentifiers(prefix, paths, make(map[string]bool))
+
+	var ret strings.Builder
+
+	fmt.Fprintf(&ret, "\n// The paths of the assets of %s.\n", variableName)
+	fmt.Fprintln(&ret, "const (")
+
+	for _, p := range paths {
+		fmt.Fprintf(&ret, "\t%s = %#v\n", ids[p], served[p])
+	}
+
+	fmt.Fprintln(&ret, ")")
+	return ret.String()
+}
+
+// Get the declarations of the accessor functions of a generated file system
+// (see Generator.Accessors), given the logical paths of its files and the
+// paths they are served under.
+func accessorFuncs(variableName string, paths []string, served map[string]string) string {
+	prefix := ""
+
+	if variableName != "Assets" {
+		prefix = variableName
+	}
+
+	ids := pathIdentifiers(prefix, paths, map[string]bool{variableName: true})
+
+	var ret strings.Builder
+
+	for i, p := range paths {
+		if i != 0 {
+			fmt.Fprintln(&ret)
+		}
+
+		fmt.Fprintf(&ret, "// %s returns the contents of %s.\n", ids[p], p)
+		fmt.Fprintf(&ret, "func %s() []byte {\n", ids[p])
+		fmt.Fprintf(&ret, "\treturn %s.MustReadFile(%#v)\n", variableName, served[p])
+		fmt.Fprintln(&ret, "}")
+	}
+
+	return ret.String()
+}
+
+// Get the sorted paths of all files (not directories) in the generated file
+// system.
+func (x *Generator) virtualFilePaths() []string {
+	var ret []string
+
+	for _, k := range x.filePaths() {
+		kk, _ := x.virtualPath(k)
+		ret = append(ret, kk)
+	}
+
+	return ret
+}
+
+// Write a go file declaring a typed Path string type and a constant for every
+// asset file in the generator (e.g. TemplatesIndexHTML Path =
+// "/templates/index.html"). The file is meant to be written into its own
+// package (named by PathsPackageName), giving code referencing assets
+// autocompletion and compile time checking. When groups are used, the
+// constants of a group are prefixed with the group name.
+func (x *Generator) WritePaths(wr io.Writer) error {
+	p := x.PathsPackageName
+
+	if len(p) == 0 {
+		p = "assetpaths"
+	}
+
+	banner, err := x.banner()
+
+	if err != nil {
+		return err
+	}
+
+	writer := &bytes.Buffer{}
+
+	fmt.Fprint(writer, banner)
+	fmt.Fprintf(writer, "package %s\n\n", p)
+	fmt.Fprintln(writer, "// The path of an embedded asset.")
+	fmt.Fprintln(writer, "type Path string")
+	fmt.Fprintln(writer)
+
+	used := make(map[string]bool)
+	gens := []*Generator{x}
+
+	if len(x.groups) != 0 {
+		gens = x.groups
+
+		if len(x.fsFilesMap) != 0 {
+			gens = append([]*Generator{x}, gens...)
+		}
+	}
+
+	for _, g := range gens {
+		prefix := ""
+
+		if len(x.groups) != 0 {
+			prefix = g.VariableName
+
+			if len(prefix) == 0 {
+				prefix = "Assets"
+			}
+		}
+
+		paths := g.virtualFilePaths()
+		ids := pathIdentifiers(prefix, paths, used)
+
+		fmt.Fprintln(writer, "const (")
+
+		for _, vp := range paths {
+			fmt.Fprintf(writer, "\t%s Path = %#v\n", ids[vp], vp)
+		}
+
+		fmt.Fprintln(writer, ")")
+		fmt.Fprintln(writer)
+	}
+
+	ret, err := x.formatSource(writer.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	_, err = wr.Write(ret)
+	return err
+}
+
+// Open the asset at the typed path p. This allows code to only accept asset
+// paths of a specific (generated) type, such as assetpaths.Path.
+func OpenPath[P ~string](fs *FileSystem, p P) (http.File, error) {
+	return fs.Open(string(p))
+}
+
+// Read the (decompressed) contents of the asset at the typed path p.
+func ReadPath[P ~string](fs *FileSystem, p P) ([]byte, error) {
+	return fs.ReadFile(string(p))
+}
@@ -0,0 +1,60 @@
+package assets
+
+// A file which would be embedded by the generator.
+type PlannedFile struct {
+	// The path of the file on disk, empty for files which do not originate
+	// from disk (e.g. fetched using AddURL).
+	SourcePath string
+
+	// The path of the file in the generated file system.
+	Path string
+
+	// The raw size of the file in bytes.
+	Size int64
+
+	// The size of the file in bytes when compressed (see Generator.Compression).
+	CompressedSize int64
+}
+
+// Get the resolved list of files that would be embedded, without writing
+// anything. This is useful to debug StripPrefix and Exclude settings before
+// generating a large asset file. The compressed size is computed regardless
+// of whether compression is enabled. Files of groups are not included, use
+// Group(name).Plan() instead.
+func (x *Generator) Plan() ([]PlannedFile, error) {
+	paths := x.filePaths()
+	ret := make([]PlannedFile, len(paths))
+
+	// Compressing files dominates planning like it dominates generation
+	err := x.parallel(len(paths), func(i int) error {
+		k := paths[i]
+		vp, _ := x.virtualPath(k)
+
+		data, err := x.read(k)
+
+		if err != nil {
+			return err
+		}
+
+		compressed, err := compress(data, x.Compression, x.CompressionLevel)
+
+		if err != nil {
+			return err
+		}
+
+		ret[i] = PlannedFile{
+			SourcePath:     x.fsFilesMap[k].path,
+			Path:           vp,
+			Size:           int64(len(data)),
+			CompressedSize: int64(len(compressed)),
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,183 @@
+package assets
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A source root of assets with its own path mapping, filters and compression
+// policy.
+type Root struct {
+	// The source directory (or file) on disk.
+	Dir string `json:"dir"`
+
+	// The prefix stripped from the (slash separated) source paths. Defaults
+	// to Dir, such that the contents of Dir are mounted directly at Mount.
+	Strip string `json:"strip,omitempty"`
+
+	// The virtual directory the stripped paths are mounted at (defaults to
+	// /). When Dir is a single file, Mount is the virtual path of the file.
+	Mount string `json:"mount,omitempty"`
+
+	// Glob patterns (see Generator.Exclude) of the files to add, matched
+	// against the paths relative to Dir. When empty, all files are added.
+	Include []string `json:"include,omitempty"`
+
+	// Glob patterns of the files and directories to exclude, matched
+	// against the paths relative to Dir. Generator.Exclude applies as well.
+	Exclude []string `json:"exclude,omitempty"`
+
+	// The compression policy of the files of the root, overriding the
+	// policy of the generator for the given extensions (see
+	// Generator.CompressionPolicy).
+	CompressionPolicy map[string]Compression `json:"compression_policy,omitempty"`
+}
+
+// Check whether the file or directory at path p relative to the root is
+// excluded by the filters of the root.
+func (r *Root) excluded(p string, dir bool) bool {
+	for _, pattern := range r.Exclude {
+		if matchPattern(pattern, p) {
+			return true
+		}
+	}
+
+	if dir || len(r.Include) == 0 {
+		return false
+	}
+
+	for _, pattern := range r.Include {
+		if matchPattern(pattern, p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check whether the file or directory at path p below the directory dir of
+// the root is excluded, either by the filters of the root or by
+// Generator.Exclude.
+func (x *Generator) rootExcluded(root *Root, dir string, p string, isDir bool) bool {
+	return x.excluded(path.Join("/", filepath.ToSlash(p))) || root.excluded(rootPath(dir, p), isDir)
+}
+
+// Add a source root to the generator. Unlike Add, each root carries its own
+// strip prefix, mount point, filters and compression policy, allowing a
+// single file system to be assembled from several source directories (e.g.
+// web/dist mounted at / and docs mounted at /docs) without changing the
+// options of the generator in between. Mount points are paths in the
+// generated file system, the global StripPrefix is not stripped from them.
+// StripPrefix must however be set before adding roots.
+func (x *Generator) AddRoot(root Root) error {
+	if err := x.addRoot(root); err != nil {
+		return err
+	}
+
+	dir := filepath.Clean(root.Dir)
+
+	x.sources = append(x.sources, source{
+		paths: []string{root.Dir},
+		add:   func() error { return x.addRoot(root) },
+		excluded: func(p string, isDir bool) bool {
+			return x.rootExcluded(&root, dir, p, isDir)
+		},
+	})
+
+	return nil
+}
+
+func (x *Generator) addRoot(root Root) error {
+	dir := filepath.Clean(root.Dir)
+	strip := root.Strip
+
+	if len(strip) == 0 {
+		strip = filepath.ToSlash(dir)
+	}
+
+	strip = path.Clean(strip)
+
+	// All paths of the root start with dir, which therefore needs to start
+	// with the stripped prefix for any of them to be added
+	if d := filepath.ToSlash(dir); d != strip && !strings.HasPrefix(d, strings.TrimSuffix(strip, "/")+"/") && strip != "." {
+		return fmt.Errorf("strip prefix %s of root %s is not a prefix of its directory", root.Strip, root.Dir)
+	}
+
+	return filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		sp := filepath.ToSlash(p)
+
+		rel := strings.TrimPrefix(sp, strip)
+
+		if strip == "." {
+			rel = sp
+		}
+
+		if err != nil {
+			if !x.ContinueOnError || p == dir {
+				return err
+			}
+
+			// Skip the unreadable file or the contents of the
+			// unreadable directory, see ContinueOnError
+			x.fail(path.Join("/", x.StripPrefix, root.Mount, rel), err)
+			return nil
+		}
+
+		if !info.IsDir() && x.isMetaFile(info.Name()) {
+			return x.loadMeta(path.Dir(path.Join("/", x.StripPrefix, root.Mount, rel)), p)
+		}
+
+		if p != dir && x.rootExcluded(&root, dir, p, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		k := path.Join("/", x.StripPrefix, root.Mount, rel)
+
+		// Directories at the maximum depth are added without their
+		// contents, see MaxDepth
+		var skip error
+
+		if info.IsDir() && p != dir && x.truncated(strings.Count(rootPath(dir, p), "/")) {
+			x.truncate(k)
+			skip = filepath.SkipDir
+		}
+
+		// With include patterns, only directories containing included
+		// files are added (as parents of those files)
+		if info.IsDir() && len(root.Include) != 0 {
+			return skip
+		}
+
+		if !info.IsDir() {
+			if err := x.checkSecret(k); err != nil {
+				return err
+			}
+		}
+
+		x.addEntry(k, file{
+			info:   info,
+			path:   p,
+			policy: root.CompressionPolicy,
+		})
+
+		return skip
+	})
+}
+
+// Get the slash separated path of p relative to the root directory dir.
+func rootPath(dir string, p string) string {
+	rel, err := filepath.Rel(dir, p)
+
+	if err != nil {
+		return filepath.ToSlash(p)
+	}
+
+	return path.Join("/", filepath.ToSlash(rel))
+}
@@ -0,0 +1,159 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// The number of files in the synthetic tree of TestLargeTree. Set the
+// GO_ASSETS_LARGE_TREE environment variable to test other sizes, e.g. 100000
+// to verify the supported capacity documented in the README.
+const defaultLargeTreeFiles = 2000
+
+func largeTreeFiles(t *testing.T) int {
+	if s := os.Getenv("GO_ASSETS_LARGE_TREE"); len(s) != 0 {
+		n, err := strconv.Atoi(s)
+
+		if err != nil {
+			t.Fatalf("invalid GO_ASSETS_LARGE_TREE: %s", err)
+		}
+
+		return n
+	}
+
+	return defaultLargeTreeFiles
+}
+
+// Generate a synthetic tree of n files, spread over directories of at most
+// 1000 files each.
+func largeTreeGenerator(n int) *Generator {
+	g := &Generator{PackageName: "largetree"}
+	mtime := time.Unix(1500000000, 0)
+
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("/tree/d%03d/f%06d.txt", i/1000, i)
+		g.addVirtual(p, 0644, mtime, []byte(fmt.Sprintf("file %d\n", i)))
+	}
+
+	return g
+}
+
+func totalAlloc() uint64 {
+	var m runtime.MemStats
+
+	runtime.ReadMemStats(&m)
+	return m.TotalAlloc
+}
+
+func TestLargeTree(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large tree test in short mode")
+	}
+
+	n := largeTreeFiles(t)
+	start := time.Now()
+	g := largeTreeGenerator(n)
+
+	t.Logf("added %d files in %s", n, time.Since(start))
+
+	start = time.Now()
+	alloc := totalAlloc()
+
+	var buf bytes.Buffer
+
+	if err := g.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Logf("generated %d bytes in %s (allocated %d MB)", buf.Len(), time.Since(start), (totalAlloc()-alloc)>>20)
+
+	start = time.Now()
+	fss, err := Parse(buf.Bytes())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := fss["Assets"]
+	t.Logf("loaded file system in %s", time.Since(start))
+
+	if len(fs.Files) != n+1+1+(n+999)/1000 {
+		t.Errorf("expected %d files, got %d", n+2+(n+999)/1000, len(fs.Files))
+	}
+
+	start = time.Now()
+
+	for i := 0; i < n; i++ {
+		p := fmt.Sprintf("/tree/d%03d/f%06d.txt", i/1000, i)
+
+		if data, err := fs.ReadFile(p); err != nil || string(data) != fmt.Sprintf("file %d\n", i) {
+			t.Fatalf("unexpected contents of %s: %q (%v)", p, data, err)
+		}
+	}
+
+	t.Logf("looked up %d files in %s", n, time.Since(start))
+
+	testCompileLargeTree(t, buf.Bytes())
+}
+
+// Compile the generated tree and run its init, when the go tool can build
+// packages in this directory. The generated package is placed in the package
+// directory with a build overlay, such that it resolves the assets package
+// without writing to the source tree.
+func testCompileLargeTree(t *testing.T, src []byte) {
+	if err := exec.Command("go", "list", ".").Run(); err != nil {
+		t.Log("skipping compilation, package cannot be built with the go tool here")
+		return
+	}
+
+	tmp := t.TempDir()
+	wd, err := os.Getwd()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	main := bytes.Replace(src, []byte("package largetree"), []byte("package main"), 1)
+	main = append(main, []byte("\nfunc main() {\n\tif _, err := Assets.Open(\"/tree/d000/f000000.txt\"); err != nil {\n\t\tpanic(err)\n\t}\n}\n")...)
+
+	if err := os.WriteFile(filepath.Join(tmp, "assets.go"), main, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	overlay, err := json.Marshal(map[string]map[string]string{
+		"Replace": {filepath.Join(wd, "_largetree", "assets.go"): filepath.Join(tmp, "assets.go")},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, "overlay.json"), overlay, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	exe := filepath.Join(tmp, "largetree")
+
+	if out, err := exec.Command("go", "build", "-overlay", filepath.Join(tmp, "overlay.json"), "-o", exe, "./_largetree").CombinedOutput(); err != nil {
+		t.Fatalf("failed to compile generated assets: %s\n%s", err, out)
+	}
+
+	t.Logf("compiled generated assets in %s", time.Since(start))
+
+	start = time.Now()
+
+	if out, err := exec.Command(exe).CombinedOutput(); err != nil {
+		t.Fatalf("failed to run generated assets: %s\n%s", err, out)
+	}
+
+	t.Logf("initialized and looked up generated assets in %s", time.Since(start))
+}
@@ -0,0 +1,91 @@
+package assets
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// The glob patterns (see Generator.Exclude) of files which likely hold
+// credentials, used when Generator.SecretPatterns is empty.
+var DefaultSecretPatterns = []string{
+	".env",
+	".env.*",
+	"*.pem",
+	"*.key",
+	"*.p12",
+	"*.pfx",
+	"id_rsa",
+	"id_dsa",
+	"id_ecdsa",
+	"id_ed25519",
+	".htpasswd",
+	".netrc",
+}
+
+// How files which look like secrets are handled when they are added, see
+// Generator.SecretPolicy.
+type SecretPolicy int
+
+const (
+	// Add the file, writing a warning to Generator.SecretOutput.
+	SecretWarn SecretPolicy = iota
+
+	// Fail adding the file.
+	SecretError
+
+	// Add the file silently.
+	SecretIgnore
+)
+
+// Check whether the file at generator path p looks like a secret (see
+// SecretPatterns), and handle it according to SecretPolicy.
+func (x *Generator) checkSecret(p string) error {
+	if x.SecretPolicy == SecretIgnore || !x.secret(p) {
+		return nil
+	}
+
+	if x.SecretPolicy == SecretError {
+		return fmt.Errorf("%s looks like a secret, refusing to embed it (see AllowSecrets)", p)
+	}
+
+	var w io.Writer = os.Stderr
+
+	if x.SecretOutput != nil {
+		w = x.SecretOutput
+	}
+
+	fmt.Fprintf(w, "warning: %s looks like a secret and will be embedded in the generated file\n", p)
+	return nil
+}
+
+// Check whether the file at generator path p matches one of the
+// SecretPatterns, and none of AllowSecrets.
+func (x *Generator) secret(p string) bool {
+	patterns := x.SecretPatterns
+
+	if len(patterns) == 0 {
+		patterns = DefaultSecretPatterns
+	}
+
+	matched := false
+
+	for _, pattern := range patterns {
+		if matchPattern(pattern, p) {
+			matched = true
+			break
+		}
+	}
+
+	if !matched {
+		return false
+	}
+
+	for _, pattern := range x.AllowSecrets {
+		if matchPattern(pattern, p) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,388 @@
+package assets
+
+import (
+	"fmt"
+	"io"
+)
+
+// The imports of the runtime written to self-contained generated files.
+var selfContainedImports = []string{
+	"bytes",
+	"compress/gzip",
+	"encoding/base64",
+	"fmt",
+	"io",
+	"io/ioutil",
+	"net/http",
+	"os",
+	"path",
+	"sort",
+	"sync",
+	"time",
+}
+
+// The runtime written to self-contained generated files (see
+// Generator.SelfContained), a minimal version of FileSystem and File which
+// only supports gzip compression.
+const selfContainedRuntime = `// A file system of assets, implementing http.FileSystem. This is a minimal
+// version of the FileSystem of github.com/jessevdk/go-assets.
+type assetsFileSystem struct {
+	// A map of directory paths to the files in those directories.
+	Dirs map[string][]string
+
+	// A map of file/directory paths to assets.
+	Files map[string]*assetsFile
+
+	// A map of logical asset keys to file paths.
+	Keys map[string]string
+
+	// A map of the original paths of fingerprinted assets to their
+	// fingerprinted paths.
+	Fingerprints map[string]string
+
+	// Whether compressed assets are decompressed once, on first access, and
+	// their decompressed data is kept in memory.
+	CacheDecompressed bool
+
+	decompressed sync.Map
+}
+
+type assetsDecompressed struct {
+	once sync.Once
+	data []byte
+	err  error
+}
+
+// An asset, implementing http.File and os.FileInfo. Reading an asset
+// yields its decompressed data.
+type assetsFile struct {
+	// The full asset file path
+	Path string
+
+	// The asset file mode
+	FileMode os.FileMode
+
+	// The asset modification time
+	Mtime time.Time
+
+	// The asset data. Note that this data might be in compressed form.
+	Data []byte
+
+	// The tags assigned to the asset at generation time.
+	Tags []string
+
+	// The title and navigation order of the asset.
+	Title string
+	Order int
+
+	// Whether the asset data is stored in gzip compressed form.
+	Compressed bool
+
+	// The hex encoded SHA-256 digest and the CRC-32 (IEEE) checksum of the
+	// (decompressed) asset data.
+	Digest   string
+	Checksum uint32
+
+	// Whether the asset is stored under a fingerprinted name.
+	Fingerprinted bool
+
+	fs       *assetsFileSystem
+	buf      *bytes.Reader
+	dirIndex int
+}
+
+type assetsFileEntry struct {
+	Path       string
+	FileMode   os.FileMode
+	Mtime      int64
+	Data       string
+	Base64     bool
+	Bytes      []byte
+	Tags       []string
+	Title      string
+	Order      int
+	Compressed bool
+	Digest     string
+	Checksum   uint32
+
+	Fingerprinted bool
+}
+
+func newAssetsFileSystem(entries []assetsFileEntry) *assetsFileSystem {
+	fs := &assetsFileSystem{
+		Dirs:  make(map[string][]string),
+		Files: make(map[string]*assetsFile, len(entries)),
+	}
+
+	for i := range entries {
+		e := &entries[i]
+
+		f := &assetsFile{
+			Path:       e.Path,
+			FileMode:   e.FileMode,
+			Tags:       e.Tags,
+			Title:      e.Title,
+			Order:      e.Order,
+			Compressed: e.Compressed,
+			Digest:     e.Digest,
+			Checksum:   e.Checksum,
+
+			Fingerprinted: e.Fingerprinted,
+			fs:         fs,
+		}
+
+		if e.Mtime != 0 {
+			f.Mtime = time.Unix(0, e.Mtime)
+		}
+
+		if f.IsDir() {
+			if _, ok := fs.Dirs[e.Path]; !ok {
+				fs.Dirs[e.Path] = []string{}
+			}
+		} else if e.Bytes != nil {
+			f.Data = e.Bytes
+		} else if e.Base64 {
+			data, err := base64.StdEncoding.DecodeString(e.Data)
+
+			if err != nil {
+				panic(fmt.Sprintf("assets: invalid base64 data of %s: %s", e.Path, err))
+			}
+
+			f.Data = data
+		} else {
+			f.Data = []byte(e.Data)
+		}
+
+		if e.Path != "/" {
+			dir := path.Dir(e.Path)
+			fs.Dirs[dir] = append(fs.Dirs[dir], path.Base(e.Path))
+		}
+
+		fs.Files[e.Path] = f
+	}
+
+	for _, names := range fs.Dirs {
+		sort.Strings(names)
+	}
+
+	return fs
+}
+
+// Implementation of http.FileSystem
+func (fs *assetsFileSystem) Open(p string) (http.File, error) {
+	f, ok := fs.Files[path.Clean(p)]
+
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+
+	ret := *f
+	return &ret, nil
+}
+
+// Read the full (decompressed) contents of the asset at the given path.
+func (fs *assetsFileSystem) ReadFile(p string) ([]byte, error) {
+	f, ok := fs.Files[path.Clean(p)]
+
+	if !ok || f.IsDir() {
+		return nil, os.ErrNotExist
+	}
+
+	if !f.Compressed {
+		return append([]byte(nil), f.Data...), nil
+	}
+
+	if fs.CacheDecompressed {
+		v, _ := fs.decompressed.LoadOrStore(f, &assetsDecompressed{})
+		d := v.(*assetsDecompressed)
+
+		d.once.Do(func() {
+			d.data, d.err = f.decompress()
+		})
+
+		if d.err != nil {
+			return nil, d.err
+		}
+
+		return append([]byte(nil), d.data...), nil
+	}
+
+	return f.decompress()
+}
+
+func (f *assetsFile) decompress() ([]byte, error) {
+	rd, err := gzip.NewReader(bytes.NewReader(f.Data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rd.Close()
+	return ioutil.ReadAll(rd)
+}
+
+// Get the path under which the asset at p is served.
+func (fs *assetsFileSystem) URL(p string) string {
+	if url, ok := fs.Fingerprints[path.Clean(p)]; ok {
+		return url
+	}
+
+	return p
+}
+
+// Read the full (decompressed) contents of the asset at the given path,
+// panicking when the asset cannot be read.
+func (fs *assetsFileSystem) MustReadFile(p string) []byte {
+	data, err := fs.ReadFile(p)
+
+	if err != nil {
+		panic(fmt.Sprintf("assets: failed to read %s: %s", p, err))
+	}
+
+	return data
+}
+
+// Open the asset registered under the given logical key.
+func (fs *assetsFileSystem) Lookup(key string) (http.File, error) {
+	if p, ok := fs.Keys[key]; ok {
+		return fs.Open(p)
+	}
+
+	return nil, os.ErrNotExist
+}
+
+func (f *assetsFile) Name() string {
+	return path.Base(f.Path)
+}
+
+func (f *assetsFile) Mode() os.FileMode {
+	return f.FileMode
+}
+
+func (f *assetsFile) ModTime() time.Time {
+	return f.Mtime
+}
+
+func (f *assetsFile) IsDir() bool {
+	return f.FileMode.IsDir()
+}
+
+func (f *assetsFile) Size() int64 {
+	return int64(len(f.Data))
+}
+
+func (f *assetsFile) Sys() interface{} {
+	return nil
+}
+
+func (f *assetsFile) Close() error {
+	f.buf = nil
+	f.dirIndex = 0
+
+	return nil
+}
+
+func (f *assetsFile) Stat() (os.FileInfo, error) {
+	return f, nil
+}
+
+func (f *assetsFile) Readdir(count int) ([]os.FileInfo, error) {
+	if !f.IsDir() {
+		return nil, os.ErrInvalid
+	}
+
+	names := f.fs.Dirs[f.Path][f.dirIndex:]
+
+	if len(names) == 0 && count > 0 {
+		return nil, io.EOF
+	}
+
+	if count > 0 && count < len(names) {
+		names = names[:count]
+	}
+
+	ret := make([]os.FileInfo, 0, len(names))
+
+	for _, name := range names {
+		ret = append(ret, f.fs.Files[path.Join(f.Path, name)])
+	}
+
+	f.dirIndex += len(names)
+	return ret, nil
+}
+
+func (f *assetsFile) reader() (*bytes.Reader, error) {
+	if f.buf == nil {
+		data, err := f.fs.ReadFile(f.Path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.buf = bytes.NewReader(data)
+	}
+
+	return f.buf, nil
+}
+
+func (f *assetsFile) Read(data []byte) (int, error) {
+	rd, err := f.reader()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rd.Read(data)
+}
+
+func (f *assetsFile) Seek(offset int64, whence int) (int64, error) {
+	rd, err := f.reader()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return rd.Seek(offset, whence)
+}
+`
+
+// Write the import block of a generated file and, for self-contained files,
+// the runtime. Test files (see WriteTest) share the runtime of the file they
+// belong to and therefore import nothing.
+func (x *Generator) writeImports(writer io.Writer, out *dataOutput) {
+	if x.SelfContained && out.test {
+		return
+	}
+
+	fmt.Fprintln(writer, "import (")
+
+	if x.SelfContained {
+		for _, imp := range selfContainedImports {
+			fmt.Fprintf(writer, "\t%q\n", imp)
+		}
+	} else {
+		if out.embed != nil {
+			fmt.Fprintln(writer, "\t_ \"embed\"")
+			fmt.Fprintln(writer)
+		}
+
+		if len(out.dev) != 0 {
+			fmt.Fprintln(writer, "\t\"path/filepath\"")
+			fmt.Fprintln(writer, "\t\"runtime\"")
+			fmt.Fprintln(writer)
+		}
+
+		if len(x.RuntimeImport) != 0 {
+			fmt.Fprintf(writer, "\tassets %q\n", x.RuntimeImport)
+		} else {
+			fmt.Fprintf(writer, "\t%q\n", DefaultRuntimeImport)
+		}
+	}
+
+	fmt.Fprintln(writer, ")")
+
+	if x.SelfContained {
+		fmt.Fprintln(writer)
+		io.WriteString(writer, selfContainedRuntime)
+	}
+}
@@ -0,0 +1,168 @@
+package assets
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// The default file served for requests for a directory.
+const DefaultIndex = "index.html"
+
+// A rule setting the Cache-Control header of matching assets.
+type CacheRule struct {
+	// The glob pattern (see Generator.Exclude) matched against asset paths.
+	Pattern string `json:"pattern" yaml:"pattern"`
+
+	// The Cache-Control header value of matching assets (e.g.
+	// "public, max-age=31536000, immutable").
+	CacheControl string `json:"cache_control" yaml:"cache_control"`
+}
+
+// The configuration of how assets are served by a Handler. The configuration
+// can be loaded from application config files, use Validate to check it
+// before serving.
+type ServerConfig struct {
+	// The content codings compressed assets may be served in as is (e.g.
+	// gzip, br). When empty, compressed assets are served as is whenever the
+	// client accepts their coding. Otherwise assets are decompressed before
+	// serving.
+	Encodings []string `json:"encodings,omitempty" yaml:"encodings,omitempty"`
+
+	// Rules setting the Cache-Control header of assets. The first matching
+	// rule applies. Private assets are never cached.
+	Cache []CacheRule `json:"cache,omitempty" yaml:"cache,omitempty"`
+
+	// The path of the asset served for requests of non-existing assets, for
+	// single page applications doing their own routing (e.g. /index.html).
+	// Not found is returned when empty.
+	SPAFallback string `json:"spa_fallback,omitempty" yaml:"spa_fallback,omitempty"`
+
+	// The name of the file served for requests for a directory (defaults to
+	// DefaultIndex). Directory listings are never served.
+	Index string `json:"index,omitempty" yaml:"index,omitempty"`
+
+	// Compress assets stored uncompressed using gzip for clients accepting
+	// it. Each asset is compressed on first request and the compressed data
+	// is kept in memory by the file system, for all handlers serving it,
+	// which costs up to the compressed size of all served assets (see
+	// MaxCompressedCache). Assets in already compressed formats (see
+	// FileSystem.Precompressed) and assets whose content type is unknown
+	// are served uncompressed.
+	CompressResponses bool `json:"compress_responses,omitempty" yaml:"compress_responses,omitempty"`
+
+	// The maximum total size in bytes of the compressed data kept in memory
+	// (0 for no limit), see CompressResponses. Assets compressed once the
+	// limit is reached are compressed again on every request.
+	MaxCompressedCache int64 `json:"max_compressed_cache,omitempty" yaml:"max_compressed_cache,omitempty"`
+
+	// Headers added to all responses.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// Authorize a request for a private asset. Private assets are not
+	// served at all when no authorization function is set. Requests which
+	// are not authorized receive the same response as requests for
+	// non-existing assets, such that the existence of private assets is not
+	// revealed.
+	Authorize func(r *http.Request, f *File) bool `json:"-" yaml:"-"`
+}
+
+// Check whether the configuration is valid.
+func (c *ServerConfig) Validate() error {
+	for _, enc := range c.Encodings {
+		if !knownEncoding(enc) {
+			return fmt.Errorf("unsupported encoding %q", enc)
+		}
+	}
+
+	for _, rule := range c.Cache {
+		if _, err := path.Match(strings.TrimPrefix(rule.Pattern, "/"), ""); err != nil {
+			return fmt.Errorf("invalid cache rule pattern %q: %s", rule.Pattern, err)
+		}
+
+		if len(rule.CacheControl) == 0 {
+			return fmt.Errorf("cache rule %q does not specify a Cache-Control value", rule.Pattern)
+		}
+	}
+
+	if len(c.SPAFallback) != 0 && !strings.HasPrefix(c.SPAFallback, "/") {
+		return fmt.Errorf("SPA fallback %q is not an absolute path", c.SPAFallback)
+	}
+
+	if strings.Contains(c.Index, "/") {
+		return fmt.Errorf("index %q is not a file name", c.Index)
+	}
+
+	for name := range c.Headers {
+		if len(name) == 0 || strings.ContainsAny(name, ": \t\r\n") {
+			return fmt.Errorf("invalid header name %q", name)
+		}
+	}
+
+	return nil
+}
+
+// Create a handler serving the assets of the file system using the
+// configuration.
+func (c *ServerConfig) Handler(fs *FileSystem) (*Handler, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Handler{FS: fs, ServerConfig: *c}, nil
+}
+
+func knownEncoding(encoding string) bool {
+	for _, codec := range Codecs {
+		if codec.Encoding == encoding {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the Cache-Control header value of the asset at p, or an empty string if
+// no rule matches.
+func (c *ServerConfig) cacheControl(p string) string {
+	for _, rule := range c.Cache {
+		if matchPattern(rule.Pattern, p) {
+			return rule.CacheControl
+		}
+	}
+
+	return ""
+}
+
+// Check whether compressed assets may be served as is in the given encoding.
+func (c *ServerConfig) allowsEncoding(encoding string) bool {
+	return len(c.Encodings) == 0 || containsString(c.Encodings, encoding)
+}
+
+// Create a mux serving the assets of the file system below the given path
+// prefix (e.g. /assets/), using the configuration (or the default
+// configuration when nil). Other routes of an application can be registered
+// on the returned mux.
+func NewServeMux(prefix string, fs *FileSystem, config *ServerConfig) (*http.ServeMux, error) {
+	if config == nil {
+		config = &ServerConfig{}
+	}
+
+	h, err := config.Handler(fs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = path.Join("/", prefix)
+	mux := http.NewServeMux()
+
+	if prefix == "/" {
+		mux.Handle("/", h)
+	} else {
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, h))
+	}
+
+	return mux, nil
+}
@@ -0,0 +1,299 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestServerConfig(t *testing.T) {
+	var config ServerConfig
+
+	err := json.Unmarshal([]byte(`{
+		"cache": [{"pattern": "*.css", "cache_control": "public, max-age=3600"}],
+		"spa_fallback": "/index.html",
+		"headers": {"X-Frame-Options": "DENY"}
+	}`), &config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := config.Handler(testFileSystem())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path         string
+		cacheControl string
+		body         string
+	}{
+		{"/css/app.css", "public, max-age=3600", "body { margin: 0; }\n"},
+		{"/", "", "<html><body>hello</body></html>\n"},
+		{"/app/route", "", "<html><body>hello</body></html>\n"},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", test.path, nil))
+
+		if w.Code != http.StatusOK || w.Body.String() != test.body {
+			t.Errorf("%s: expected %q, got %d %q", test.path, test.body, w.Code, w.Body.String())
+		}
+
+		if cc := w.Header().Get("Cache-Control"); cc != test.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", test.path, test.cacheControl, cc)
+		}
+
+		if xfo := w.Header().Get("X-Frame-Options"); xfo != "DENY" {
+			t.Errorf("%s: expected configured header, got %q", test.path, xfo)
+		}
+	}
+}
+
+func TestServerConfigValidate(t *testing.T) {
+	invalid := []ServerConfig{
+		{Encodings: []string{"compress"}},
+		{Cache: []CacheRule{{Pattern: "[", CacheControl: "no-cache"}}},
+		{Cache: []CacheRule{{Pattern: "*.css"}}},
+		{SPAFallback: "index.html"},
+		{Index: "sub/index.html"},
+		{Headers: map[string]string{"X Bad": "value"}},
+	}
+
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("expected %+v to be invalid", config)
+		}
+	}
+
+	valid := ServerConfig{Encodings: []string{"gzip"}, Index: "default.html"}
+
+	if err := valid.Validate(); err != nil {
+		t.Errorf("expected valid config, got %s", err)
+	}
+}
+
+func TestServerConfigCompressResponses(t *testing.T) {
+	h, err := (&ServerConfig{CompressResponses: true}).Handler(testFileSystem())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/css/app.css", nil)
+		r.Header.Set("Accept-Encoding", "gzip, deflate")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("expected gzip encoded response, got %v", w.Header())
+		}
+
+		rd, err := gzip.NewReader(w.Body)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if data, err := ioutil.ReadAll(rd); err != nil || string(data) != "body { margin: 0; }\n" {
+			t.Errorf("expected decompressed body, got %q (%v)", data, err)
+		}
+	}
+
+	// Clients not accepting gzip receive the asset as is
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/css/app.css", nil))
+
+	if w.Header().Get("Content-Encoding") != "" || w.Body.String() != "body { margin: 0; }\n" {
+		t.Errorf("expected uncompressed response, got %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestServerConfigCompressResponsesCache(t *testing.T) {
+	fs := testFileSystem()
+
+	serve := func(h http.Handler, p string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", p, nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	// Handlers serving the same file system share the compressed data
+	for i := 0; i < 2; i++ {
+		if w := serve(&Handler{FS: fs, ServerConfig: ServerConfig{CompressResponses: true}}, "/css/app.css"); w.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzip encoded response, got %v", w.Header())
+		}
+	}
+
+	if len(fs.gzipped) != 1 {
+		t.Errorf("expected a single cached asset, got %d", len(fs.gzipped))
+	}
+
+	// Assets compressed beyond the limit are served compressed but not kept
+	h := &Handler{FS: fs, ServerConfig: ServerConfig{CompressResponses: true, MaxCompressedCache: fs.gzippedSize}}
+
+	if w := serve(h, "/index.html"); w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoded response, got %v", w.Header())
+	}
+
+	if _, ok := fs.gzipped[fs.Files["/index.html"]]; ok {
+		t.Errorf("expected /index.html not to be cached beyond the limit")
+	}
+
+	// Precompressed patterns of the file system are honoured
+	fs = testFileSystem()
+	fs.Precompressed = []string{"*.css"}
+
+	if w := serve(&Handler{FS: fs, ServerConfig: ServerConfig{CompressResponses: true}}, "/css/app.css"); w.Header().Get("Content-Encoding") != "" || w.Body.String() != "body { margin: 0; }\n" {
+		t.Errorf("expected uncompressed response, got %v %q", w.Header(), w.Body.String())
+	}
+}
+
+func TestStreamDevFileSystem(t *testing.T) {
+	dir := t.TempDir()
+	data := bytes.Repeat([]byte("0123456789"), 1000)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.txt"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The data of development file systems is read from the source file
+	fs := NewDevFileSystem(FormatVersion, []FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/large.txt", FileMode: 0644, Source: "large.txt"},
+	}, dir)
+
+	w := &streamRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	if _, err := fs.Files["/large.txt"].Stream(w, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(len(data)) || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Errorf("expected Content-Length %d and the source data, got %q and %d bytes", len(data), cl, w.Body.Len())
+	}
+}
+
+// A response recorder recording flushes and write deadlines
+type streamRecorder struct {
+	*httptest.ResponseRecorder
+
+	flushes   int
+	deadlines []time.Time
+}
+
+func (w *streamRecorder) Flush() {
+	w.flushes++
+	w.ResponseRecorder.Flush()
+}
+
+func (w *streamRecorder) SetWriteDeadline(deadline time.Time) error {
+	w.deadlines = append(w.deadlines, deadline)
+	return nil
+}
+
+func TestStream(t *testing.T) {
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+
+	fs := NewFileSystemFromEntries([]FileEntry{
+		{Path: "/", FileMode: os.ModeDir | 0755},
+		{Path: "/large.txt", FileMode: 0644, Data: string(data)},
+	}, "")
+
+	w := &streamRecorder{ResponseRecorder: httptest.NewRecorder()}
+	start := time.Now()
+
+	n, err := fs.Files["/large.txt"].Stream(w, StreamOptions{ChunkSize: 1000, WriteTimeout: time.Minute})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(data)) || !bytes.Equal(w.Body.Bytes(), data) {
+		t.Errorf("expected %d bytes to be streamed, got %d (%d in body)", len(data), n, w.Body.Len())
+	}
+
+	if cl := w.Header().Get("Content-Length"); cl != strconv.Itoa(len(data)) {
+		t.Errorf("expected Content-Length %d, got %q", len(data), cl)
+	}
+
+	// One flush and one extended deadline per chunk
+	if w.flushes != 100 || len(w.deadlines) != 100 {
+		t.Errorf("expected 100 flushes and deadlines, got %d and %d", w.flushes, len(w.deadlines))
+	}
+
+	for _, deadline := range w.deadlines {
+		if deadline.Before(start.Add(time.Minute)) {
+			t.Errorf("expected deadlines to be extended by the write timeout, got %s", deadline)
+			break
+		}
+	}
+
+	// Without a write timeout, deadlines are left alone
+	w = &streamRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+	if _, err := fs.Files["/large.txt"].Stream(w, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.flushes != 4 || len(w.deadlines) != 0 {
+		t.Errorf("expected 4 flushes of the default chunk size and no deadlines, got %d and %d", w.flushes, len(w.deadlines))
+	}
+
+	// Compressed assets are decompressed while streaming, without a length
+	dir := t.TempDir()
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "large.txt"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{Compressed: true}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfs, err := g.FileSystem()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+
+	if _, err := cfs.Files["/large.txt"].Stream(rec, StreamOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rec.Body.Bytes(), data) {
+		t.Errorf("expected decompressed data to be streamed, got %d bytes", rec.Body.Len())
+	}
+
+	if cl := rec.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("expected no Content-Length for compressed assets, got %q", cl)
+	}
+
+	// Response writers without flushing and deadline support still work
+	dw := &discardResponseWriter{header: make(http.Header)}
+
+	if n, err := fs.Files["/large.txt"].Stream(dw, StreamOptions{WriteTimeout: time.Second}); err != nil || n != int64(len(data)) {
+		t.Errorf("expected streaming to a plain writer to succeed, got %d, %v", n, err)
+	}
+}
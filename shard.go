@@ -0,0 +1,142 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// The asset data declarations of a generated file, split into shards which
+// are written to separate data files, see Generator.DataShardSize. With a
+// zero limit, each declaration is written to its own shard (see WriteDir).
+type dataShards struct {
+	limit  int64
+	shards []*bytes.Buffer
+
+	// The name of the first variable declared in each shard
+	names []string
+}
+
+// Add the declaration of the variable name to the last shard, starting a new
+// shard when the last shard would exceed the size limit.
+func (s *dataShards) add(name string, decl string) {
+	n := len(s.shards)
+
+	if n == 0 || (s.shards[n-1].Len() != 0 && int64(s.shards[n-1].Len()+len(decl)) > s.limit) {
+		s.shards = append(s.shards, &bytes.Buffer{})
+		s.names = append(s.names, name)
+		n++
+	}
+
+	s.shards[n-1].WriteString(decl)
+}
+
+// Get the formatted contents of the data file of a shard.
+func (x *Generator) dataFile(shard *bytes.Buffer) ([]byte, error) {
+	var buf bytes.Buffer
+
+	header := x.DataHeader
+
+	if len(header) == 0 {
+		header = x.Header
+	}
+
+	banner, err := x.banner()
+
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprint(&buf, banner)
+	fmt.Fprint(&buf, commentBlock(header))
+	fmt.Fprintf(&buf, "package %s\n\n", x.packageName())
+	buf.Write(shard.Bytes())
+
+	return x.formatSource(buf.Bytes())
+}
+
+// Get the name of the i-th data file (starting at 1) belonging to filename.
+func dataFilename(filename string, i int) string {
+	return fmt.Sprintf("%s_data%d.go", strings.TrimSuffix(filename, ".go"), i)
+}
+
+// Write the data shards to the data files belonging to filename, removing
+// data files left behind by previous generations with more shards.
+func (x *Generator) writeDataFiles(filename string, shards *dataShards) error {
+	n := 0
+
+	if shards != nil {
+		n = len(shards.shards)
+	}
+
+	for i := 0; i < n; i++ {
+		data, err := x.dataFile(shards.shards[i])
+
+		if err != nil {
+			return err
+		}
+
+		if err := writeFileAtomic(dataFilename(filename, i+1), data); err != nil {
+			return err
+		}
+	}
+
+	prefix := strings.TrimSuffix(filename, ".go") + "_data"
+	existing, _ := filepath.Glob(prefix + "*.go")
+
+	for _, f := range existing {
+		i, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(f, prefix), ".go"))
+
+		if err != nil || i <= n {
+			continue
+		}
+
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get the name of the generated package.
+func (x *Generator) packageName() string {
+	if len(x.PackageName) == 0 && len(x.inferredPackage) != 0 {
+		return x.inferredPackage
+	} else if len(x.PackageName) == 0 {
+		return "main"
+	}
+
+	return x.PackageName
+}
+
+// Format text as a comment block written before the package clause of a
+// generated file, separated by an empty line such that it does not become
+// the package documentation.
+func commentBlock(text string) string {
+	text = strings.TrimRight(text, "\n")
+
+	if len(text) == 0 {
+		return ""
+	}
+
+	var buf strings.Builder
+
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			buf.WriteString(line)
+		} else if len(line) == 0 {
+			buf.WriteString("//")
+		} else {
+			buf.WriteString("// " + line)
+		}
+
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("\n")
+	return buf.String()
+}
@@ -0,0 +1,96 @@
+package assets
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// The error returned by VerifySignature for file systems which have not been
+// signed, see Generator.SigningKey.
+var ErrNotSigned = errors.New("asset file system is not signed")
+
+// The error returned by VerifySignature when the signature does not match the
+// assets of the file system.
+var ErrInvalidSignature = errors.New("asset file system signature is invalid")
+
+// A file covered by the signature of a file system, in its stored form. The
+// compression of uncompressed files is NoCompression.
+type signedFile struct {
+	mode        os.FileMode
+	compression Compression
+	encrypted   bool
+
+	// The SHA-256 digest of the stored data
+	sum [sha256.Size]byte
+}
+
+// Get the SHA-256 digest signed by Generator.SigningKey. The digest covers
+// the paths, modes and stored data of all files and the asset keys. Data is
+// covered as stored (i.e. compressed or encrypted), such that verifying the
+// signature does not require decompressing or decrypting assets. Directories
+// are derived from the files and are not covered themselves.
+func signedDigest(files map[string]signedFile, keys map[string]string) []byte {
+	h := sha256.New()
+
+	for _, p := range sortedKeys(files) {
+		f := files[p]
+		fmt.Fprintf(h, "file %q %d %d %t %x\n", p, f.mode, f.compression, f.encrypted, f.sum)
+	}
+
+	for _, key := range sortedKeys(keys) {
+		fmt.Fprintf(h, "key %q %q\n", key, keys[key])
+	}
+
+	return h.Sum(nil)
+}
+
+// Verify that the assets of the file system have been signed by the private
+// key belonging to key (see Generator.SigningKey) and have not been tampered
+// with since. Unlike Verify, this detects deliberate modifications of the
+// assets (e.g. of an embedded web UI or configuration), provided that key
+// itself is obtained from a trusted source. File systems which have not been
+// signed fail with ErrNotSigned.
+func (f *FileSystem) VerifySignature(key ed25519.PublicKey) error {
+	if f.packErr != nil {
+		return f.packErr
+	}
+
+	if len(f.Signature) == 0 {
+		return ErrNotSigned
+	}
+
+	if len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid public key size %d", len(key))
+	}
+
+	files := make(map[string]signedFile)
+
+	for p, fi := range f.Files {
+		if fi.IsDir() {
+			continue
+		}
+
+		sum, err := fi.storedSum()
+
+		if err != nil {
+			return err
+		}
+
+		sf := signedFile{mode: fi.FileMode, compression: NoCompression, encrypted: fi.Encrypted, sum: sum}
+
+		if fi.compressed() {
+			sf.compression = fi.compression()
+		}
+
+		files[p] = sf
+	}
+
+	if !ed25519.Verify(key, signedDigest(files, f.Keys), f.Signature) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,239 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+)
+
+// An implementation of the snappy block format
+// (https://github.com/google/snappy/blob/main/format_description.txt).
+// Snappy compresses less than gzip, but decompresses several times faster,
+// which matters for assets decompressed on every use.
+
+var errSnappyCorrupt = errors.New("snappy: corrupt input")
+
+const (
+	snappyTagLiteral = 0x00
+	snappyTagCopy1   = 0x01
+	snappyTagCopy2   = 0x02
+	snappyTagCopy4   = 0x03
+
+	// The size of the blocks compressed independently, such that copy
+	// offsets always fit in two bytes
+	snappyBlockSize = 1 << 16
+
+	snappyTableBits = 14
+)
+
+var snappyCodec = Codec{
+	Compress: func(data []byte, level int) ([]byte, error) {
+		return snappyEncode(data), nil
+	},
+	Decompress: func(r io.Reader) (io.ReadCloser, error) {
+		src, err := ioutil.ReadAll(r)
+
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := snappyDecode(src)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	},
+}
+
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(make([]byte, 0, len(src)/2+16), uint64(len(src)))
+
+	for len(src) > 0 {
+		block := src
+
+		if len(block) > snappyBlockSize {
+			block = block[:snappyBlockSize]
+		}
+
+		dst = snappyEncodeBlock(dst, block)
+		src = src[len(block):]
+	}
+
+	return dst
+}
+
+func snappyHash(v uint32) uint32 {
+	return (v * 0x1e35a7bd) >> (32 - snappyTableBits)
+}
+
+func snappyEncodeBlock(dst []byte, src []byte) []byte {
+	// Positions (plus one) of previous occurrences of four byte sequences
+	var table [1 << snappyTableBits]int32
+
+	lit := 0
+
+	for i := 0; i+4 <= len(src); {
+		v := binary.LittleEndian.Uint32(src[i:])
+		h := snappyHash(v)
+		candidate := int(table[h]) - 1
+		table[h] = int32(i + 1)
+
+		if candidate < 0 || binary.LittleEndian.Uint32(src[candidate:]) != v {
+			i++
+			continue
+		}
+
+		n := 4
+
+		for i+n < len(src) && src[candidate+n] == src[i+n] {
+			n++
+		}
+
+		dst = snappyEmitLiteral(dst, src[lit:i])
+		dst = snappyEmitCopy(dst, i-candidate, n)
+
+		i += n
+		lit = i
+	}
+
+	return snappyEmitLiteral(dst, src[lit:])
+}
+
+func snappyEmitLiteral(dst []byte, lit []byte) []byte {
+	if len(lit) == 0 {
+		return dst
+	}
+
+	n := uint32(len(lit) - 1)
+
+	switch {
+	case n < 60:
+		dst = append(dst, byte(n)<<2|snappyTagLiteral)
+	case n < 1<<8:
+		dst = append(dst, 60<<2|snappyTagLiteral, byte(n))
+	case n < 1<<16:
+		dst = append(dst, 61<<2|snappyTagLiteral, byte(n), byte(n>>8))
+	case n < 1<<24:
+		dst = append(dst, 62<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16))
+	default:
+		dst = append(dst, 63<<2|snappyTagLiteral, byte(n), byte(n>>8), byte(n>>16), byte(n>>24))
+	}
+
+	return append(dst, lit...)
+}
+
+func snappyEmitCopy(dst []byte, offset int, length int) []byte {
+	// Emit copies of at most 64 bytes, making sure the remainder is at
+	// least 4 bytes long
+	for length >= 68 {
+		dst = append(dst, 63<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 64
+	}
+
+	if length > 64 {
+		dst = append(dst, 59<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+		length -= 60
+	}
+
+	if length >= 12 || offset >= 2048 {
+		return append(dst, byte(length-1)<<2|snappyTagCopy2, byte(offset), byte(offset>>8))
+	}
+
+	return append(dst, byte(offset>>8)<<5|byte(length-4)<<2|snappyTagCopy1, byte(offset))
+}
+
+func snappyDecode(src []byte) ([]byte, error) {
+	n, s := binary.Uvarint(src)
+
+	// Each element decodes to at most 64 bytes per 3 input bytes
+	if s <= 0 || n > 0xffffffff || n > uint64(len(src))*22 {
+		return nil, errSnappyCorrupt
+	}
+
+	dst := make([]byte, 0, n)
+
+	for s < len(src) {
+		var length, offset int
+
+		tag := src[s]
+
+		switch tag & 0x03 {
+		case snappyTagLiteral:
+			x := int(tag >> 2)
+			s++
+
+			if x >= 60 {
+				nb := x - 59
+
+				if s+nb > len(src) {
+					return nil, errSnappyCorrupt
+				}
+
+				x = 0
+
+				for i := nb - 1; i >= 0; i-- {
+					x = x<<8 | int(src[s+i])
+				}
+
+				s += nb
+			}
+
+			length = x + 1
+
+			if length <= 0 || s+length > len(src) || uint64(len(dst)+length) > n {
+				return nil, errSnappyCorrupt
+			}
+
+			dst = append(dst, src[s:s+length]...)
+			s += length
+			continue
+		case snappyTagCopy1:
+			if s+2 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+
+			length = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[s+1])
+			s += 2
+		case snappyTagCopy2:
+			if s+3 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[s+1:]))
+			s += 3
+		case snappyTagCopy4:
+			if s+5 > len(src) {
+				return nil, errSnappyCorrupt
+			}
+
+			length = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[s+1:]))
+			s += 5
+		}
+
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+length) > n {
+			return nil, errSnappyCorrupt
+		}
+
+		if start := len(dst) - offset; offset >= length {
+			dst = append(dst, dst[start:start+length]...)
+		} else {
+			// Overlapping copies repeat their own output, copy byte by byte
+			for i := 0; i < length; i++ {
+				dst = append(dst, dst[start+i])
+			}
+		}
+	}
+
+	if uint64(len(dst)) != n {
+		return nil, errSnappyCorrupt
+	}
+
+	return dst, nil
+}
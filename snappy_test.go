@@ -0,0 +1,72 @@
+package assets
+
+import (
+	"bytes"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestSnappyDecode(t *testing.T) {
+	// A literal "hello " followed by a copy of 5 bytes at offset 6
+	src := []byte("\x0b\x14hello \x05\x06")
+
+	data, err := snappyDecode(src)
+
+	if err != nil || string(data) != "hello hello" {
+		t.Errorf("expected %q, got %q (%v)", "hello hello", data, err)
+	}
+
+	for _, corrupt := range []string{"", "\x0b\x14hello \x05\x07", "\x0c\x14hello \x05\x06", "\x0b\x14hel"} {
+		if _, err := snappyDecode([]byte(corrupt)); err == nil {
+			t.Errorf("expected %q to be corrupt", corrupt)
+		}
+	}
+}
+
+func TestSnappyRoundtrip(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	random := make([]byte, 200000)
+	rnd.Read(random)
+
+	inputs := [][]byte{
+		nil,
+		[]byte("a"),
+		[]byte(strings.Repeat("a", 1000)),
+		[]byte(strings.Repeat("<div class=\"item\">{{.Name}}</div>\n", 5000)),
+		random,
+	}
+
+	for _, input := range inputs {
+		compressed := snappyEncode(input)
+		data, err := snappyDecode(compressed)
+
+		if err != nil || !bytes.Equal(data, input) {
+			t.Errorf("roundtrip of %d bytes failed (%v)", len(input), err)
+		}
+	}
+
+	if compressed := snappyEncode(inputs[3]); len(compressed) > len(inputs[3])/10 {
+		t.Errorf("expected repetitive input to compress well, got %d of %d bytes", len(compressed), len(inputs[3]))
+	}
+}
+
+func TestCompressionSnappy(t *testing.T) {
+	g := &Generator{StripPrefix: "/testdata", Compressed: true, Compression: Snappy}
+
+	if err := g.Add("testdata"); err != nil {
+		t.Fatal(err)
+	}
+
+	fss, err := Parse(generate(t, g))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "console.log(\"hello\");\n"
+
+	if data, err := fss["Assets"].ReadFile("/static/app.js"); err != nil || string(data) != expected {
+		t.Errorf("expected %q, got %q (%v)", expected, data, err)
+	}
+}
@@ -0,0 +1,115 @@
+package assets
+
+import (
+	"fmt"
+)
+
+// An option configuring the generator used by Snapshot.
+type SnapshotOption func(g *Generator)
+
+// Build an in-memory file system of the assets in the generator, without
+// generating any code. Groups and test-only assets are not included, use
+// Group(name).FileSystem() for groups.
+func (x *Generator) FileSystem() (*FileSystem, error) {
+	x = x.partition(false)
+
+	if err := x.checkLicenses(); err != nil {
+		return nil, err
+	}
+
+	maxModTime, err := x.maxModTime()
+
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := x.virtualDirs()
+	files := make(map[string]*File)
+
+	paths := x.filePaths()
+	stored, err := x.readAll(paths)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data := make(map[string]storedFile, len(paths))
+
+	for i, k := range paths {
+		if stored[i].err != nil {
+			return nil, stored[i].err
+		}
+
+		data[k] = stored[i]
+	}
+
+	for _, k := range x.sortedPaths() {
+		v := x.fsFilesMap[k]
+		kk, ok := x.virtualPath(k)
+
+		if !ok {
+			continue
+		}
+
+		f := &File{
+			Path:     kk,
+			FileMode: x.fileMode(k, v.info),
+			Mtime:    x.modTime(v.info, maxModTime),
+			Tags:     x.tags(k),
+		}
+
+		m := x.meta(k)
+		f.Title, f.Order = m.title, m.order
+
+		if stored, ok := data[k]; ok {
+			f.Data = stored.data
+			f.Compressed = stored.compressed
+			f.Compression = stored.compression
+			f.Encrypted = stored.encrypted
+		}
+
+		files[kk] = f
+	}
+
+	fs := NewFileSystem(dirs, files, "")
+
+	if key := x.EncryptionKey; len(key) != 0 {
+		fs.DecryptionKey = func() ([]byte, error) { return key, nil }
+	}
+
+	for key, p := range x.keys {
+		p = x.Normalization.normalize(p)
+
+		if _, ok := files[p]; !ok {
+			return nil, fmt.Errorf("asset key %q refers to non-existing asset %s", key, p)
+		}
+
+		if fs.Keys == nil {
+			fs.Keys = make(map[string]string)
+		}
+
+		fs.Keys[key] = p
+	}
+
+	return fs, nil
+}
+
+// Snapshot the contents of the directory dir into a new in-memory file system.
+// The contents of dir are mounted at the root of the file system. This gives
+// access to the runtime features of this package (handlers, manifests, etc.)
+// for assets which are only available at runtime (e.g. mounted from
+// configuration) without generating any code. The options configure the
+// generator used to build the snapshot, for example to set Exclude or Tags.
+func Snapshot(dir string, opts ...SnapshotOption) (*FileSystem, error) {
+	g := &Generator{}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	if err := g.AddRoot(Root{Dir: dir}); err != nil {
+		return nil, err
+	}
+
+	return g.FileSystem()
+}
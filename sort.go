@@ -0,0 +1,222 @@
+package assets
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The ordering used when sorting asset names.
+type Ordering int
+
+const (
+	// Sort names lexically, byte by byte.
+	LexicalOrder Ordering = iota
+
+	// Sort names comparing runs of digits by their numeric value, such that
+	// 2_add_users.sql sorts before 10_add_index.sql.
+	NumericOrder
+
+	// Sort names by a leading semantic version (e.g. v1.2.0_init.sql or
+	// 1.10.0-rc.1.sql). Pre-release versions sort before their release and
+	// names without a version sort after all versioned names.
+	SemverOrder
+)
+
+// Get the paths of the files in the directory dir, sorted lexically by name.
+// Sub directories are not included. This provides the guaranteed ordering
+// required by consumers such as database migrations.
+func SortedByName(fs *FileSystem, dir string) ([]string, error) {
+	return Sorted(fs, dir, LexicalOrder)
+}
+
+// Get the paths of the files in the directory dir, sorted by name using the
+// given ordering. Sub directories are not included.
+func Sorted(fs *FileSystem, dir string, ordering Ordering) ([]string, error) {
+	d, err := fs.Open(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := d.Readdir(-1)
+	d.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+
+	var less func(a, b string) bool
+
+	switch ordering {
+	case NumericOrder:
+		less = numericLess
+	case SemverOrder:
+		less = semverLess
+	default:
+		less = func(a, b string) bool { return a < b }
+	}
+
+	sort.SliceStable(names, func(i, j int) bool {
+		return less(names[i], names[j])
+	})
+
+	ret := make([]string, len(names))
+
+	for i, name := range names {
+		ret[i] = path.Join("/", dir, name)
+	}
+
+	return ret, nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func digitRun(s string) string {
+	i := 0
+
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+
+	return s[:i]
+}
+
+func compareNumeric(a, b string) int {
+	// Compare digit runs by value without risking overflow
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func numericLess(a, b string) bool {
+	i, j := 0, 0
+
+	for i < len(a) && j < len(b) {
+		if isDigit(a[i]) && isDigit(b[j]) {
+			da, db := digitRun(a[i:]), digitRun(b[j:])
+
+			if c := compareNumeric(da, db); c != 0 {
+				return c < 0
+			}
+
+			i += len(da)
+			j += len(db)
+		} else if a[i] != b[j] {
+			return a[i] < b[j]
+		} else {
+			i++
+			j++
+		}
+	}
+
+	if len(a)-i != len(b)-j {
+		return len(a)-i < len(b)-j
+	}
+
+	return a < b
+}
+
+type semver struct {
+	parts      [3]string
+	prerelease string
+}
+
+func parseSemver(name string) (semver, bool) {
+	var v semver
+
+	s := strings.TrimPrefix(name, "v")
+
+	for i := 0; i < 3; i++ {
+		d := digitRun(s)
+
+		if len(d) == 0 {
+			if i == 0 {
+				return v, false
+			}
+
+			break
+		}
+
+		v.parts[i] = d
+		s = s[len(d):]
+
+		if i < 2 {
+			if !strings.HasPrefix(s, ".") || len(digitRun(s[1:])) == 0 {
+				break
+			}
+
+			s = s[1:]
+		}
+	}
+
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+
+		end := strings.IndexAny(s, "_+")
+
+		if end < 0 {
+			end = len(s)
+		}
+
+		// Do not treat the file extension as part of the pre-release
+		if i := strings.LastIndex(s[:end], "."); i >= 0 && i == strings.LastIndex(s, ".") {
+			if _, err := strconv.Atoi(s[i+1 : end]); err != nil {
+				end = i
+			}
+		}
+
+		v.prerelease = s[:end]
+	}
+
+	return v, true
+}
+
+func semverLess(a, b string) bool {
+	va, oka := parseSemver(a)
+	vb, okb := parseSemver(b)
+
+	if !oka || !okb {
+		if oka != okb {
+			return oka
+		}
+
+		return numericLess(a, b)
+	}
+
+	for i := 0; i < 3; i++ {
+		if c := compareNumeric(va.parts[i], vb.parts[i]); c != 0 {
+			return c < 0
+		}
+	}
+
+	if va.prerelease != vb.prerelease {
+		if len(va.prerelease) == 0 || len(vb.prerelease) == 0 {
+			return len(vb.prerelease) == 0
+		}
+
+		return numericLess(va.prerelease, vb.prerelease)
+	}
+
+	return numericLess(a, b)
+}
@@ -0,0 +1,401 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"path"
+	"reflect"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// The number of largest files reported in Stats.
+const StatsLargestFiles = 10
+
+// The size of a single embedded file.
+type FileSize struct {
+	// The path of the file in the generated file system.
+	Path string
+
+	// The raw size of the file in bytes.
+	Size int64
+
+	// The size of the file as stored in the generated file.
+	StoredSize int64
+}
+
+// Statistics of a generation run.
+type Stats struct {
+	// The number of embedded files.
+	Files int
+
+	// The total raw size of the embedded files in bytes.
+	Size int64
+
+	// The total size of the embedded files as stored in the generated
+	// file (i.e. compressed if compression is enabled).
+	StoredSize int64
+
+	// The largest embedded files (by raw size), in descending order.
+	Largest []FileSize
+
+	// The time it took to generate the file.
+	Elapsed time.Duration
+
+	// The directories added without their contents, see
+	// Generator.MaxDepth.
+	Truncated []string
+}
+
+func (s *Stats) add(p string, size int64, storedSize int64) {
+	s.Files++
+	s.Size += size
+	s.StoredSize += storedSize
+
+	i := sort.Search(len(s.Largest), func(i int) bool {
+		return s.Largest[i].Size < size
+	})
+
+	if i < StatsLargestFiles {
+		s.Largest = append(s.Largest, FileSize{})
+		copy(s.Largest[i+1:], s.Largest[i:])
+		s.Largest[i] = FileSize{Path: p, Size: size, StoredSize: storedSize}
+
+		if len(s.Largest) > StatsLargestFiles {
+			s.Largest = s.Largest[:StatsLargestFiles]
+		}
+	}
+}
+
+// Print a human readable statistics report.
+func (s *Stats) Print(w io.Writer) error {
+	ratio := 100.0
+
+	if s.Size != 0 {
+		ratio = float64(s.StoredSize) / float64(s.Size) * 100
+	}
+
+	fmt.Fprintf(w, "files:   %d\n", s.Files)
+	fmt.Fprintf(w, "size:    %d bytes\n", s.Size)
+	fmt.Fprintf(w, "stored:  %d bytes (%.1f%%)\n", s.StoredSize, ratio)
+	fmt.Fprintf(w, "elapsed: %s\n", s.Elapsed)
+
+	for _, d := range s.Truncated {
+		fmt.Fprintf(w, "truncated: %s (see MaxDepth)\n", d)
+	}
+
+	if len(s.Largest) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "LARGEST\tSIZE\tSTORED")
+
+	for _, f := range s.Largest {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", f.Path, f.Size, f.StoredSize)
+	}
+
+	return tw.Flush()
+}
+
+// Get the statistics of the last successful Write, or nil if the generator
+// has not been written yet.
+func (x *Generator) Stats() *Stats {
+	return x.stats
+}
+
+// The size of the assets originating from a single source directory.
+type DirectorySize struct {
+	// The source directory.
+	Dir string
+
+	// The number of files in the directory.
+	Files int
+
+	// The raw size of the files in bytes.
+	Size int64
+
+	// The size of the files as stored in the generated file (i.e.
+	// compressed if compression is enabled).
+	StoredSize int64
+
+	// The change in stored size compared to a previously generated file.
+	Delta int64
+}
+
+// Attribute the stored size of the assets to the source directories they
+// originate from. Files are attributed to their immediate directory. If
+// previous is not empty, it names a previously generated asset file and
+// the returned sizes include the change in stored size relative to it, such
+// that size regressions can be pinpointed to the directory that introduced
+// them. Directories are sorted by descending stored size.
+func (x *Generator) DirectorySizes(previous string) ([]DirectorySize, error) {
+	dirs := make(map[string]*DirectorySize)
+
+	dir := func(p string) *DirectorySize {
+		d := path.Dir(p)
+
+		if _, ok := dirs[d]; !ok {
+			dirs[d] = &DirectorySize{Dir: d}
+		}
+
+		return dirs[d]
+	}
+
+	for _, k := range x.sortedPaths() {
+		f := x.fsFilesMap[k]
+
+		if f.info.IsDir() {
+			continue
+		}
+
+		if _, ok := x.virtualPath(k); !ok {
+			continue
+		}
+
+		stored, err := x.storedData(k)
+
+		if err != nil {
+			return nil, err
+		}
+
+		data := stored.data
+
+		d := dir(k)
+		d.Files++
+		d.Size += f.info.Size()
+		d.StoredSize += int64(len(data))
+		d.Delta += int64(len(data))
+	}
+
+	if len(previous) != 0 {
+		fss, err := ParseFile(previous)
+
+		if err != nil {
+			return nil, err
+		}
+
+		variableName := x.VariableName
+
+		if len(variableName) == 0 {
+			variableName = "Assets"
+		}
+
+		if fs, ok := fss[variableName]; ok {
+			for p, f := range fs.Files {
+				if !f.IsDir() {
+					// Map back from the virtual path to the generator path
+					dir(path.Join("/", x.StripPrefix, p)).Delta -= int64(len(f.Data))
+				}
+			}
+		}
+	}
+
+	ret := make([]DirectorySize, 0, len(dirs))
+
+	for _, d := range dirs {
+		ret = append(ret, *d)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].StoredSize != ret[j].StoredSize {
+			return ret[i].StoredSize > ret[j].StoredSize
+		}
+
+		return ret[i].Dir < ret[j].Dir
+	})
+
+	return ret, nil
+}
+
+// Write a human readable table of directory sizes.
+func WriteDirectorySizes(w io.Writer, sizes []DirectorySize) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "DIRECTORY\tFILES\tSIZE\tSTORED\tDELTA")
+
+	for _, d := range sizes {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%+d\n", d.Dir, d.Files, d.Size, d.StoredSize, d.Delta)
+	}
+
+	return tw.Flush()
+}
+
+// The contribution of a single asset to the size of the executable the
+// generated file is built into.
+type AssetSize struct {
+	// The variable name of the file system of the asset.
+	FileSystem string
+
+	// The path of the asset in the generated file system.
+	Path string
+
+	// The size of the asset data in the executable (i.e. compressed if
+	// compression is enabled). Assets with the same contents as an asset
+	// reported earlier share its data and do not contribute to it.
+	DataSize int64
+
+	// The estimated size of the entry describing the asset (its path,
+	// digest, tags and fixed size fields).
+	EntrySize int64
+}
+
+// Get the total contribution of the asset to the size of the executable.
+func (s AssetSize) Size() int64 {
+	return s.DataSize + s.EntrySize
+}
+
+// The fixed size of an entry in the generated file system table.
+var fileEntrySize = int64(reflect.TypeOf(FileEntry{}).Size())
+
+// Estimate the size of an entry with the given path, digest and tags.
+func entrySize(p string, digest string, tags []string) int64 {
+	ret := fileEntrySize + int64(len(p)+len(digest))
+
+	for _, tag := range tags {
+		ret += int64(len(tag))
+	}
+
+	return ret
+}
+
+// Sort asset sizes by descending total size, and by file system and path
+// for equal sizes.
+func sortAssetSizes(sizes []AssetSize) {
+	sort.Slice(sizes, func(i, j int) bool {
+		if sizes[i].Size() != sizes[j].Size() {
+			return sizes[i].Size() > sizes[j].Size()
+		}
+
+		if sizes[i].FileSystem != sizes[j].FileSystem {
+			return sizes[i].FileSystem < sizes[j].FileSystem
+		}
+
+		return sizes[i].Path < sizes[j].Path
+	})
+}
+
+// Attribute the size the generated file adds to the executable to the
+// individual assets of the generator and its groups, sorted by descending
+// size, such that the assets bloating the executable can be found before
+// writing the file. Data written to a PackFile is not part of the
+// executable, unless it is appended (see AppendPack). Data encoded with
+// Base64 is reported at its encoded size.
+func (x *Generator) AssetSizes() ([]AssetSize, error) {
+	var ret []AssetSize
+
+	contents := make(map[string]bool)
+
+	for _, g := range x.fileSystems() {
+		variableName := g.VariableName
+
+		if len(variableName) == 0 {
+			variableName = "Assets"
+		}
+
+		if !x.SharedData {
+			contents = make(map[string]bool)
+		}
+
+		paths := g.filePaths()
+
+		err := g.eachStored(paths, func(i int, sf storedFile) error {
+			k := paths[i]
+
+			if sf.err != nil {
+				return nil
+			}
+
+			vp, _ := g.virtualPath(k)
+
+			if len(sf.fingerprint) != 0 {
+				vp = fingerprintPath(vp, sf.fingerprint)
+			}
+
+			size := AssetSize{
+				FileSystem: variableName,
+				Path:       vp,
+				EntrySize:  entrySize(vp, sf.digest, g.tags(k)),
+			}
+
+			// Identical contents share a single variable, see
+			// outputFileSystem
+			digest := g.digest(sf.data)
+
+			if !contents[digest] && (len(g.PackFile) == 0 || g.AppendPack) {
+				size.DataSize = int64(len(sf.data))
+
+				if g.Base64 {
+					size.DataSize = int64(base64.StdEncoding.EncodedLen(len(sf.data)))
+				}
+			}
+
+			contents[digest] = true
+			ret = append(ret, size)
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sortAssetSizes(ret)
+	return ret, nil
+}
+
+// Attribute the size of the file system (e.g. parsed from a generated file
+// using ParseFile) to its individual assets, sorted by descending size.
+// Assets with identical stored data are assumed to share it. The FileSystem
+// field of the returned sizes is left empty.
+func (f *FileSystem) AssetSizes() []AssetSize {
+	var ret []AssetSize
+
+	contents := make(map[[sha256.Size]byte]bool)
+
+	for _, p := range sortedKeys(f.Files) {
+		fi := f.Files[p]
+
+		if fi.IsDir() {
+			continue
+		}
+
+		size := AssetSize{
+			Path:      p,
+			EntrySize: entrySize(p, fi.Digest, fi.Tags),
+		}
+
+		// Data which cannot be read is not assumed to be shared
+		sum, err := fi.storedSum()
+
+		if err != nil || !contents[sum] {
+			size.DataSize = fi.Size()
+		}
+
+		if err == nil {
+			contents[sum] = true
+		}
+		ret = append(ret, size)
+	}
+
+	sortAssetSizes(ret)
+	return ret
+}
+
+// Write a human readable table of asset sizes.
+func WriteAssetSizes(w io.Writer, sizes []AssetSize) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "SIZE\tDATA\tENTRY\tFILESYSTEM\tPATH")
+
+	for _, s := range sizes {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%s\n", s.Size(), s.DataSize, s.EntrySize, s.FileSystem, s.Path)
+	}
+
+	return tw.Flush()
+}
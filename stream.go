@@ -0,0 +1,118 @@
+package assets
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The default number of bytes written to a client between flushes when
+// streaming an asset.
+const DefaultStreamChunkSize = 32 * 1024
+
+// Options for streaming an asset to an HTTP client.
+type StreamOptions struct {
+	// The number of bytes written between flushes (defaults to
+	// DefaultStreamChunkSize),
+	ChunkSize int
+
+	// When non-zero, the write deadline of the connection is extended by
+	// this duration before each chunk is written. Slow clients are then
+	// only cut off when they stop making progress, instead of when the
+	// whole transfer exceeds the server write timeout,
+	WriteTimeout time.Duration
+}
+
+// Stream the (decompressed) asset data to the given response writer. The data
+// is written in chunks and the response is flushed after each chunk so that
+// large downloads start immediately. Compressed assets are decompressed while
+// streaming; since their size is not known up front, the response will then
+// use chunked transfer encoding. Flushing and write deadlines are silently
+// skipped when the response writer does not support them. Stream returns the
+// number of bytes written.
+func (f *File) Stream(w http.ResponseWriter, opts StreamOptions) (int64, error) {
+	rd, err := f.Reader()
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer rd.Close()
+
+	chunkSize := opts.ChunkSize
+
+	if chunkSize <= 0 {
+		chunkSize = DefaultStreamChunkSize
+	}
+
+	if size, ok := streamSize(f, rd); ok && len(w.Header().Get("Content-Length")) == 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+
+	rc := http.NewResponseController(w)
+	buf := make([]byte, chunkSize)
+
+	var written int64
+
+	for {
+		n := 0
+		var rerr error
+
+		for n < len(buf) && rerr == nil {
+			var nr int
+
+			nr, rerr = rd.Read(buf[n:])
+			n += nr
+		}
+
+		if n > 0 {
+			if opts.WriteTimeout != 0 {
+				if err := rc.SetWriteDeadline(time.Now().Add(opts.WriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return written, err
+				}
+			}
+
+			nw, err := w.Write(buf[:n])
+			written += int64(nw)
+
+			if err != nil {
+				return written, err
+			}
+
+			if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+				return written, err
+			}
+		}
+
+		if rerr == io.EOF {
+			return written, nil
+		} else if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// Get the size of the data streamed from rd, the reader of the asset f, if it
+// is known up front. Assets of development file systems (see
+// NewDevFileSystem) are read from their source file, whose size is taken from
+// the opened file.
+func streamSize(f *File, rd io.Reader) (int64, bool) {
+	if fd, ok := rd.(*os.File); ok {
+		info, err := fd.Stat()
+
+		if err != nil {
+			return 0, false
+		}
+
+		return info.Size(), true
+	}
+
+	if f.compressed() || f.Encrypted {
+		return 0, false
+	}
+
+	return f.Size(), true
+}
@@ -0,0 +1,116 @@
+package assets
+
+import (
+	"text/template"
+)
+
+// The data the template of generated files is executed with, see
+// Generator.Template. The code is formatted after executing the template.
+type OutputFile struct {
+	// The generated code marker followed by the banner (see
+	// Generator.Banner), including the empty line separating it from the
+	// rest of the file.
+	Banner string
+
+	// The build constraint of the file (see Generator.DevTag), or empty.
+	Constraint string
+
+	// The name of the generated package.
+	Package string
+
+	// The header comment (see Generator.Header), including the empty line
+	// separating it from the package clause, or empty.
+	Header string
+
+	// The import block, followed by the inlined runtime for self-contained
+	// files (see Generator.SelfContained).
+	Imports string
+
+	// The file systems defined in the file.
+	FileSystems []*OutputFileSystem
+
+	// Declarations following the file systems, such as the digest of the
+	// pack file or the embedded directory.
+	Footer string
+}
+
+// A file system of a generated file, see OutputFile.
+type OutputFileSystem struct {
+	// The name of the variable holding the file system.
+	VariableName string
+
+	// A marker comment which is replaced by the declarations of the asset
+	// data after formatting the file, such that the data is streamed to the
+	// output instead of being formatted. Empty when the data is written
+	// elsewhere, such as to data files or a pack file. Templates must write
+	// the marker on a line of its own, Write fails otherwise.
+	Data string
+
+	// The declarations of the asset path constants, see
+	// Generator.PathConstants, or empty.
+	Paths string
+
+	// The type of the file entries.
+	EntryType string
+
+	// The file entries of the file system.
+	Entries []OutputEntry
+
+	// The expression creating the file system from its file entries, which
+	// are declared in _<VariableName>Entries.
+	Constructor string
+
+	// Statements initializing the file system, executed in an init function.
+	Init []string
+
+	// The declarations of the asset accessor functions, see
+	// Generator.Accessors, or empty.
+	Accessors string
+}
+
+// A file entry of a generated file system, see OutputFileSystem.
+type OutputEntry struct {
+	// The path of the asset.
+	Path string
+
+	// The composite literal of the entry.
+	Literal string
+}
+
+// The default template of generated files, see Generator.Template. The
+// template can be cloned and its parts redefined, or used as a starting point
+// for a custom template.
+var DefaultTemplate = template.Must(template.New("assets").Parse(`{{.Banner}}{{if .Constraint}}//go:build {{.Constraint}}
+
+{{end}}{{.Header}}package {{.Package}}
+
+{{.Imports}}
+{{- range .FileSystems}}
+
+{{.Data}}{{.Paths}}
+var _{{.VariableName}}Entries = [...]{{.EntryType}}{
+{{- range .Entries}}
+	{{.Literal}},
+{{- end}}
+}
+
+// {{.VariableName}} returns go-assets FileSystem
+var {{.VariableName}} = {{.Constructor}}
+{{- if .Init}}
+
+func init() {
+{{- range .Init}}
+	{{.}}
+{{- end}}
+}
+{{- end}}
+{{- if .Accessors}}
+
+{{.Accessors}}
+{{- end}}
+{{- end}}
+{{- if .Footer}}
+
+{{.Footer}}
+{{- end}}
+`))
@@ -0,0 +1,195 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+// Check whether the asset at the virtual path vp is test-only, i.e. whether
+// it or any of its parent directories matches one of the TestOnly patterns.
+func (x *Generator) testOnly(vp string) bool {
+	for d := vp; d != "/" && d != "."; d = path.Dir(d) {
+		for _, pattern := range x.TestOnly {
+			if matchPattern(pattern, d) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Get a generator for either only the test-only assets of x, or only its
+// other assets. The file system of test-only assets is named after the
+// variable name of x, with a Test suffix.
+func (x *Generator) partition(test bool) *Generator {
+	if len(x.TestOnly) == 0 && !test {
+		return x
+	}
+
+	g := *x
+
+	g.fsFilesMap = make(map[string]file)
+	g.fsDirsMap = make(map[string][]string)
+	g.fsDirsIndex = nil
+	g.groups = nil
+	g.sources = nil
+	g.keys = nil
+
+	if test {
+		if len(g.VariableName) == 0 {
+			g.VariableName = "Assets"
+		}
+
+		g.VariableName += "Test"
+	}
+
+	// Add an asset and all of its parents
+	include := func(k string) {
+		for {
+			if _, ok := g.fsFilesMap[k]; ok {
+				return
+			}
+
+			f, ok := x.fsFilesMap[k]
+
+			if !ok {
+				return
+			}
+
+			g.fsFilesMap[k] = f
+
+			if f.info.IsDir() {
+				if _, ok := g.fsDirsMap[k]; !ok {
+					g.fsDirsMap[k] = []string{}
+				}
+			}
+
+			if k == "/" {
+				return
+			}
+
+			g.appendFileInDir(path.Dir(k), path.Base(k))
+			k = path.Dir(k)
+		}
+	}
+
+	for k, f := range x.fsFilesMap {
+		vp, ok := x.virtualPath(k)
+
+		if !ok {
+			continue
+		}
+
+		if f.info.IsDir() {
+			// Keep the root and empty directories with the other assets
+			if vp == "/" || (!test && len(x.fsDirsMap[k]) == 0 && !x.testOnly(vp)) {
+				include(k)
+			}
+		} else if x.testOnly(vp) == test {
+			include(k)
+		}
+	}
+
+	for key, p := range x.keys {
+		if x.testOnly(x.Normalization.normalize(p)) == test {
+			if g.keys == nil {
+				g.keys = make(map[string]string)
+			}
+
+			g.keys[key] = p
+		}
+	}
+
+	return &g
+}
+
+// Check whether the generator contains any files (not directories).
+func (x *Generator) hasFiles() bool {
+	for _, f := range x.fsFilesMap {
+		if !f.info.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the generators of the test-only assets of x and its groups.
+func (x *Generator) testGenerators() []*Generator {
+	var ret []*Generator
+
+	if len(x.TestOnly) == 0 {
+		return nil
+	}
+
+	for _, g := range append([]*Generator{x}, x.groups...) {
+		if t := g.partition(true); t.hasFiles() {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret
+}
+
+// Write the test-only assets (see TestOnly) to the given writer. The written
+// go file is meant to be saved as a _test.go file in the package of the
+// assets generated by Write, such that the test-only assets are only
+// compiled into tests. The file defines a file system for each file system
+// of the generator which has test-only assets, named after the file system
+// with a Test suffix (e.g. AssetsTest).
+func (x *Generator) WriteTest(wr io.Writer) error {
+	fss := x.testGenerators()
+
+	if len(fss) == 0 {
+		return fmt.Errorf("no test-only assets")
+	}
+
+	return x.write(wr, fss, &Stats{}, &dataOutput{test: true})
+}
+
+// Get the name of the file test-only assets are written to by WriteFile for
+// assets written to filename.
+func testFilename(filename string) string {
+	return strings.TrimSuffix(filename, ".go") + "_testonly_test.go"
+}
+
+// Write the test-only assets to the test file belonging to filename, if
+// there are any test-only assets. Otherwise, a test file left behind by a
+// previous generation is removed, since it would still define the test-only
+// file systems.
+func (x *Generator) writeTestFile(filename string) error {
+	if len(x.testGenerators()) == 0 {
+		return removeGeneratedFile(testFilename(filename))
+	}
+
+	var buf bytes.Buffer
+
+	if err := x.WriteTest(&buf); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(testFilename(filename), buf.Bytes())
+}
+
+// Remove the file at filename if it exists and was generated by go-assets.
+func removeGeneratedFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if !bytes.HasPrefix(data, []byte(generatedMarker+"\n")) {
+		return nil
+	}
+
+	return os.Remove(filename)
+}
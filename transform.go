@@ -0,0 +1,109 @@
+package assets
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// A transform of the contents of files before they are embedded, such as a
+// minifier, a SASS compiler or an SVG optimizer, see Generator.Transforms.
+type Transform struct {
+	// The name of the transform, used in error messages
+	Name string
+
+	// Glob patterns (see Exclude) of the files the transform applies to,
+	// matched against the paths of the generated file system. A transform
+	// without patterns applies to all files.
+	Patterns []string
+
+	// Transform the contents of the file at path (the path of the file in
+	// the generated file system). Files are read concurrently, Func must
+	// therefore be safe for concurrent use. The data passed to Func must not
+	// be modified.
+	Func func(path string, data []byte) ([]byte, error)
+}
+
+// Check whether the transform applies to the file at the virtual path vp.
+func (t Transform) matches(vp string) bool {
+	if len(t.Patterns) == 0 {
+		return true
+	}
+
+	for _, pattern := range t.Patterns {
+		if matchPattern(pattern, vp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the minifier of the file at the virtual path vp, see Minify.
+func (x *Generator) minifier(vp string) Minifier {
+	if !x.Minify || strings.Contains(path.Base(vp), ".min.") {
+		return nil
+	}
+
+	if m, ok := Minifiers[path.Ext(vp)]; ok {
+		return m
+	}
+
+	for _, pattern := range x.MinifyJS {
+		if matchPattern(pattern, vp) {
+			return minifyJS
+		}
+	}
+
+	return nil
+}
+
+// Check whether the contents of the file at path k are transformed or
+// minified, see Transforms and Minify.
+func (x *Generator) transformed(k string) bool {
+	vp, _ := x.virtualPath(k)
+
+	if x.minifier(vp) != nil {
+		return true
+	}
+
+	for _, t := range x.Transforms {
+		if t.matches(vp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Read the contents of the file at path k as they are embedded, i.e. after
+// applying the transforms matching the file in order and minifying it.
+func (x *Generator) read(k string) ([]byte, error) {
+	data, err := x.fsFilesMap[k].read()
+
+	if err != nil {
+		return nil, err
+	}
+
+	vp, _ := x.virtualPath(k)
+
+	for _, t := range x.Transforms {
+		if !t.matches(vp) {
+			continue
+		}
+
+		if data, err = t.Func(vp, data); err != nil {
+			return nil, fmt.Errorf("%s: transform %s: %s", vp, t.Name, err)
+		}
+	}
+
+	// Files which cannot be minified (e.g. using syntax the minifier does
+	// not understand) are embedded as is
+	if m := x.minifier(vp); m != nil {
+		if minified, err := m(data); err == nil {
+			data = minified
+		}
+	}
+
+	return data, nil
+}
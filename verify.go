@@ -0,0 +1,85 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"path"
+)
+
+// Verify the structure of the file system and that its asset data can be
+// read. Verify checks that directory listings and asset keys refer to
+// existing assets, that all assets are listed in their parent directory and
+// that the data of compressed assets can be decompressed. Asset data is only
+// checked against its checksum and digest when these are known (see
+// Generator.Checksums and Generator.Digests); without them, corrupted data
+// which still decompresses is not detected. The first problem found is
+// returned.
+func (f *FileSystem) Verify() error {
+	if f.packErr != nil {
+		return f.packErr
+	}
+
+	for _, p := range sortedKeys(f.Files) {
+		fi := f.Files[p]
+
+		if fi.Path != p {
+			return fmt.Errorf("asset %s has mismatching path %s", p, fi.Path)
+		}
+
+		if p != "/" {
+			if !containsString(f.Dirs[path.Dir(p)], path.Base(p)) {
+				return fmt.Errorf("asset %s is not listed in its directory", p)
+			}
+		}
+
+		if fi.IsDir() {
+			if _, ok := f.Dirs[p]; !ok {
+				return fmt.Errorf("directory %s has no listing", p)
+			}
+
+			continue
+		}
+
+		rd, err := fi.Reader()
+
+		var data []byte
+
+		if err == nil {
+			data, err = ioutil.ReadAll(rd)
+			rd.Close()
+		}
+
+		if err != nil {
+			return fmt.Errorf("asset %s is corrupt: %s", p, err)
+		}
+
+		if fi.Checksum != 0 && crc32.ChecksumIEEE(data) != fi.Checksum {
+			return fmt.Errorf("asset %s does not match its checksum", p)
+		}
+
+		if len(fi.Digest) != 0 {
+			if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != fi.Digest {
+				return fmt.Errorf("asset %s does not match its digest", p)
+			}
+		}
+	}
+
+	for _, d := range sortedKeys(f.Dirs) {
+		for _, name := range f.Dirs[d] {
+			if _, ok := f.Files[path.Join(d, name)]; !ok {
+				return fmt.Errorf("directory %s lists non-existing asset %s", d, name)
+			}
+		}
+	}
+
+	for _, key := range sortedKeys(f.Keys) {
+		if _, ok := f.Files[f.Keys[key]]; !ok {
+			return fmt.Errorf("asset key %q refers to non-existing asset %s", key, f.Keys[key])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package assets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// The default interval at which Watch polls the sources for changes.
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// The observed state of a source file, used to detect changes.
+type watchState struct {
+	size  int64
+	mode  os.FileMode
+	mtime time.Time
+}
+
+// Watch the files and directories added to the generator (and its groups)
+// with Add and AddRoot, as well as the inputs of bundles (see Bundle), and regenerate the assets to the file at outputPath
+// whenever they change. The assets are generated once initially, after which
+// the sources are polled every WatchInterval. Files and directories excluded
+// from the assets (see Exclude and Root) are not watched. Changes are debounced: the
+// assets are regenerated once the sources have not changed for a full
+// interval, such that a burst of changes (e.g. from a frontend build) results
+// in a single regeneration. Errors during regeneration are reported to
+// WatchOutput and do not stop watching. Watch returns when ctx is done.
+func (x *Generator) Watch(ctx context.Context, outputPath string) error {
+	return x.watch(ctx, outputPath, func(err error) {
+		if x.WatchOutput == nil {
+			return
+		}
+
+		if err != nil {
+			fmt.Fprintf(x.WatchOutput, "failed to regenerate %s: %s\n", outputPath, err)
+		} else {
+			fmt.Fprintf(x.WatchOutput, "regenerated %s\n", outputPath)
+		}
+	})
+}
+
+// Watch the inputs and roots of the config and regenerate the configured
+// output file whenever they change, see Generator.Watch. After each
+// regeneration, onRegenerate is called with the error of the regeneration
+// (nil on success), allowing tools to embed watching with their own
+// reporting (e.g. rebuilding or reloading after successful regeneration).
+// An error is returned if the initial generation fails, otherwise Watch
+// returns when ctx is done.
+func Watch(ctx context.Context, config *Config, onRegenerate func(err error)) error {
+	g, err := config.Generator()
+
+	if err != nil {
+		return err
+	}
+
+	return g.watch(ctx, config.Output, onRegenerate)
+}
+
+func (x *Generator) watch(ctx context.Context, outputPath string, onRegenerate func(err error)) error {
+	if err := x.WriteFile(outputPath); err != nil {
+		return err
+	}
+
+	interval := x.WatchInterval
+
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	generators := append([]*Generator{x}, x.groups...)
+	w := newWatcher(outputPath)
+	current := w.states(generators)
+	pending := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		states := w.states(generators)
+
+		if !watchStatesEqual(current, states) {
+			current = states
+			pending = true
+			continue
+		}
+
+		if !pending {
+			continue
+		}
+
+		pending = false
+
+		err := x.regenerate(outputPath)
+
+		if onRegenerate != nil {
+			onRegenerate(err)
+		}
+	}
+}
+
+// Rebuild the assets of the generator and its groups by adding all of their
+// sources again, then generate the assets into the file at filename.
+func (x *Generator) regenerate(filename string) error {
+	if err := x.reset(); err != nil {
+		return err
+	}
+
+	for _, g := range x.groups {
+		if err := g.reset(); err != nil {
+			return err
+		}
+	}
+
+	return x.WriteFile(filename)
+}
+
+// Reset the assets of the generator and add its sources again in the order
+// they were originally added.
+func (x *Generator) reset() error {
+	x.fsFilesMap = nil
+	x.fsDirsMap = nil
+	x.fsDirsIndex = nil
+	x.dirMeta = nil
+	x.failures = nil
+	x.truncatedDirs = nil
+	x.init()
+
+	for _, s := range x.sources {
+		if err := s.add(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// A poller of the state of the on-disk sources of generators. Polling stats
+// every watched file and directory, but only lists the contents of
+// directories whose modification time changed since they were last listed,
+// which keeps polling cheap for large source trees.
+//
+// Watching uses polling instead of file system notifications (e.g. inotify),
+// which are not available portably without dependencies and which are
+// unreliable on network and container mounted file systems, where assets
+// are commonly built.
+type watcher struct {
+	// The absolute path of the generated output file, which is not watched
+	output string
+
+	// The last listings of the watched directories
+	listings map[string]watchListing
+}
+
+// The listing of a watched directory.
+type watchListing struct {
+	mtime time.Time
+	read  time.Time
+	names []string
+}
+
+// The time after the modification time of a directory after which its
+// listing may be reused. Modification times have a limited resolution on some
+// file systems, a directory changed in the same tick as it was listed would
+// otherwise go unnoticed.
+const watchListingResolution = 2 * time.Second
+
+func newWatcher(outputPath string) *watcher {
+	output, _ := filepath.Abs(outputPath)
+
+	return &watcher{
+		output:   output,
+		listings: make(map[string]watchListing),
+	}
+}
+
+// Get the state of all files in the on-disk sources of the generators,
+// excluding the generated output file itself. Files excluded from their
+// source are not watched, just like they are not added.
+func (w *watcher) states(generators []*Generator) map[string]watchState {
+	ret := make(map[string]watchState)
+	listings := make(map[string]watchListing)
+
+	for _, g := range generators {
+		for _, s := range g.sources {
+			for _, p := range s.paths {
+				abs, err := filepath.Abs(p)
+
+				if err == nil {
+					w.walk(&s, p, abs, true, ret, listings)
+				}
+			}
+		}
+	}
+
+	w.listings = listings
+	return ret
+}
+
+// Record the state of the file at p (with absolute path abs) of the source s
+// and, for directories, the states of its contents.
+func (w *watcher) walk(s *source, p string, abs string, top bool, ret map[string]watchState, listings map[string]watchListing) {
+	info, err := os.Lstat(p)
+
+	if err != nil || abs == w.output {
+		// Missing sources are recorded by their absence
+		return
+	}
+
+	if !top && s.excluded != nil && s.excluded(p, info.IsDir()) {
+		return
+	}
+
+	ret[p] = watchState{
+		size:  info.Size(),
+		mode:  info.Mode(),
+		mtime: info.ModTime(),
+	}
+
+	if !info.IsDir() {
+		return
+	}
+
+	listing, ok := listings[p]
+
+	if !ok {
+		listing, ok = w.listings[p]
+	}
+
+	if !ok || !listing.mtime.Equal(info.ModTime()) || listing.read.Sub(listing.mtime) < watchListingResolution {
+		read := time.Now()
+
+		fd, err := os.Open(p)
+
+		if err != nil {
+			return
+		}
+
+		names, err := fd.Readdirnames(-1)
+		fd.Close()
+
+		if err != nil {
+			return
+		}
+
+		listing = watchListing{mtime: info.ModTime(), read: read, names: names}
+	}
+
+	listings[p] = listing
+
+	for _, name := range listing.names {
+		w.walk(s, filepath.Join(p, name), filepath.Join(abs, name), false, ret, listings)
+	}
+}
+
+func watchStatesEqual(a, b map[string]watchState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if w, ok := b[k]; !ok || w.size != v.size || w.mode != v.mode || !w.mtime.Equal(v.mtime) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,215 @@
+package assets
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	output := filepath.Join(dir, "assets.go")
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("first"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{WatchInterval: 10 * time.Millisecond}
+
+	if err := g.AddRoot(Root{Dir: src}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Bundle inputs are watched and read again as well
+	input := filepath.Join(dir, "input.css")
+
+	if err := ioutil.WriteFile(input, []byte("bundled-one"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.Bundle("/bundle.css", input); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- g.Watch(ctx, output)
+	}()
+
+	waitFor := func(contains string, absent string) {
+		deadline := time.Now().Add(5 * time.Second)
+
+		for time.Now().Before(deadline) {
+			data, _ := ioutil.ReadFile(output)
+
+			if strings.Contains(string(data), contains) && (len(absent) == 0 || !strings.Contains(string(data), absent)) {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Fatalf("timeout waiting for %s to contain %q", output, contains)
+	}
+
+	waitFor("first", "")
+
+	if err := ioutil.WriteFile(filepath.Join(src, "b.txt"), []byte("second"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("/b.txt", "")
+
+	if err := os.Remove(filepath.Join(src, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("/b.txt", "/a.txt")
+
+	if err := ioutil.WriteFile(input, []byte("bundled-three"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor("bundled-three", "bundled-one")
+
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestWatchStates(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	for _, name := range []string{"a.txt", "drafts/b.txt", "c.map"} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ioutil.WriteFile(p, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	g := &Generator{Exclude: []string{"*.map"}}
+
+	if err := g.AddRoot(Root{Dir: src, Exclude: []string{"drafts"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	w := newWatcher(filepath.Join(dir, "assets.go"))
+	states := w.states([]*Generator{g})
+
+	// Excluded files are not watched, like they are not added
+	for name, expected := range map[string]bool{"a.txt": true, "drafts": false, "drafts/b.txt": false, "c.map": false} {
+		if _, ok := states[filepath.Join(src, filepath.FromSlash(name))]; ok != expected {
+			t.Errorf("expected %s to be watched: %v", name, expected)
+		}
+	}
+
+	// Directories are only listed again when they change
+	l := w.listings[src]
+	l.read = l.mtime.Add(watchListingResolution)
+	l.names = []string{"a.txt"}
+	w.listings[src] = l
+
+	if err := ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	states = w.states([]*Generator{g})
+
+	if st := states[filepath.Join(src, "a.txt")]; st.size != int64(len("changed")) {
+		t.Errorf("expected change of a.txt to be observed, got size %d", st.size)
+	}
+
+	// A new file goes unnoticed while the directory appears unchanged
+	if err := ioutil.WriteFile(filepath.Join(src, "d.txt"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mtime := range []time.Time{l.mtime, l.mtime.Add(time.Second)} {
+		if err := os.Chtimes(src, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+
+		_, ok := w.states([]*Generator{g})[filepath.Join(src, "d.txt")]
+
+		if expected := !mtime.Equal(l.mtime); ok != expected {
+			t.Errorf("expected new file to be watched with directory modified at %s: %v", mtime, expected)
+		}
+	}
+}
+
+func TestWatchConfig(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &Config{
+		Output: filepath.Join(dir, "assets.go"),
+		Roots:  []Root{{Dir: src}},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	regenerated := make(chan error, 1)
+	done := make(chan error)
+
+	go func() {
+		done <- Watch(ctx, config, func(err error) {
+			select {
+			case regenerated <- err:
+			default:
+			}
+		})
+	}()
+
+	// Wait for the initial generation before changing the sources
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(config.Output); err == nil {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for initial generation")
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(src, "a.txt"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-regenerated:
+		if err != nil {
+			t.Errorf("unexpected regeneration error: %s", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("timeout waiting for regeneration")
+	}
+
+	cancel()
+	<-done
+
+	data, _ := ioutil.ReadFile(config.Output)
+
+	if !strings.Contains(string(data), "/a.txt") {
+		t.Errorf("expected regenerated assets to contain /a.txt")
+	}
+}
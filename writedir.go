@@ -0,0 +1,103 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Write the asset tree as a dedicated package into the directory dir, with
+// the data of each asset in its own file (named after the variable holding
+// the data) and the file systems in index.go. Since unchanged assets keep
+// their files, regenerating after a change only rewrites the files of the
+// changed assets. PackageName must be set to the name of the package in dir.
+// Files written by a previous WriteDir which are no longer needed (as
+// identified by their generated code marker) are removed, other files in dir
+// are left untouched.
+func (x *Generator) WriteDir(dir string) error {
+	if len(x.PackageName) == 0 {
+		return fmt.Errorf("no package name specified for %s", dir)
+	}
+
+	if len(x.PackFile) != 0 || len(x.EmbedDir) != 0 {
+		return fmt.Errorf("pack files and embedded directories can only be written by WriteFile")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	// A zero limit writes each asset to its own shard
+	shards := &dataShards{}
+
+	if err := x.writeAll(&buf, &dataOutput{shards: shards}); err != nil {
+		return err
+	}
+
+	files := map[string][]byte{
+		"index.go": buf.Bytes(),
+	}
+
+	for i, shard := range shards.shards {
+		data, err := x.dataFile(shard)
+
+		if err != nil {
+			return err
+		}
+
+		files[strings.TrimPrefix(shards.names[i], "_")+".go"] = data
+	}
+
+	for name, data := range files {
+		filename := filepath.Join(dir, name)
+
+		// Leave unchanged files alone, keeping their modification time
+		if existing, err := ioutil.ReadFile(filename); err == nil && bytes.Equal(existing, data) {
+			continue
+		}
+
+		if err := writeFileAtomic(filename, data); err != nil {
+			return err
+		}
+	}
+
+	if err := removeGenerated(dir, files); err != nil {
+		return err
+	}
+
+	return x.writeTestFile(filepath.Join(dir, "index.go"))
+}
+
+// Remove the files in dir written by WriteDir, except for the given files.
+func removeGenerated(dir string, keep map[string][]byte) error {
+	existing, err := filepath.Glob(filepath.Join(dir, "*.go"))
+
+	if err != nil {
+		return err
+	}
+
+	for _, filename := range existing {
+		if _, ok := keep[filepath.Base(filename)]; ok {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filename)
+
+		if err != nil {
+			return err
+		}
+
+		if bytes.HasPrefix(data, []byte(generatedMarker+"\n")) {
+			if err := os.Remove(filename); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
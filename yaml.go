@@ -0,0 +1,949 @@
+package assets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Unmarshal the YAML document data into v. The document is decoded into the
+// values JSON would be decoded into, such that v is populated following the
+// rules of encoding/json (including json struct tags). The commonly used
+// subset of YAML found in configuration and locale files is supported: block
+// and flow collections, plain, quoted and block (| and >) scalars and
+// comments. Anchors, aliases, tags, complex keys and multiple documents are
+// not supported and fail to unmarshal.
+func UnmarshalYAML(data []byte, v interface{}) error {
+	p, err := newYAMLParser(data)
+
+	if err != nil {
+		return err
+	}
+
+	value, err := p.document()
+
+	if err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(value)
+
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// A line of a YAML document.
+type yamlLine struct {
+	// The line number, for error messages
+	num int
+
+	// The number of spaces the line is indented by, and the text following
+	// them, without and with trailing white space
+	indent int
+	text   string
+	rest   string
+}
+
+// Check whether the line is empty or only contains a comment.
+func (l *yamlLine) blank() bool {
+	return len(l.text) == 0 || l.text[0] == '#'
+}
+
+// A parser of the YAML subset supported by UnmarshalYAML.
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func newYAMLParser(data []byte) (*yamlParser, error) {
+	if !utf8.Valid(data) {
+		return nil, fmt.Errorf("yaml: invalid UTF-8")
+	}
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+
+	p := &yamlParser{}
+
+	for i, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+
+		p.lines = append(p.lines, yamlLine{
+			num:    i + 1,
+			indent: len(line) - len(trimmed),
+			text:   strings.TrimRight(trimmed, " \t"),
+			rest:   trimmed,
+		})
+	}
+
+	return p, nil
+}
+
+func (p *yamlParser) errorf(line *yamlLine, format string, args ...interface{}) error {
+	return fmt.Errorf("yaml: line %d: %s", line.num, fmt.Sprintf(format, args...))
+}
+
+// Get the next line which is not blank, or nil at the end of the document.
+func (p *yamlParser) next() *yamlLine {
+	for p.pos < len(p.lines) {
+		line := &p.lines[p.pos]
+
+		if !line.blank() {
+			return line
+		}
+
+		p.pos++
+	}
+
+	return nil
+}
+
+// Parse the document, which may be preceded by a document start marker and
+// followed by a document end marker.
+func (p *yamlParser) document() (interface{}, error) {
+	if line := p.next(); line != nil && line.indent == 0 && yamlMarker(line.text, "---") {
+		if rest := strings.TrimSpace(line.text[3:]); len(rest) != 0 && rest[0] != '#' {
+			return nil, p.errorf(line, "content on the document start marker is not supported")
+		}
+
+		p.pos++
+	}
+
+	var value interface{}
+
+	if line := p.next(); line != nil && !p.documentEnd(line) {
+		var err error
+
+		if value, err = p.node(0); err != nil {
+			return nil, err
+		}
+	}
+
+	if line := p.next(); line != nil {
+		if !p.documentEnd(line) {
+			return nil, p.errorf(line, "unexpected content")
+		}
+
+		if yamlMarker(line.text, "---") {
+			return nil, p.errorf(line, "multiple documents are not supported")
+		}
+
+		p.pos++
+
+		if line := p.next(); line != nil {
+			return nil, p.errorf(line, "content after the document end")
+		}
+	}
+
+	return value, nil
+}
+
+// Check whether the line ends the document.
+func (p *yamlParser) documentEnd(line *yamlLine) bool {
+	return line.indent == 0 && (yamlMarker(line.text, "---") || yamlMarker(line.text, "..."))
+}
+
+// Check whether text is the given document marker, optionally followed by
+// white space and content.
+func yamlMarker(text string, marker string) bool {
+	return strings.HasPrefix(text, marker) && (len(text) == len(marker) || text[len(marker)] == ' ' || text[len(marker)] == '\t')
+}
+
+// Parse the block node starting at the next line, which is indented by at
+// least indent spaces.
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	line := p.next()
+
+	if line == nil || line.indent < indent || p.documentEnd(line) {
+		return nil, nil
+	}
+
+	if strings.HasPrefix(line.text, "\t") {
+		return nil, p.errorf(line, "tabs are not allowed for indentation")
+	}
+
+	if yamlSequenceItem(line.text) {
+		return p.sequence(line.indent)
+	}
+
+	if _, _, ok, err := p.mappingEntry(line); err != nil {
+		return nil, err
+	} else if ok {
+		return p.mapping(line.indent)
+	}
+
+	p.pos++
+	return p.value(line, line.text, indent-1)
+}
+
+// Check whether text starts a block sequence item.
+func yamlSequenceItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ") || strings.HasPrefix(text, "-\t")
+}
+
+// Parse a block sequence with items indented by indent spaces.
+func (p *yamlParser) sequence(indent int) ([]interface{}, error) {
+	ret := []interface{}{}
+
+	for {
+		line := p.next()
+
+		if line == nil || line.indent != indent || !yamlSequenceItem(line.text) || p.documentEnd(line) {
+			if line != nil && line.indent > indent && !p.documentEnd(line) {
+				return nil, p.errorf(line, "unexpected indentation")
+			}
+
+			return ret, nil
+		}
+
+		rest := strings.TrimLeft(line.text[1:], " ")
+
+		if len(rest) == 0 || rest[0] == '#' {
+			p.pos++
+
+			item, err := p.node(indent + 1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			ret = append(ret, item)
+			continue
+		}
+
+		if strings.HasPrefix(rest, "\t") {
+			return nil, p.errorf(line, "tabs are not allowed for indentation")
+		}
+
+		// Parse the rest of the line as a node of its own, indented by
+		// the column it starts at, such that the lines following it
+		// continue the item (e.g. the entries of a mapping)
+		line.indent += len(line.text) - len(rest)
+		line.text = rest
+
+		item, err := p.node(indent + 1)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, item)
+	}
+}
+
+// Parse a block mapping with entries indented by indent spaces.
+func (p *yamlParser) mapping(indent int) (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+
+	for {
+		line := p.next()
+
+		if line == nil || line.indent != indent || p.documentEnd(line) {
+			if line != nil && line.indent > indent && !p.documentEnd(line) {
+				return nil, p.errorf(line, "unexpected indentation")
+			}
+
+			return ret, nil
+		}
+
+		if strings.HasPrefix(line.text, "\t") {
+			return nil, p.errorf(line, "tabs are not allowed for indentation")
+		}
+
+		key, rest, ok, err := p.mappingEntry(line)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			if yamlSequenceItem(line.text) {
+				return nil, p.errorf(line, "unexpected sequence item")
+			}
+
+			return nil, p.errorf(line, "expected a mapping entry")
+		}
+
+		if _, ok := ret[key]; ok {
+			return nil, p.errorf(line, "duplicate key %q", key)
+		}
+
+		p.pos++
+
+		var value interface{}
+
+		if len(rest) == 0 || rest[0] == '#' {
+			// The value is the block node on the following lines. Block
+			// sequences may be indented like the key itself.
+			if next := p.next(); next != nil && next.indent == indent && yamlSequenceItem(next.text) && !p.documentEnd(next) {
+				value, err = p.sequence(indent)
+			} else {
+				value, err = p.node(indent + 1)
+			}
+		} else {
+			value, err = p.value(line, rest, indent)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret[key] = value
+	}
+}
+
+// Split a block mapping entry into its key and the (unparsed) rest of the
+// line following the key indicator. ok is false if the line is not a mapping
+// entry.
+func (p *yamlParser) mappingEntry(line *yamlLine) (string, string, bool, error) {
+	text := line.text
+
+	switch text[0] {
+	case '"', '\'':
+		key, n, err := yamlQuoted(text)
+
+		if err != nil {
+			return "", "", false, nil
+		}
+
+		rest := strings.TrimLeft(text[n:], " ")
+
+		if !strings.HasPrefix(rest, ":") || (len(rest) > 1 && rest[1] != ' ' && rest[1] != '\t') {
+			return "", "", false, nil
+		}
+
+		return key, strings.TrimSpace(rest[1:]), true, nil
+	case '?':
+		if len(text) == 1 || text[1] == ' ' {
+			return "", "", false, p.errorf(line, "complex keys are not supported")
+		}
+	case '[', '{', '-', '#', '|', '>', '&', '*', '!', '%', '@', '`':
+		if text[0] != '-' || yamlSequenceItem(text) || len(text) == 1 {
+			return "", "", false, nil
+		}
+	}
+
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '#':
+			if i > 0 && (text[i-1] == ' ' || text[i-1] == '\t') {
+				return "", "", false, nil
+			}
+		case ':':
+			if i+1 == len(text) || text[i+1] == ' ' || text[i+1] == '\t' {
+				return strings.TrimRight(text[:i], " \t"), strings.TrimSpace(text[i+1:]), true, nil
+			}
+		}
+	}
+
+	return "", "", false, nil
+}
+
+// Parse the value text found on line, of a node nested in a parent indented
+// by indent spaces. Plain scalars may continue on the following lines which
+// are indented further than the parent.
+func (p *yamlParser) value(line *yamlLine, text string, indent int) (interface{}, error) {
+	switch text[0] {
+	case '[', '{':
+		return p.flow(line, text, indent)
+	case '"', '\'':
+		value, n, err := yamlQuoted(text)
+
+		if err != nil {
+			return nil, p.errorf(line, "%s", err)
+		}
+
+		if rest := strings.TrimSpace(text[n:]); len(rest) != 0 && rest[0] != '#' {
+			return nil, p.errorf(line, "unexpected %q after quoted scalar", rest)
+		}
+
+		return value, nil
+	case '|', '>':
+		return p.blockScalar(line, text, indent)
+	case '&', '*', '!':
+		return nil, p.errorf(line, "anchors, aliases and tags are not supported")
+	case '@', '`', '%':
+		return nil, p.errorf(line, "plain scalars cannot start with %q", text[0])
+	}
+
+	lines := []string{yamlStripComment(text)}
+	commented := len(lines[0]) != len(text)
+
+	// Continuation lines of multi-line plain scalars
+	for !commented && p.pos < len(p.lines) {
+		next := &p.lines[p.pos]
+
+		if len(next.text) != 0 && (next.indent <= indent || next.text[0] == '#' || p.documentEnd(next)) {
+			break
+		}
+
+		if len(next.text) != 0 {
+			if _, _, ok, _ := p.mappingEntry(next); ok {
+				return nil, p.errorf(next, "unexpected mapping entry")
+			}
+		}
+
+		stripped := yamlStripComment(next.text)
+		commented = len(stripped) != len(next.text)
+
+		lines = append(lines, stripped)
+		p.pos++
+	}
+
+	// Trailing empty lines are not part of the scalar
+	for len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+
+	if len(lines) == 1 {
+		return yamlResolve(lines[0]), nil
+	}
+
+	return yamlFold(lines), nil
+}
+
+// Remove a trailing comment from the text of a plain scalar.
+func yamlStripComment(text string) string {
+	for i := 1; i < len(text); i++ {
+		if text[i] == '#' && (text[i-1] == ' ' || text[i-1] == '\t') {
+			return strings.TrimRight(text[:i], " \t")
+		}
+	}
+
+	return text
+}
+
+// Fold the lines of a multi-line scalar, joining lines by a space and
+// replacing empty lines by line breaks.
+func yamlFold(lines []string) string {
+	var buf strings.Builder
+	empty := 0
+
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+
+		if len(line) == 0 {
+			empty++
+			continue
+		}
+
+		if i != 0 {
+			if empty == 0 {
+				buf.WriteByte(' ')
+			} else {
+				buf.WriteString(strings.Repeat("\n", empty))
+			}
+		}
+
+		buf.WriteString(line)
+		empty = 0
+	}
+
+	return buf.String()
+}
+
+// Resolve the value of a single line plain scalar, following the YAML core
+// schema.
+func yamlResolve(text string) interface{} {
+	switch text {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if n, ok := yamlNumber(text); ok {
+		return n
+	}
+
+	return text
+}
+
+// Parse a plain scalar as a number, returning it as a JSON number.
+func yamlNumber(text string) (json.Number, bool) {
+	digits := strings.TrimLeft(text, "+-")
+
+	if len(digits) == 0 || len(text)-len(digits) > 1 {
+		return "", false
+	}
+
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0o") {
+		base := 16
+
+		if digits[1] == 'o' {
+			base = 8
+		}
+
+		n, err := strconv.ParseUint(digits[2:], base, 64)
+
+		if err != nil {
+			return "", false
+		}
+
+		if text[0] == '-' {
+			return json.Number("-" + strconv.FormatUint(n, 10)), true
+		}
+
+		return json.Number(strconv.FormatUint(n, 10)), true
+	}
+
+	if c := digits[0]; (c < '0' || c > '9') && c != '.' {
+		return "", false
+	}
+
+	for _, c := range digits {
+		if (c < '0' || c > '9') && c != '.' && c != 'e' && c != 'E' && c != '+' && c != '-' {
+			return "", false
+		}
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+
+	if err != nil || math.IsInf(f, 0) {
+		return "", false
+	}
+
+	// Keep the exact representation of integers, which may not be
+	// representable as floating point numbers
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return json.Number(strconv.FormatInt(n, 10)), true
+	}
+
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), true
+}
+
+// Parse the quoted scalar at the start of text, returning its value and the
+// length of the quoted text.
+func yamlQuoted(text string) (string, int, error) {
+	quote := text[0]
+
+	var buf strings.Builder
+
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case c == quote && quote == '\'':
+			if i+1 < len(text) && text[i+1] == '\'' {
+				buf.WriteByte('\'')
+				i++
+				continue
+			}
+
+			return buf.String(), i + 1, nil
+		case c == quote:
+			return buf.String(), i + 1, nil
+		case c == '\\' && quote == '"':
+			if i+1 == len(text) {
+				return "", 0, fmt.Errorf("unterminated escape sequence")
+			}
+
+			i++
+
+			n, err := yamlEscape(&buf, text[i:])
+
+			if err != nil {
+				return "", 0, err
+			}
+
+			i += n - 1
+		default:
+			buf.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated quoted scalar (multi-line quoted scalars are not supported)")
+}
+
+// The single character escape sequences of double quoted scalars.
+var yamlEscapes = map[byte]string{
+	'0': "\x00", 'a': "\a", 'b': "\b", 't': "\t", '\t': "\t", 'n': "\n",
+	'v': "\v", 'f': "\f", 'r': "\r", 'e': "\x1b", ' ': " ", '"': "\"",
+	'/': "/", '\\': "\\", 'N': "\u0085", '_': "\u00a0", 'L': "\u2028",
+	'P': "\u2029",
+}
+
+// Write the character of the escape sequence at the start of text (following
+// the backslash), returning the length of the sequence.
+func yamlEscape(buf *strings.Builder, text string) (int, error) {
+	if s, ok := yamlEscapes[text[0]]; ok {
+		buf.WriteString(s)
+		return 1, nil
+	}
+
+	var size int
+
+	switch text[0] {
+	case 'x':
+		size = 2
+	case 'u':
+		size = 4
+	case 'U':
+		size = 8
+	default:
+		return 0, fmt.Errorf("invalid escape sequence \\%c", text[0])
+	}
+
+	if len(text) < size+1 {
+		return 0, fmt.Errorf("invalid escape sequence \\%s", text)
+	}
+
+	r, err := strconv.ParseUint(text[1:size+1], 16, 32)
+
+	if err != nil || !utf8.ValidRune(rune(r)) {
+		return 0, fmt.Errorf("invalid escape sequence \\%s", text[:size+1])
+	}
+
+	buf.WriteRune(rune(r))
+	return size + 1, nil
+}
+
+// Parse a block scalar with the given header, of a node nested in a parent
+// indented by indent spaces.
+func (p *yamlParser) blockScalar(line *yamlLine, header string, indent int) (string, error) {
+	literal := header[0] == '|'
+	chomp := byte(0)
+	explicit := 0
+
+	for _, c := range []byte(yamlStripComment(header)[1:]) {
+		switch {
+		case (c == '-' || c == '+') && chomp == 0:
+			chomp = c
+		case c >= '1' && c <= '9' && explicit == 0:
+			explicit = int(c - '0')
+		default:
+			return "", p.errorf(line, "invalid block scalar header %q", header)
+		}
+	}
+
+	// The content is indented by the explicit indentation, or by the
+	// indentation of its first non-empty line
+	content := -1
+
+	if explicit != 0 {
+		content = indent + explicit
+
+		if indent < 0 {
+			content = explicit
+		}
+	}
+
+	var lines []string
+
+	for ; p.pos < len(p.lines); p.pos++ {
+		raw := p.lines[p.pos]
+
+		if len(raw.text) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		if content < 0 {
+			if raw.indent <= indent {
+				break
+			}
+
+			content = raw.indent
+		}
+
+		if raw.indent < content || p.documentEnd(&raw) {
+			break
+		}
+
+		lines = append(lines, strings.Repeat(" ", raw.indent-content)+raw.rest)
+	}
+
+	trailing := 0
+
+	for len(lines) != 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+		trailing++
+	}
+
+	var buf bytes.Buffer
+
+	if literal {
+		buf.WriteString(strings.Join(lines, "\n"))
+	} else {
+		normal := false
+		empty := 0
+
+		for _, l := range lines {
+			if len(l) == 0 {
+				empty++
+				continue
+			}
+
+			more := l[0] == ' ' || l[0] == '\t'
+
+			switch {
+			case buf.Len() == 0:
+				buf.WriteString(strings.Repeat("\n", empty))
+			case normal && !more && empty == 0:
+				buf.WriteByte(' ')
+			case normal && !more:
+				buf.WriteString(strings.Repeat("\n", empty))
+			default:
+				buf.WriteString(strings.Repeat("\n", empty+1))
+			}
+
+			buf.WriteString(l)
+			normal = !more
+			empty = 0
+		}
+	}
+
+	switch {
+	case chomp == '-' || len(lines) == 0 && chomp != '+':
+	case chomp == '+':
+		buf.WriteString(strings.Repeat("\n", trailing+1))
+	default:
+		buf.WriteByte('\n')
+	}
+
+	return buf.String(), nil
+}
+
+// Parse the flow collection starting with text on line, which may continue
+// on the following lines indented further than the parent, which is
+// indented by indent spaces.
+func (p *yamlParser) flow(line *yamlLine, text string, indent int) (interface{}, error) {
+	f := &yamlFlow{text: text}
+
+	for {
+		value, err := f.value()
+
+		if err == nil {
+			if f.skip(); f.pos != len(f.text) {
+				return nil, p.errorf(line, "unexpected %q after flow collection", f.text[f.pos:])
+			}
+
+			return value, nil
+		}
+
+		if err != errYAMLFlowEnd || p.pos >= len(p.lines) {
+			return nil, p.errorf(line, "%s", err)
+		}
+
+		// Flow collections may span several lines
+		next := &p.lines[p.pos]
+
+		if len(next.text) != 0 && (next.indent <= indent || p.documentEnd(next)) {
+			return nil, p.errorf(line, "unterminated flow collection")
+		}
+
+		f.text += "\n" + next.text
+		f.pos = 0
+		p.pos++
+	}
+}
+
+// The error of flow collections ending before they are closed.
+var errYAMLFlowEnd = fmt.Errorf("unterminated flow collection")
+
+// A parser of flow collections.
+type yamlFlow struct {
+	text string
+	pos  int
+}
+
+// Skip white space and comments.
+func (f *yamlFlow) skip() {
+	for f.pos < len(f.text) {
+		switch c := f.text[f.pos]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			f.pos++
+		case c == '#' && (f.pos == 0 || yamlSpace(f.text[f.pos-1])):
+			// Comments extend to the end of the line, which is where
+			// lines are joined
+			if i := strings.IndexByte(f.text[f.pos:], '\n'); i >= 0 {
+				f.pos += i + 1
+			} else {
+				f.pos = len(f.text)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// Parse a flow node.
+func (f *yamlFlow) value() (interface{}, error) {
+	f.skip()
+
+	if f.pos == len(f.text) {
+		return nil, errYAMLFlowEnd
+	}
+
+	switch c := f.text[f.pos]; c {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		value, n, err := yamlQuoted(f.text[f.pos:])
+
+		if err != nil {
+			return nil, err
+		}
+
+		f.pos += n
+		return value, nil
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported")
+	}
+
+	return yamlResolve(f.plain()), nil
+}
+
+// Parse a plain scalar in a flow collection.
+func (f *yamlFlow) plain() string {
+	start := f.pos
+
+	for ; f.pos < len(f.text); f.pos++ {
+		c := f.text[f.pos]
+
+		if c == ',' || c == ']' || c == '}' || c == '[' || c == '{' {
+			break
+		}
+
+		if c == ':' && (f.pos+1 == len(f.text) || strings.IndexByte(" \t\n,]}", f.text[f.pos+1]) >= 0) {
+			break
+		}
+
+		if c == '#' && f.pos > start && yamlSpace(f.text[f.pos-1]) {
+			break
+		}
+	}
+
+	return yamlFold(strings.Split(strings.TrimSpace(f.text[start:f.pos]), "\n"))
+}
+
+// Check whether c separates tokens in flow collections.
+func yamlSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n'
+}
+
+// Parse a flow sequence.
+func (f *yamlFlow) sequence() ([]interface{}, error) {
+	ret := []interface{}{}
+	f.pos++
+
+	for {
+		f.skip()
+
+		if f.pos == len(f.text) {
+			return nil, errYAMLFlowEnd
+		}
+
+		if f.text[f.pos] == ']' {
+			f.pos++
+			return ret, nil
+		}
+
+		value, err := f.value()
+
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, value)
+
+		if err := f.separator(']'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Parse a flow mapping.
+func (f *yamlFlow) mapping() (map[string]interface{}, error) {
+	ret := make(map[string]interface{})
+	f.pos++
+
+	for {
+		f.skip()
+
+		if f.pos == len(f.text) {
+			return nil, errYAMLFlowEnd
+		}
+
+		if f.text[f.pos] == '}' {
+			f.pos++
+			return ret, nil
+		}
+
+		var key string
+
+		switch f.text[f.pos] {
+		case '"', '\'':
+			k, n, err := yamlQuoted(f.text[f.pos:])
+
+			if err != nil {
+				return nil, err
+			}
+
+			key = k
+			f.pos += n
+		case '[', '{', '?':
+			return nil, fmt.Errorf("complex keys are not supported")
+		default:
+			key = f.plain()
+		}
+
+		if _, ok := ret[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+
+		f.skip()
+
+		var value interface{}
+
+		if f.pos < len(f.text) && f.text[f.pos] == ':' {
+			f.pos++
+			f.skip()
+
+			if f.pos < len(f.text) && f.text[f.pos] != ',' && f.text[f.pos] != '}' {
+				var err error
+
+				if value, err = f.value(); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		ret[key] = value
+
+		if err := f.separator('}'); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Skip the separator following an entry of a flow collection, which is
+// either a comma or the end of the collection.
+func (f *yamlFlow) separator(end byte) error {
+	f.skip()
+
+	if f.pos == len(f.text) {
+		return errYAMLFlowEnd
+	}
+
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case end:
+		return nil
+	}
+
+	return fmt.Errorf("expected , or %c in flow collection, got %q", end, f.text[f.pos:])
+}
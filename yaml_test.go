@@ -0,0 +1,101 @@
+package assets
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	type m = map[string]interface{}
+	type s = []interface{}
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"", nil},
+		{"# only a comment\n", nil},
+		{"plain", "plain"},
+		{"---\nkey: value\n...\n", m{"key": "value"}},
+		{
+			"a: 1\nb: -2.5\nc: true\nd: ~\ne: null\nf: 0x1f\ng: 1.2.3\nh: 'it''s'\ni: \"tab\\there \\u00e9\"\nj:\n",
+			m{"a": 1.0, "b": -2.5, "c": true, "d": nil, "e": nil, "f": 31.0, "g": "1.2.3", "h": "it's", "i": "tab\there é", "j": nil},
+		},
+		{
+			"url: http://example.com/a#b # comment\nkey with spaces: value: with colon\n\"quoted: key\": x\n",
+			m{"url": "http://example.com/a#b", "key with spaces": "value: with colon", "quoted: key": "x"},
+		},
+		{
+			"nested:\n  a: 1\n  b:\n    c: [1, \"two\", {three: 3}]\n  d: {}\n",
+			m{"nested": m{"a": 1.0, "b": m{"c": s{1.0, "two", m{"three": 3.0}}}, "d": m{}}},
+		},
+		{
+			"list:\n- a\n- b: 1\n  c: 2\n-\n  - nested\n- - inline\n  - second\nafter: x\n",
+			m{"list": s{"a", m{"b": 1.0, "c": 2.0}, s{"nested"}, s{"inline", "second"}}, "after": "x"},
+		},
+		{
+			"indented:\n  - a  # comment\n  - 'b # not a comment'\n",
+			m{"indented": s{"a", "b # not a comment"}},
+		},
+		{
+			"multi: a plain\n  scalar over\n\n  lines\nflow: [a,\n  b, # comment\n  c]\n",
+			m{"multi": "a plain scalar over\nlines", "flow": s{"a", "b", "c"}},
+		},
+		{
+			"literal: |\n  line 1\n    indented\n\n  line 3\nstrip: |-\n  text\n\nkeep: |+\n  text\n\nfolded: >\n  folded\n  text\n\n  paragraph\n    more\n",
+			m{"literal": "line 1\n  indented\n\nline 3\n", "strip": "text", "keep": "text\n\n", "folded": "folded text\nparagraph\n  more\n"},
+		},
+		{
+			"- |\n  block\n  in a sequence\n- last\n",
+			s{"block\nin a sequence\n", "last"},
+		},
+	}
+
+	for _, test := range tests {
+		var v interface{}
+
+		if err := UnmarshalYAML([]byte(test.input), &v); err != nil {
+			t.Errorf("%q: %s", test.input, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(v, test.expected) {
+			t.Errorf("%q: expected %#v, got %#v", test.input, test.expected, v)
+		}
+	}
+
+	for _, input := range []string{
+		"a: 1\na: 2\n",
+		"a: 1\n  b: 2\n",
+		"a:\n\tb: 1\n",
+		"a: &anchor 1\n",
+		"a: *alias\n",
+		"a: !tag 1\n",
+		"? complex\n: key\n",
+		"a: 1\n---\nb: 2\n",
+		"a: [1, 2\n",
+		"a: 'unterminated\n",
+		"a: \"\\q\"\n",
+		"- a\nb: 1\n",
+	} {
+		var v interface{}
+
+		if err := UnmarshalYAML([]byte(input), &v); err == nil {
+			t.Errorf("%q: expected error, got %#v", input, v)
+		} else if !strings.HasPrefix(err.Error(), "yaml: ") {
+			t.Errorf("%q: expected yaml error, got %s", input, err)
+		}
+	}
+
+	// Structs are populated following their json tags
+	var config Config
+
+	if err := UnmarshalYAML([]byte("output: assets.go\nstrip_prefix: /web\ninputs:\n- web\nroots:\n- dir: docs\n  mount: /docs\n"), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Output != "assets.go" || config.StripPrefix != "/web" || !reflect.DeepEqual(config.Inputs, []string{"web"}) || len(config.Roots) != 1 || config.Roots[0].Mount != "/docs" {
+		t.Errorf("unexpected config %+v", config)
+	}
+}